@@ -0,0 +1,300 @@
+// Package accumulator implements an RSA accumulator over the modulus of an rsa key
+// the accumulator of a set of primes S is A = G^(prod S) (mod N), members are hashed to primes by HashToPrime
+//
+// membership witness of x is W with W^x = A
+// non-membership witness of x is (a, B) with A^a * B^x = G, found by Bezout a*prod(S) + b*x = 1
+// witnesses are updated by holders from the published Update of a batch of additions or deletions
+package accumulator
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/hongyanwang/crypto-lab/asymmetric/rsa"
+	"github.com/hongyanwang/crypto-lab/common/modular"
+)
+
+const (
+	// PrimeBits bit length of primes output by HashToPrime
+	PrimeBits = 256
+	// primality test rounds of accumulated elements
+	primeRounds = 20
+)
+
+var (
+	ErrNotPrime      = errors.New("accumulator: element is not an odd prime")
+	ErrAlreadyMember = errors.New("accumulator: element is already a member")
+	ErrNotMember     = errors.New("accumulator: element is not a member")
+	ErrNoTrapdoor    = errors.New("accumulator: missing trapdoor")
+
+	one = big.NewInt(1)
+)
+
+// PublicParams RSA modulus and generator, enough to verify witnesses and proofs
+type PublicParams struct {
+	N *big.Int
+	G *big.Int // G is a random quadratic residue mod N
+}
+
+// Accumulator state kept by the accumulator manager
+type Accumulator struct {
+	PublicParams
+	Value *big.Int // Value = G^(prod of members) (mod N)
+
+	members map[string]bool
+	prod    *big.Int // product of members
+	phi     *big.Int // trapdoor, nil if unknown
+}
+
+// Update change of the accumulator by a batch of additions followed by a batch of deletions
+// holders of witnesses apply it by UpdateMembershipWitness and UpdateNonMembershipWitness
+type Update struct {
+	Added   *big.Int // product of added elements
+	Deleted *big.Int // product of deleted elements
+	Prev    *big.Int // accumulator value before the update
+	Value   *big.Int // accumulator value after the update
+}
+
+// NonMembershipWitness (a, B) with Value^A * B^x = G (mod N) and 0 <= A < x
+type NonMembershipWitness struct {
+	A *big.Int
+	B *big.Int
+}
+
+// New create an empty accumulator with a random generator, deletions recompute the value from the members
+func New(pub *rsa.PublicKey) (*Accumulator, error) {
+	r, err := modular.RandomUnit(pub.N)
+	if err != nil {
+		return nil, err
+	}
+	g := r.Mul(r, r)
+	g.Mod(g, pub.N)
+	return &Accumulator{
+		PublicParams: PublicParams{N: new(big.Int).Set(pub.N), G: g},
+		Value:        new(big.Int).Set(g),
+		members:      make(map[string]bool),
+		prod:         big.NewInt(1),
+	}, nil
+}
+
+// NewWithTrapdoor create an empty accumulator whose manager knows phi(N)
+// deletions and membership witnesses then cost a single exponentiation
+func NewWithTrapdoor(prvkey *rsa.PrivateKey) (*Accumulator, error) {
+	if prvkey.Phi == nil {
+		return nil, ErrNoTrapdoor
+	}
+	acc, err := New(&prvkey.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	acc.phi = new(big.Int).Set(prvkey.Phi)
+	return acc, nil
+}
+
+// HashToPrime map data to a prime of PrimeBits bits
+// SHA-256(data || counter) with top and lowest bits set, for counter = 0, 1, ... until a prime is found
+func HashToPrime(data []byte) *big.Int {
+	var counter [4]byte
+	x := new(big.Int)
+	for i := uint32(0); ; i++ {
+		binary.BigEndian.PutUint32(counter[:], i)
+		h := sha256.New()
+		h.Write(data)
+		h.Write(counter[:])
+		x.SetBytes(h.Sum(nil))
+		x.SetBit(x, PrimeBits-1, 1)
+		x.SetBit(x, 0, 1)
+		if x.ProbablyPrime(primeRounds) {
+			return x
+		}
+	}
+}
+
+// Len number of members
+func (acc *Accumulator) Len() int {
+	return len(acc.members)
+}
+
+// IsMember whether x is accumulated
+func (acc *Accumulator) IsMember(x *big.Int) bool {
+	return acc.members[x.String()]
+}
+
+// Add accumulate a batch of primes, Value = Value^(prod xs)
+func (acc *Accumulator) Add(xs ...*big.Int) (*Update, error) {
+	seen := make(map[string]bool)
+	for _, x := range xs {
+		if err := checkPrime(x); err != nil {
+			return nil, err
+		}
+		if acc.members[x.String()] || seen[x.String()] {
+			return nil, ErrAlreadyMember
+		}
+		seen[x.String()] = true
+	}
+	added := product(xs)
+	upd := &Update{Added: added, Deleted: big.NewInt(1), Prev: acc.Value}
+	for _, x := range xs {
+		acc.members[x.String()] = true
+	}
+	acc.prod.Mul(acc.prod, added)
+	acc.Value = new(big.Int).Exp(acc.Value, added, acc.N)
+	upd.Value = acc.Value
+	return upd, nil
+}
+
+// Delete remove a batch of members
+// with trapdoor Value = Value^((prod xs)^-1 mod phi), otherwise Value = G^(prod of remaining members)
+func (acc *Accumulator) Delete(xs ...*big.Int) (*Update, error) {
+	seen := make(map[string]bool)
+	for _, x := range xs {
+		if !acc.members[x.String()] || seen[x.String()] {
+			return nil, ErrNotMember
+		}
+		seen[x.String()] = true
+	}
+	deleted := product(xs)
+	upd := &Update{Added: big.NewInt(1), Deleted: deleted, Prev: acc.Value}
+	for _, x := range xs {
+		delete(acc.members, x.String())
+	}
+	acc.prod.Div(acc.prod, deleted)
+	if inv := acc.inversePhi(deleted); inv != nil {
+		acc.Value = new(big.Int).Exp(acc.Value, inv, acc.N)
+	} else {
+		acc.Value = new(big.Int).Exp(acc.G, acc.prod, acc.N)
+	}
+	upd.Value = acc.Value
+	return upd, nil
+}
+
+// MembershipWitness W with W^(prod xs) = Value, a single witness for a batch of members
+func (acc *Accumulator) MembershipWitness(xs ...*big.Int) (*big.Int, error) {
+	for _, x := range xs {
+		if !acc.members[x.String()] {
+			return nil, ErrNotMember
+		}
+	}
+	x := product(xs)
+	if inv := acc.inversePhi(x); inv != nil {
+		return new(big.Int).Exp(acc.Value, inv, acc.N), nil
+	}
+	return new(big.Int).Exp(acc.G, new(big.Int).Div(acc.prod, x), acc.N), nil
+}
+
+// inversePhi x^-1 (mod phi) by the trapdoor
+// nil without trapdoor or if x shares a factor with phi, e.g. a small prime, then the members are used instead
+func (acc *Accumulator) inversePhi(x *big.Int) *big.Int {
+	if acc.phi == nil {
+		return nil
+	}
+	return new(big.Int).ModInverse(x, acc.phi)
+}
+
+// NonMembershipWitness witness that none of xs is a member
+// Bezout a*prod(S) + b*x = 1 for x = prod xs gives Value^a * (G^b)^x = G
+func (acc *Accumulator) NonMembershipWitness(xs ...*big.Int) (*NonMembershipWitness, error) {
+	for _, x := range xs {
+		if err := checkPrime(x); err != nil {
+			return nil, err
+		}
+		if acc.members[x.String()] {
+			return nil, ErrAlreadyMember
+		}
+	}
+	x := product(xs)
+	a, b := new(big.Int), new(big.Int)
+	if new(big.Int).GCD(a, b, acc.prod, x).Cmp(one) != 0 {
+		return nil, ErrAlreadyMember
+	}
+	// G^a = Value^a by itself, so reduce a (mod x) into B
+	return normalize(a, modular.ExpInt(acc.G, b, acc.N), x, acc.Value, acc.N), nil
+}
+
+// VerifyMembership check W^(prod xs) = value
+func (pp *PublicParams) VerifyMembership(value, w *big.Int, xs ...*big.Int) bool {
+	if !modular.IsUnit(w, pp.N) {
+		return false
+	}
+	return new(big.Int).Exp(w, product(xs), pp.N).Cmp(value) == 0
+}
+
+// VerifyNonMembership check value^a * B^(prod xs) = G
+func (pp *PublicParams) VerifyNonMembership(value *big.Int, w *NonMembershipWitness, xs ...*big.Int) bool {
+	if w.A == nil || w.A.Sign() < 0 || !modular.IsUnit(w.B, pp.N) {
+		return false
+	}
+	lhs := new(big.Int).Exp(value, w.A, pp.N)
+	lhs.Mul(lhs, new(big.Int).Exp(w.B, product(xs), pp.N))
+	lhs.Mod(lhs, pp.N)
+	return lhs.Cmp(pp.G) == 0
+}
+
+// UpdateMembershipWitness witness of member x after upd, without knowledge of other members
+// additions: W' = W^Added, deletions: Bezout a*x + b*Deleted = 1 and W' = W^b * Value^a
+func (pp *PublicParams) UpdateMembershipWitness(x, w *big.Int, upd *Update) (*big.Int, error) {
+	w = new(big.Int).Exp(w, upd.Added, pp.N)
+	if upd.Deleted.Cmp(one) == 0 {
+		return w, nil
+	}
+	a, b := new(big.Int), new(big.Int)
+	if new(big.Int).GCD(a, b, x, upd.Deleted).Cmp(one) != 0 {
+		return nil, ErrNotMember
+	}
+	w = modular.ExpInt(w, b, pp.N)
+	w.Mul(w, modular.ExpInt(upd.Value, a, pp.N))
+	return w.Mod(w, pp.N), nil
+}
+
+// UpdateNonMembershipWitness witness that x is not a member after upd
+// additions: the new value is Prev^Added, so a becomes a*Added^-1 (mod x)
+// deletions: the value before deletion is Value^Deleted, so a becomes a*Deleted (mod x)
+func (pp *PublicParams) UpdateNonMembershipWitness(x *big.Int, w *NonMembershipWitness, upd *Update) (*NonMembershipWitness, error) {
+	a, b := w.A, w.B
+	if upd.Added.Cmp(one) != 0 {
+		inv := new(big.Int).ModInverse(upd.Added, x)
+		if inv == nil {
+			return nil, ErrAlreadyMember
+		}
+		// Prev^a = (Prev^Added)^a' * Prev^(a - Added*a') with x | a - Added*a'
+		a2 := inv.Mul(inv, a)
+		a2.Mod(a2, x)
+		k := new(big.Int).Mul(upd.Added, a2)
+		k.Sub(a, k)
+		k.Div(k, x)
+		b = modular.ExpInt(upd.Prev, k, pp.N)
+		b.Mul(b, w.B)
+		b.Mod(b, pp.N)
+		a = a2
+	}
+	if upd.Deleted.Cmp(one) == 0 {
+		return &NonMembershipWitness{A: a, B: b}, nil
+	}
+	return normalize(new(big.Int).Mul(a, upd.Deleted), b, x, upd.Value, pp.N), nil
+}
+
+// normalize reduce a of witness value^a * B^x = G into [0, x) by moving multiples of x into B
+func normalize(a, b, x, value, n *big.Int) *NonMembershipWitness {
+	k, r := new(big.Int).DivMod(a, x, new(big.Int))
+	b = new(big.Int).Mul(b, modular.ExpInt(value, k, n))
+	return &NonMembershipWitness{A: r, B: b.Mod(b, n)}
+}
+
+// checkPrime accumulated elements must be odd primes so that they are pairwise coprime
+func checkPrime(x *big.Int) error {
+	if x == nil || x.Cmp(big.NewInt(2)) <= 0 || !x.ProbablyPrime(primeRounds) {
+		return ErrNotPrime
+	}
+	return nil
+}
+
+// product of xs, 1 if empty
+func product(xs []*big.Int) *big.Int {
+	prod := big.NewInt(1)
+	for _, x := range xs {
+		prod.Mul(prod, x)
+	}
+	return prod
+}
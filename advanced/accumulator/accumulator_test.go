@@ -0,0 +1,387 @@
+package accumulator
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/hongyanwang/crypto-lab/asymmetric/rsa"
+	"github.com/hongyanwang/crypto-lab/common/modular"
+)
+
+var secbit = 1024
+
+// elements primes hashed from "prefix-i"
+func elements(prefix string, num int) []*big.Int {
+	xs := make([]*big.Int, num)
+	for i := range xs {
+		xs[i] = HashToPrime([]byte(fmt.Sprintf("%s-%d", prefix, i)))
+	}
+	return xs
+}
+
+func newAccumulators(t testing.TB) []*Accumulator {
+	key, err := rsa.GenerateKey(secbit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	acc, err := New(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	accT, err := NewWithTrapdoor(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []*Accumulator{acc, accT}
+}
+
+func TestHashToPrime(t *testing.T) {
+	x := HashToPrime([]byte("revoked certificate"))
+	if x.BitLen() != PrimeBits || !x.ProbablyPrime(20) {
+		t.Fatal("HashToPrime output is not a prime of PrimeBits bits")
+	}
+	if x.Cmp(HashToPrime([]byte("revoked certificate"))) != 0 {
+		t.Fatal("HashToPrime is not deterministic")
+	}
+	if x.Cmp(HashToPrime([]byte("valid certificate"))) == 0 {
+		t.Fatal("different data hashed to the same prime")
+	}
+}
+
+func TestMembership(t *testing.T) {
+	for _, acc := range newAccumulators(t) {
+		xs := elements("member", 10)
+		if _, err := acc.Add(xs...); err != nil {
+			t.Fatal(err)
+		}
+		if acc.Len() != len(xs) {
+			t.Fatalf("expect %d members, got %d", len(xs), acc.Len())
+		}
+		for _, x := range xs {
+			w, err := acc.MembershipWitness(x)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !acc.VerifyMembership(acc.Value, w, x) {
+				t.Fatal("membership witness verification failed")
+			}
+			if acc.VerifyMembership(acc.Value, w, xs[0], xs[1]) {
+				t.Fatal("witness of one member verified for another set")
+			}
+		}
+
+		// batch witness
+		w, err := acc.MembershipWitness(xs[2:5]...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !acc.VerifyMembership(acc.Value, w, xs[2:5]...) {
+			t.Fatal("batch membership witness verification failed")
+		}
+
+		outsider := HashToPrime([]byte("outsider"))
+		if _, err := acc.MembershipWitness(outsider); err != ErrNotMember {
+			t.Fatalf("expect ErrNotMember, got %v", err)
+		}
+		if _, err := acc.Add(xs[0]); err != ErrAlreadyMember {
+			t.Fatalf("expect ErrAlreadyMember, got %v", err)
+		}
+		if _, err := acc.Add(big.NewInt(15)); err != ErrNotPrime {
+			t.Fatalf("expect ErrNotPrime, got %v", err)
+		}
+	}
+}
+
+func TestNonMembership(t *testing.T) {
+	for _, acc := range newAccumulators(t) {
+		xs := elements("member", 10)
+		if _, err := acc.Add(xs...); err != nil {
+			t.Fatal(err)
+		}
+		ys := elements("outsider", 3)
+		for _, y := range ys {
+			w, err := acc.NonMembershipWitness(y)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if w.A.Cmp(y) >= 0 {
+				t.Fatal("coefficient of non-membership witness is not reduced")
+			}
+			if !acc.VerifyNonMembership(acc.Value, w, y) {
+				t.Fatal("non-membership witness verification failed")
+			}
+			if acc.VerifyNonMembership(acc.Value, w, xs[0]) {
+				t.Fatal("non-membership witness verified for a member")
+			}
+		}
+		w, err := acc.NonMembershipWitness(ys...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !acc.VerifyNonMembership(acc.Value, w, ys...) {
+			t.Fatal("batch non-membership witness verification failed")
+		}
+		if _, err := acc.NonMembershipWitness(ys[0], xs[3]); err != ErrAlreadyMember {
+			t.Fatalf("expect ErrAlreadyMember, got %v", err)
+		}
+	}
+}
+
+func TestDelete(t *testing.T) {
+	key, err := rsa.GenerateKey(secbit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	acc, err := New(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	accT, err := NewWithTrapdoor(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// same generator, both accumulators must agree on the value
+	accT.G = acc.G
+	accT.Value = acc.Value
+
+	xs := elements("member", 8)
+	for _, a := range []*Accumulator{acc, accT} {
+		if _, err := a.Add(xs...); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := a.Delete(xs[1], xs[4]); err != nil {
+			t.Fatal(err)
+		}
+		if a.IsMember(xs[1]) || !a.IsMember(xs[0]) || a.Len() != 6 {
+			t.Fatal("unexpected members after deletion")
+		}
+		if _, err := a.Delete(xs[1]); err != ErrNotMember {
+			t.Fatalf("expect ErrNotMember, got %v", err)
+		}
+	}
+	if acc.Value.Cmp(accT.Value) != 0 {
+		t.Fatal("deletion with trapdoor differs from recomputation")
+	}
+	w, err := acc.NonMembershipWitness(xs[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !acc.VerifyNonMembership(acc.Value, w, xs[1]) {
+		t.Fatal("deleted element is still a member")
+	}
+}
+
+// small primes may divide phi, the trapdoor then falls back to the product of members
+func TestSmallPrime(t *testing.T) {
+	var key *rsa.PrivateKey
+	for key == nil || new(big.Int).Mod(key.Phi, big.NewInt(3)).Sign() != 0 {
+		var err error
+		if key, err = rsa.GenerateKey(secbit); err != nil {
+			t.Fatal(err)
+		}
+	}
+	acc, err := NewWithTrapdoor(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	three, five := big.NewInt(3), big.NewInt(5)
+	if _, err := acc.Add(three, five); err != nil {
+		t.Fatal(err)
+	}
+	w, err := acc.MembershipWitness(three)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !acc.VerifyMembership(acc.Value, w, three) {
+		t.Fatal("membership witness of 3 rejected")
+	}
+	if _, err := acc.Delete(three); err != nil {
+		t.Fatal(err)
+	}
+	if acc.Value.Cmp(new(big.Int).Exp(acc.G, five, acc.N)) != 0 {
+		t.Fatal("wrong value after deleting 3")
+	}
+}
+
+func TestUpdateWitness(t *testing.T) {
+	for _, acc := range newAccumulators(t) {
+		xs := elements("member", 6)
+		if _, err := acc.Add(xs...); err != nil {
+			t.Fatal(err)
+		}
+		x, y := xs[0], HashToPrime([]byte("outsider"))
+		memW, err := acc.MembershipWitness(x)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nonW, err := acc.NonMembershipWitness(y)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// batch of additions, batch of deletions, and both in one update
+		added := elements("new", 5)
+		updates := make([]*Update, 0, 3)
+		upd, err := acc.Add(added[:3]...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		updates = append(updates, upd)
+		upd, err = acc.Delete(xs[1], xs[2], added[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		updates = append(updates, upd)
+		prev := acc.Value
+		addUpd, err := acc.Add(added[3:]...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		delUpd, err := acc.Delete(xs[3])
+		if err != nil {
+			t.Fatal(err)
+		}
+		updates = append(updates, &Update{Added: addUpd.Added, Deleted: delUpd.Deleted, Prev: prev, Value: acc.Value})
+
+		for _, upd := range updates {
+			if memW, err = acc.UpdateMembershipWitness(x, memW, upd); err != nil {
+				t.Fatal(err)
+			}
+			if !acc.VerifyMembership(upd.Value, memW, x) {
+				t.Fatal("updated membership witness verification failed")
+			}
+			if nonW, err = acc.UpdateNonMembershipWitness(y, nonW, upd); err != nil {
+				t.Fatal(err)
+			}
+			if !acc.VerifyNonMembership(upd.Value, nonW, y) {
+				t.Fatal("updated non-membership witness verification failed")
+			}
+		}
+
+		// witness of a deleted member can not be updated, neither of a newly added non-member
+		w, err := acc.MembershipWitness(xs[4])
+		if err != nil {
+			t.Fatal(err)
+		}
+		upd, err = acc.Delete(xs[4])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := acc.UpdateMembershipWitness(xs[4], w, upd); err != ErrNotMember {
+			t.Fatalf("expect ErrNotMember, got %v", err)
+		}
+		upd, err = acc.Add(y)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := acc.UpdateNonMembershipWitness(y, nonW, upd); err != ErrAlreadyMember {
+			t.Fatalf("expect ErrAlreadyMember, got %v", err)
+		}
+	}
+}
+
+func TestPoE(t *testing.T) {
+	acc := newAccumulators(t)[0]
+	x := new(big.Int).Lsh(big.NewInt(12345), 2000)
+	w := new(big.Int).Exp(acc.G, x, acc.N)
+	proof := acc.ProvePoE(acc.G, w, x)
+	if !acc.VerifyPoE(acc.G, w, x, proof) {
+		t.Fatal("PoE verification failed")
+	}
+	if acc.VerifyPoE(acc.G, w, new(big.Int).Add(x, one), proof) {
+		t.Fatal("PoE verified for a wrong exponent")
+	}
+	proof.Q.Add(proof.Q, one)
+	if acc.VerifyPoE(acc.G, w, x, proof) {
+		t.Fatal("tampered PoE verified")
+	}
+}
+
+func TestPoKE(t *testing.T) {
+	acc := newAccumulators(t)[0]
+	u, err := modular.RandomUnit(acc.N)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := new(big.Int).Lsh(big.NewInt(54321), 1000)
+	w := new(big.Int).Exp(u, x, acc.N)
+	proof := acc.ProvePoKE(u, w, x)
+	if !acc.VerifyPoKE(u, w, proof) {
+		t.Fatal("PoKE verification failed")
+	}
+	if acc.VerifyPoKE(u, new(big.Int).Exp(u, one, acc.N), proof) {
+		t.Fatal("PoKE verified for a wrong statement")
+	}
+	proof.R.Add(proof.R, one)
+	if acc.VerifyPoKE(u, w, proof) {
+		t.Fatal("tampered PoKE verified")
+	}
+}
+
+func TestBatchProofs(t *testing.T) {
+	for _, acc := range newAccumulators(t) {
+		xs := elements("member", 20)
+		if _, err := acc.Add(xs...); err != nil {
+			t.Fatal(err)
+		}
+		mem, err := acc.ProveMembership(xs[5:15]...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !acc.VerifyMembershipProof(acc.Value, mem, xs[5:15]...) {
+			t.Fatal("batched membership proof verification failed")
+		}
+		if acc.VerifyMembershipProof(acc.Value, mem, xs[5:14]...) {
+			t.Fatal("batched membership proof verified for a different set")
+		}
+
+		ys := elements("outsider", 10)
+		non, err := acc.ProveNonMembership(ys...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !acc.VerifyNonMembershipProof(acc.Value, non, ys...) {
+			t.Fatal("batched non-membership proof verification failed")
+		}
+		if acc.VerifyNonMembershipProof(acc.Value, non, ys[1:]...) {
+			t.Fatal("batched non-membership proof verified for a different set")
+		}
+		if _, err := acc.ProveNonMembership(ys[0], xs[0]); err != ErrAlreadyMember {
+			t.Fatalf("expect ErrAlreadyMember, got %v", err)
+		}
+	}
+}
+
+func BenchmarkVerifyMembership100(b *testing.B) {
+	acc := newAccumulators(b)[1]
+	xs := elements("member", 100)
+	acc.Add(xs...)
+	w, _ := acc.MembershipWitness(xs...)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		acc.VerifyMembership(acc.Value, w, xs...)
+	}
+}
+
+func BenchmarkVerifyMembershipProof100(b *testing.B) {
+	acc := newAccumulators(b)[1]
+	xs := elements("member", 100)
+	acc.Add(xs...)
+	proof, _ := acc.ProveMembership(xs...)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		acc.VerifyMembershipProof(acc.Value, proof, xs...)
+	}
+}
+
+func BenchmarkVerifyNonMembershipProof100(b *testing.B) {
+	acc := newAccumulators(b)[1]
+	acc.Add(elements("member", 100)...)
+	ys := elements("outsider", 100)
+	proof, _ := acc.ProveNonMembership(ys...)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		acc.VerifyNonMembershipProof(acc.Value, proof, ys...)
+	}
+}
@@ -0,0 +1,170 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/big"
+
+	"github.com/hongyanwang/crypto-lab/common/modular"
+)
+
+// PoE Wesolowski proof of exponentiation u^x = w as in Boneh-Bunz-Fisch
+// for challenge prime l = H(u, w, x), Q = u^(x/l), the verifier checks Q^l * u^(x mod l) = w
+type PoE struct {
+	Q *big.Int
+}
+
+// PoKE proof of knowledge of an integer exponent x with u^x = w (PoKE2 of Boneh-Bunz-Fisch), x is not revealed
+// z = G^x, l = H(u, w, z), alpha = H(u, w, z, l), Q = (u * G^alpha)^(x/l), R = x mod l
+// the verifier checks Q^l * (u * G^alpha)^R = w * z^alpha
+type PoKE struct {
+	Z *big.Int
+	Q *big.Int
+	R *big.Int
+}
+
+// MembershipProof W with W^(prod xs) = value and PoE of it
+// verification costs two short exponentiations instead of one by prod xs
+type MembershipProof struct {
+	W   *big.Int
+	PoE *PoE
+}
+
+// NonMembershipProof constant-size proof that none of xs is a member
+// for witness value^a * B^x = G, V = value^a is proven by PoKE and B^x = G * V^-1 by PoE
+type NonMembershipProof struct {
+	V    *big.Int
+	B    *big.Int
+	PoKE *PoKE
+	PoE  *PoE
+}
+
+// ProvePoE prove u^x = w for non-negative x
+func (pp *PublicParams) ProvePoE(u, w, x *big.Int) *PoE {
+	l := challengePrime("PoE", u, w, x)
+	q := new(big.Int).Div(x, l)
+	return &PoE{Q: new(big.Int).Exp(u, q, pp.N)}
+}
+
+// VerifyPoE verify proof of u^x = w
+func (pp *PublicParams) VerifyPoE(u, w, x *big.Int, proof *PoE) bool {
+	if proof == nil || x.Sign() < 0 || !modular.IsUnit(proof.Q, pp.N) {
+		return false
+	}
+	l := challengePrime("PoE", u, w, x)
+	r := new(big.Int).Mod(x, l)
+	lhs := new(big.Int).Exp(proof.Q, l, pp.N)
+	lhs.Mul(lhs, new(big.Int).Exp(u, r, pp.N))
+	lhs.Mod(lhs, pp.N)
+	return lhs.Cmp(w) == 0
+}
+
+// ProvePoKE prove knowledge of non-negative x with u^x = w
+func (pp *PublicParams) ProvePoKE(u, w, x *big.Int) *PoKE {
+	z := new(big.Int).Exp(pp.G, x, pp.N)
+	l := challengePrime("PoKE", u, w, z)
+	alpha := challengeInt("PoKE", u, w, z, l)
+	base := pp.pokeBase(u, alpha)
+	q, r := new(big.Int).DivMod(x, l, new(big.Int))
+	return &PoKE{Z: z, Q: base.Exp(base, q, pp.N), R: r}
+}
+
+// VerifyPoKE verify proof of knowledge of x with u^x = w
+func (pp *PublicParams) VerifyPoKE(u, w *big.Int, proof *PoKE) bool {
+	if proof == nil || !modular.IsUnit(proof.Z, pp.N) || !modular.IsUnit(proof.Q, pp.N) || proof.R == nil {
+		return false
+	}
+	l := challengePrime("PoKE", u, w, proof.Z)
+	if proof.R.Sign() < 0 || proof.R.Cmp(l) >= 0 {
+		return false
+	}
+	alpha := challengeInt("PoKE", u, w, proof.Z, l)
+	base := pp.pokeBase(u, alpha)
+	lhs := new(big.Int).Exp(proof.Q, l, pp.N)
+	lhs.Mul(lhs, base.Exp(base, proof.R, pp.N))
+	lhs.Mod(lhs, pp.N)
+	rhs := new(big.Int).Exp(proof.Z, alpha, pp.N)
+	rhs.Mul(rhs, w)
+	rhs.Mod(rhs, pp.N)
+	return lhs.Cmp(rhs) == 0
+}
+
+// pokeBase u * G^alpha
+func (pp *PublicParams) pokeBase(u, alpha *big.Int) *big.Int {
+	base := new(big.Int).Exp(pp.G, alpha, pp.N)
+	base.Mul(base, u)
+	return base.Mod(base, pp.N)
+}
+
+// ProveMembership batched membership proof of xs
+func (acc *Accumulator) ProveMembership(xs ...*big.Int) (*MembershipProof, error) {
+	w, err := acc.MembershipWitness(xs...)
+	if err != nil {
+		return nil, err
+	}
+	return &MembershipProof{W: w, PoE: acc.ProvePoE(w, acc.Value, product(xs))}, nil
+}
+
+// VerifyMembershipProof verify batched membership proof of xs against value
+func (pp *PublicParams) VerifyMembershipProof(value *big.Int, proof *MembershipProof, xs ...*big.Int) bool {
+	if proof == nil || !modular.IsUnit(proof.W, pp.N) {
+		return false
+	}
+	return pp.VerifyPoE(proof.W, value, product(xs), proof.PoE)
+}
+
+// ProveNonMembership batched non-membership proof of xs
+func (acc *Accumulator) ProveNonMembership(xs ...*big.Int) (*NonMembershipProof, error) {
+	w, err := acc.NonMembershipWitness(xs...)
+	if err != nil {
+		return nil, err
+	}
+	v := new(big.Int).Exp(acc.Value, w.A, acc.N)
+	gv := new(big.Int).ModInverse(v, acc.N)
+	gv.Mul(gv, acc.G)
+	gv.Mod(gv, acc.N)
+	return &NonMembershipProof{
+		V:    v,
+		B:    w.B,
+		PoKE: acc.ProvePoKE(acc.Value, v, w.A),
+		PoE:  acc.ProvePoE(w.B, gv, product(xs)),
+	}, nil
+}
+
+// VerifyNonMembershipProof verify batched non-membership proof of xs against value
+func (pp *PublicParams) VerifyNonMembershipProof(value *big.Int, proof *NonMembershipProof, xs ...*big.Int) bool {
+	if proof == nil || !modular.IsUnit(proof.V, pp.N) || !modular.IsUnit(proof.B, pp.N) {
+		return false
+	}
+	if !pp.VerifyPoKE(value, proof.V, proof.PoKE) {
+		return false
+	}
+	gv := new(big.Int).ModInverse(proof.V, pp.N)
+	gv.Mul(gv, pp.G)
+	gv.Mod(gv, pp.N)
+	return pp.VerifyPoE(proof.B, gv, product(xs), proof.PoE)
+}
+
+// challengePrime Fiat-Shamir challenge prime of PrimeBits bits
+func challengePrime(label string, values ...*big.Int) *big.Int {
+	return HashToPrime(transcript(label, values...))
+}
+
+// challengeInt Fiat-Shamir challenge integer of 256 bits
+func challengeInt(label string, values ...*big.Int) *big.Int {
+	h := sha256.Sum256(transcript(label, values...))
+	return new(big.Int).SetBytes(h[:])
+}
+
+// transcript label || len(v_1) || v_1 || ... with 4-byte big-endian lengths
+func transcript(label string, values ...*big.Int) []byte {
+	buf := []byte("accumulator:" + label)
+	var length [4]byte
+	for _, v := range values {
+		b := v.Bytes()
+		binary.BigEndian.PutUint32(length[:], uint32(len(b)))
+		buf = append(buf, length[:]...)
+		buf = append(buf, b...)
+	}
+	return buf
+}
@@ -0,0 +1,491 @@
+// Package distributed_rsa implements Boneh-Franklin distributed generation of RSA keys without a trusted dealer
+// n >= 3 semi-honest parties jointly generate N = PQ, no party learns P or Q
+//
+//  1. party i samples p_i, q_i, P = sum(p_i) and Q = sum(q_i) are 3 (mod 4)
+//  2. N = PQ is computed by BGW multiplication of Shamir shares over a prime field, N is public
+//  3. trial division of N and the distributed biprimality test reject candidates until N is a product of two primes
+//  4. parties derive additive shares of D = E^-1 (mod phi), revealing only phi (mod E)
+package distributed_rsa
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/hongyanwang/crypto-lab/advanced/ss/shamir"
+	"github.com/hongyanwang/crypto-lab/asymmetric/rsa"
+	"github.com/hongyanwang/crypto-lab/common/modular"
+)
+
+const (
+	// MinParties BGW multiplication of degree t polynomials needs 2t+1 parties with t >= 1
+	MinParties = 3
+	// biprimalityRounds a non-biprime N passes each round with probability at most 1/2
+	biprimalityRounds = 40
+	// field size margin in bits over products of shares
+	fieldMargin = 64
+)
+
+var (
+	one = big.NewInt(1)
+	e   = big.NewInt(rsa.DefaultE)
+)
+
+// KeyShare private output of a party
+// P and Q are additive shares of the factors, D is an additive share of the private exponent
+type KeyShare struct {
+	Index int
+	P, Q  *big.Int
+	D     *big.Int
+}
+
+// Party one participant of distributed key generation
+type Party struct {
+	Index  int
+	n, t   int
+	secbit int
+	net    *network
+
+	// prime fields of Shamir sharing, larger than every reconstructed product
+	// N is computed for many candidates in the small field, the gcd test runs once in the large field
+	field, largeField *shamirField
+	// additive shares of P and Q of the current candidate
+	pi, qi *big.Int
+}
+
+// NewParties create partyNum parties connected by in-process channels, Run each of them concurrently
+func NewParties(partyNum, secbit int) ([]*Party, error) {
+	if partyNum < MinParties {
+		return nil, fmt.Errorf("at least %d parties are required", MinParties)
+	}
+	if secbit < 256 {
+		return nil, fmt.Errorf("key size too small: %d", secbit)
+	}
+	net := newNetwork(partyNum)
+	parties := make([]*Party, partyNum)
+	for i := range parties {
+		parties[i] = &Party{
+			Index:  i + 1,
+			n:      partyNum,
+			t:      (partyNum - 1) / 2,
+			secbit: secbit,
+			net:    net,
+		}
+	}
+	return parties, nil
+}
+
+// Generate run distributed key generation with partyNum in-process parties
+func Generate(partyNum, secbit int) (*rsa.PublicKey, []*KeyShare, error) {
+	parties, err := NewParties(partyNum, secbit)
+	if err != nil {
+		return nil, nil, err
+	}
+	pubs := make([]*rsa.PublicKey, partyNum)
+	shares := make([]*KeyShare, partyNum)
+	errs := make([]error, partyNum)
+	var wg sync.WaitGroup
+	for i, party := range parties {
+		wg.Add(1)
+		go func(i int, party *Party) {
+			defer wg.Done()
+			pubs[i], shares[i], errs[i] = party.Run()
+		}(i, party)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil && err != ErrAborted {
+			return nil, nil, err
+		}
+	}
+	for _, pub := range pubs[1:] {
+		if pub.N.Cmp(pubs[0].N) != 0 {
+			return nil, nil, fmt.Errorf("parties disagree on N")
+		}
+	}
+	return pubs[0], shares, nil
+}
+
+// Run run the protocol until a key is generated, every party outputs the same public key
+func (p *Party) Run() (*rsa.PublicKey, *KeyShare, error) {
+	pub, share, err := p.run()
+	if err != nil {
+		p.net.abort()
+	}
+	return pub, share, err
+}
+
+func (p *Party) run() (*rsa.PublicKey, *KeyShare, error) {
+	if err := p.setupField(); err != nil {
+		return nil, nil, err
+	}
+	for {
+		n, ok, err := p.candidate()
+		if err != nil {
+			return nil, nil, err
+		}
+		if !ok {
+			continue
+		}
+		d, ok, err := p.privateExponent(n)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !ok {
+			continue
+		}
+		pub := &rsa.PublicKey{N: n, E: new(big.Int).Set(e)}
+		return pub, &KeyShare{Index: p.Index, P: p.pi, Q: p.qi, D: d}, nil
+	}
+}
+
+// setupField party 1 chooses the prime fields
+// P < 2^(secbit/2+1) so N < 2^(secbit+2) fits in secbit+64 bits, and r < n*N so (P+Q-1)*r fits in 2*secbit+64 bits
+func (p *Party) setupField() error {
+	var primes []*big.Int
+	if p.Index != 1 {
+		msg, err := p.receive(1)
+		if err != nil {
+			return err
+		}
+		primes = msg
+	} else {
+		for _, bits := range []int{p.secbit + fieldMargin, 2*p.secbit + fieldMargin} {
+			prime, err := rand.Prime(rand.Reader, bits)
+			if err != nil {
+				return err
+			}
+			primes = append(primes, prime)
+		}
+		for j := 2; j <= p.n; j++ {
+			if err := p.send(j, primes); err != nil {
+				return err
+			}
+		}
+	}
+	field, err := newShamirField(primes[0], p.n)
+	if err != nil {
+		return err
+	}
+	largeField, err := newShamirField(primes[1], p.n)
+	if err != nil {
+		return err
+	}
+	p.field, p.largeField = field, largeField
+	return nil
+}
+
+// candidate sample shares of P and Q, compute N and test it, ok is false if the candidate is rejected
+func (p *Party) candidate() (*big.Int, bool, error) {
+	// 1. p_i, q_i with P = Q = 3 (mod 4): p_1 = q_1 = 3 (mod 4), others 0 (mod 4)
+	// the sum of n shares is about 2^(secbit/2) so that N has secbit bits with fair probability
+	shareBits := p.secbit/2 - bitLen(p.n) + 1
+	var err error
+	if p.pi, err = p.sampleShare(shareBits); err != nil {
+		return nil, false, err
+	}
+	if p.qi, err = p.sampleShare(shareBits); err != nil {
+		return nil, false, err
+	}
+
+	// 2. Shamir shares of P and Q of degree t and of zero of degree 2t
+	shares, err := p.share(p.field, []*big.Int{p.pi, p.qi}, []int{p.t, p.t}, true)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// 3. N_j = P_j * Q_j + Z_j is a share of degree 2t, interpolate N
+	nj := new(big.Int).Mul(shares[0], shares[1])
+	nj.Add(nj, shares[2])
+	nj.Mod(nj, p.field.prime)
+	n, err := p.open(p.field, nj)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// 4. public checks, every party reaches the same decision
+	if n.BitLen() != p.secbit || !trialDivision(n) {
+		return nil, false, nil
+	}
+	ok, err := p.biprimalityTest(n)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	ok, err = p.gcdTest(n)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	return n, true, nil
+}
+
+// sampleShare random additive share of bits length, 3 (mod 4) for party 1 and 0 (mod 4) for others
+func (p *Party) sampleShare(bits int) (*big.Int, error) {
+	s, err := rand.Int(rand.Reader, new(big.Int).Lsh(one, uint(bits)))
+	if err != nil {
+		return nil, err
+	}
+	s.SetBit(s, 0, 0)
+	s.SetBit(s, 1, 0)
+	if p.Index == 1 {
+		s.SetBit(s, 0, 1)
+		s.SetBit(s, 1, 1)
+	}
+	return s, nil
+}
+
+// share Shamir-share secrets among parties with given degrees, each party gets the sum of all parties' shares
+// with zero set, a random degree 2t sharing of 0 is appended to re-randomize products
+func (p *Party) share(f *shamirField, secrets []*big.Int, degrees []int, zero bool) ([]*big.Int, error) {
+	if zero {
+		secrets = append(secrets, new(big.Int))
+		degrees = append(degrees, 2*p.t)
+	}
+	msgs := make([][]*big.Int, p.n)
+	for j := range msgs {
+		msgs[j] = make([]*big.Int, len(secrets))
+	}
+	for k, secret := range secrets {
+		ys, err := shamir.GenerateSharesModP(f.xs, degrees[k]+1, secret, f.prime)
+		if err != nil {
+			return nil, err
+		}
+		for j, y := range ys {
+			msgs[j][k] = y
+		}
+	}
+	received, err := p.exchange(msgs)
+	if err != nil {
+		return nil, err
+	}
+	sums := make([]*big.Int, len(secrets))
+	for k := range sums {
+		sums[k] = new(big.Int)
+		for _, msg := range received {
+			sums[k].Add(sums[k], msg[k])
+		}
+		sums[k].Mod(sums[k], f.prime)
+	}
+	return sums, nil
+}
+
+// open broadcast share and interpolate the secret from all n shares
+func (p *Party) open(f *shamirField, share *big.Int) (*big.Int, error) {
+	received, err := p.broadcast(share)
+	if err != nil {
+		return nil, err
+	}
+	ys := make([]*big.Int, p.n)
+	for j, msg := range received {
+		ys[j] = msg[0]
+	}
+	return shamir.InterpolateAtZero(f.lambda, ys, f.prime), nil
+}
+
+// biprimalityTest Boneh-Franklin test for N = PQ with P = Q = 3 (mod 4)
+// for random g with Jacobi symbol (g/N) = 1, g^((N-P-Q+1)/4) = +-1 (mod N) if P and Q are primes
+// party 1 computes v_1 = g^((N-p_1-q_1+1)/4), others v_i = g^((p_i+q_i)/4), check v_1 = +-mul(v_i)
+func (p *Party) biprimalityTest(n *big.Int) (bool, error) {
+	exp := new(big.Int).Add(p.pi, p.qi)
+	if p.Index == 1 {
+		exp.Sub(n, exp)
+		exp.Add(exp, one)
+	}
+	exp.Rsh(exp, 2)
+
+	for round := 0; round < biprimalityRounds; {
+		g, err := p.publicRandom(n)
+		if err != nil {
+			return false, err
+		}
+		if big.Jacobi(g, n) != 1 {
+			continue
+		}
+		round++
+
+		received, err := p.broadcast(new(big.Int).Exp(g, exp, n))
+		if err != nil {
+			return false, err
+		}
+		prod := big.NewInt(1)
+		for _, msg := range received[1:] {
+			prod.Mul(prod, msg[0])
+			prod.Mod(prod, n)
+		}
+		v1 := received[0][0]
+		if v1.Cmp(prod) != 0 && new(big.Int).Add(v1, prod).Cmp(n) != 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// gcdTest check gcd(N, P+Q-1) = 1 which the biprimality test assumes
+// z = (P+Q-1)*r for joint random r is computed by BGW multiplication, only z is revealed
+func (p *Party) gcdTest(n *big.Int) (bool, error) {
+	ri, err := rand.Int(rand.Reader, n)
+	if err != nil {
+		return false, err
+	}
+	shares, err := p.share(p.largeField, []*big.Int{p.pi, p.qi, ri}, []int{p.t, p.t, p.t}, true)
+	if err != nil {
+		return false, err
+	}
+	// shares of the public constant 1 are 1
+	zj := new(big.Int).Add(shares[0], shares[1])
+	zj.Sub(zj, one)
+	zj.Mul(zj, shares[2])
+	zj.Add(zj, shares[3])
+	zj.Mod(zj, p.largeField.prime)
+	z, err := p.open(p.largeField, zj)
+	if err != nil {
+		return false, err
+	}
+	return new(big.Int).GCD(nil, nil, z, n).Cmp(one) == 0, nil
+}
+
+// privateExponent additive shares of D = E^-1 (mod phi) with phi = N - P - Q + 1
+// phi_1 = N - p_1 - q_1 + 1 and phi_i = -(p_i + q_i), only phi (mod E) is revealed
+// zeta = -phi^-1 (mod E), D = (1 + zeta*phi) / E, d_1 = floor((1 + zeta*phi_1) / E), d_i = floor(zeta*phi_i / E)
+// the floors lose delta in [0, n), which is found by a test signature and added to d_1
+func (p *Party) privateExponent(n *big.Int) (*big.Int, bool, error) {
+	phi := new(big.Int).Add(p.pi, p.qi)
+	phi.Neg(phi)
+	if p.Index == 1 {
+		phi.Add(phi, n)
+		phi.Add(phi, one)
+	}
+
+	// phi (mod E) by summing random additive shares of phi_i (mod E)
+	masks := make([][]*big.Int, p.n)
+	rest := new(big.Int).Mod(phi, e)
+	for j := 1; j < p.n; j++ {
+		m, err := rand.Int(rand.Reader, e)
+		if err != nil {
+			return nil, false, err
+		}
+		masks[j] = []*big.Int{m}
+		rest.Sub(rest, m)
+	}
+	masks[0] = []*big.Int{rest.Mod(rest, e)}
+	received, err := p.exchange(masks)
+	if err != nil {
+		return nil, false, err
+	}
+	sum := new(big.Int)
+	for _, msg := range received {
+		sum.Add(sum, msg[0])
+	}
+	received, err = p.broadcast(sum.Mod(sum, e))
+	if err != nil {
+		return nil, false, err
+	}
+	phiModE := new(big.Int)
+	for _, msg := range received {
+		phiModE.Add(phiModE, msg[0])
+	}
+	phiModE.Mod(phiModE, e)
+	if phiModE.Sign() == 0 {
+		// E divides phi, start over with a new N
+		return nil, false, nil
+	}
+
+	zeta := new(big.Int).ModInverse(phiModE, e)
+	zeta.Sub(e, zeta)
+	d := new(big.Int).Mul(zeta, phi)
+	if p.Index == 1 {
+		d.Add(d, one)
+	}
+	// Euclidean division is floor division for positive E
+	d.Div(d, e)
+
+	// test signature on x = 2, (x^(D-delta) * x^delta)^E = x
+	x := big.NewInt(2)
+	received, err = p.broadcast(modular.ExpInt(x, d, n))
+	if err != nil {
+		return nil, false, err
+	}
+	y := big.NewInt(1)
+	for _, msg := range received {
+		y.Mul(y, msg[0])
+		y.Mod(y, n)
+	}
+	for delta := 0; delta < p.n; delta++ {
+		if new(big.Int).Exp(y, e, n).Cmp(x) == 0 {
+			if p.Index == 1 {
+				d.Add(d, big.NewInt(int64(delta)))
+			}
+			return d, true, nil
+		}
+		y.Mul(y, x)
+		y.Mod(y, n)
+	}
+	return nil, false, fmt.Errorf("failed to correct private exponent shares")
+}
+
+// publicRandom joint random element of Z_N, sum of contributions of all parties
+func (p *Party) publicRandom(n *big.Int) (*big.Int, error) {
+	gi, err := rand.Int(rand.Reader, n)
+	if err != nil {
+		return nil, err
+	}
+	received, err := p.broadcast(gi)
+	if err != nil {
+		return nil, err
+	}
+	g := new(big.Int)
+	for _, msg := range received {
+		g.Add(g, msg[0])
+	}
+	return g.Mod(g, n), nil
+}
+
+// SignShare partial RSA signature x^d_i (mod N) of message representative x
+func SignShare(pub *rsa.PublicKey, share *KeyShare, x *big.Int) *big.Int {
+	return modular.ExpInt(x, share.D, pub.N)
+}
+
+// Combine multiply partial signatures of all parties into RSA signature x^D (mod N)
+func Combine(pub *rsa.PublicKey, sigShares []*big.Int) *big.Int {
+	sig := big.NewInt(1)
+	for _, s := range sigShares {
+		sig.Mul(sig, s)
+		sig.Mod(sig, pub.N)
+	}
+	return sig
+}
+
+// trialDivision N has no prime factor below 2000
+func trialDivision(n *big.Int) bool {
+	r := new(big.Int)
+	for _, sp := range modular.SmallPrimes {
+		if r.Mod(n, big.NewInt(sp)).Sign() == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// shamirField prime field of Shamir sharing at points 1..n with their Lagrange coefficients at 0
+type shamirField struct {
+	prime  *big.Int
+	xs     []*big.Int
+	lambda []*big.Int
+}
+
+func newShamirField(prime *big.Int, n int) (*shamirField, error) {
+	xs := make([]*big.Int, n)
+	for i := range xs {
+		xs[i] = big.NewInt(int64(i + 1))
+	}
+	lambda, err := shamir.LagrangeCoefficients(xs, prime)
+	if err != nil {
+		return nil, err
+	}
+	return &shamirField{prime: prime, xs: xs, lambda: lambda}, nil
+}
+
+// bitLen number of bits of x
+func bitLen(x int) int {
+	return big.NewInt(int64(x)).BitLen()
+}
@@ -0,0 +1,79 @@
+package distributed_rsa
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/hongyanwang/crypto-lab/asymmetric/rsa"
+)
+
+var (
+	secbit = 512
+	msg    = big.NewInt(123456789)
+)
+
+func TestGenerate(t *testing.T) {
+	for _, partyNum := range []int{3, 5} {
+		pub, shares, err := Generate(partyNum, secbit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pub.N.BitLen() != secbit || pub.E.Int64() != rsa.DefaultE {
+			t.Fatalf("%d parties: unexpected public key", partyNum)
+		}
+
+		// P and Q are only known by summing all shares
+		P, Q := new(big.Int), new(big.Int)
+		for _, share := range shares {
+			P.Add(P, share.P)
+			Q.Add(Q, share.Q)
+		}
+		if !P.ProbablyPrime(20) || !Q.ProbablyPrime(20) || new(big.Int).Mul(P, Q).Cmp(pub.N) != 0 {
+			t.Fatalf("%d parties: N is not a product of two primes", partyNum)
+		}
+
+		// partial signatures of all parties combine into a standard RSA signature
+		sigShares := make([]*big.Int, partyNum)
+		for i, share := range shares {
+			sigShares[i] = SignShare(pub, share, msg)
+		}
+		sig := Combine(pub, sigShares)
+		if ok, err := rsa.RSAVerify(msg, sig, pub); err != nil || !ok {
+			t.Errorf("%d parties: combined signature verification failed: %v", partyNum, err)
+		}
+		if ok, _ := rsa.RSAVerify(msg, Combine(pub, sigShares[1:]), pub); ok {
+			t.Errorf("%d parties: signature without all shares verified", partyNum)
+		}
+
+		// the shares form a regular private key
+		D := new(big.Int)
+		for _, share := range shares {
+			D.Add(D, share.D)
+		}
+		key := &rsa.PrivateKey{
+			PublicKey: *pub,
+			P:         P,
+			Q:         Q,
+			Phi:       new(big.Int).Mul(new(big.Int).Sub(P, one), new(big.Int).Sub(Q, one)),
+			D:         D,
+		}
+		if err := key.Validate(); err != nil {
+			t.Errorf("%d parties: %v", partyNum, err)
+		}
+	}
+}
+
+func TestInvalidParams(t *testing.T) {
+	if _, _, err := Generate(2, secbit); err == nil {
+		t.Errorf("2 parties accepted")
+	}
+	if _, _, err := Generate(3, 128); err == nil {
+		t.Errorf("small key accepted")
+	}
+}
+
+func BenchmarkGenerate(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		Generate(3, secbit)
+	}
+}
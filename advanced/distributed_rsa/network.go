@@ -0,0 +1,93 @@
+package distributed_rsa
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+)
+
+var ErrAborted = errors.New("distributed_rsa: protocol aborted by another party")
+
+// network in-process point-to-point channels between parties
+// links[i][j] carries messages from party i+1 to party j+1 in order, so parties stay in lockstep
+type network struct {
+	links [][]chan []*big.Int
+	done  chan struct{}
+	once  sync.Once
+}
+
+func newNetwork(partyNum int) *network {
+	net := &network{
+		links: make([][]chan []*big.Int, partyNum),
+		done:  make(chan struct{}),
+	}
+	for i := range net.links {
+		net.links[i] = make([]chan []*big.Int, partyNum)
+		for j := range net.links[i] {
+			if i != j {
+				net.links[i][j] = make(chan []*big.Int, 1)
+			}
+		}
+	}
+	return net
+}
+
+// abort stop all parties, e.g. when one of them fails
+func (net *network) abort() {
+	net.once.Do(func() { close(net.done) })
+}
+
+// send message to party to
+func (p *Party) send(to int, msg []*big.Int) error {
+	select {
+	case p.net.links[p.Index-1][to-1] <- msg:
+		return nil
+	case <-p.net.done:
+		return ErrAborted
+	}
+}
+
+// receive next message from party from
+func (p *Party) receive(from int) ([]*big.Int, error) {
+	select {
+	case msg := <-p.net.links[from-1][p.Index-1]:
+		return msg, nil
+	case <-p.net.done:
+		return nil, ErrAborted
+	}
+}
+
+// exchange send msgs[j-1] to every party j and receive one message from every party
+// the result is indexed by sender, the own message is kept locally
+func (p *Party) exchange(msgs [][]*big.Int) ([][]*big.Int, error) {
+	for j := 1; j <= p.n; j++ {
+		if j == p.Index {
+			continue
+		}
+		if err := p.send(j, msgs[j-1]); err != nil {
+			return nil, err
+		}
+	}
+	received := make([][]*big.Int, p.n)
+	for j := 1; j <= p.n; j++ {
+		if j == p.Index {
+			received[j-1] = msgs[j-1]
+			continue
+		}
+		msg, err := p.receive(j)
+		if err != nil {
+			return nil, err
+		}
+		received[j-1] = msg
+	}
+	return received, nil
+}
+
+// broadcast send the same message to every party and receive the broadcasts of others
+func (p *Party) broadcast(msg ...*big.Int) ([][]*big.Int, error) {
+	msgs := make([][]*big.Int, p.n)
+	for j := range msgs {
+		msgs[j] = msg
+	}
+	return p.exchange(msgs)
+}
@@ -8,6 +8,7 @@ import (
 	"reflect"
 
 	"github.com/hongyanwang/crypto-lab/symmetric/aes"
+	"github.com/hongyanwang/crypto-lab/symmetric/sm4"
 )
 
 const (
@@ -16,6 +17,28 @@ const (
 	BLOCKLEN = 16
 )
 
+// CipherParams authenticated encryption used to encrypt gate outputs
+// the first KeyLen bytes of an input label are used as key, ciphertexts are nonce||ciphertext||tag
+type CipherParams struct {
+	Encrypt func(msg, key, additionalData []byte) ([]byte, error)
+	Decrypt func(ciphertext, key, additionalData []byte) ([]byte, error)
+	KeyLen  int
+}
+
+var (
+	CipherAES256 = &CipherParams{
+		Encrypt: aes.EncryptGCM,
+		Decrypt: aes.DecryptGCM,
+		KeyLen:  32,
+	}
+	CipherSM4 = &CipherParams{
+		Encrypt: sm4.EncryptGCM,
+		Decrypt: sm4.DecryptGCM,
+		KeyLen:  sm4.KeySize,
+	}
+	DefaultCipher = CipherAES256
+)
+
 // one gate is (X,Yg)->W
 type Gate struct {
 	X uint8
@@ -96,17 +119,23 @@ func GenerateEncGates(truthGates []Gate, encGateMap *EncGateMap) []EncGate {
 	return encGates
 }
 
-// EncOutputs encrypt output of each gate using inputs
+// EncOutputs encrypt output of each gate using inputs with DefaultCipher
 func EncOutputs(encGates []EncGate) ([][]byte, error) {
+	return EncOutputsWithCipher(encGates, DefaultCipher)
+}
+
+// EncOutputsWithCipher encrypt output of each gate using inputs
+// encXYW = Enc_X(Enc_Y(W)), Enc is authenticated so only the right row can be decrypted
+func EncOutputsWithCipher(encGates []EncGate, params *CipherParams) ([][]byte, error) {
 	var encWs [][]byte
 
 	for _, gate := range encGates {
-		encYW, err := aes.Encrypt(gate.EncW, gate.EncY)
+		encYW, err := encrypt(params, gate.EncY, gate.EncW)
 		if err != nil {
 			return nil, err
 		}
 
-		encXYW, err := aes.Encrypt(encYW, gate.EncX)
+		encXYW, err := encrypt(params, gate.EncX, encYW)
 		if err != nil {
 			return nil, err
 		}
@@ -116,27 +145,42 @@ func EncOutputs(encGates []EncGate) ([][]byte, error) {
 	return encWs, nil
 }
 
-// DecOutput decrypts encrypted outputs and get the valid result
+// DecOutput decrypts encrypted outputs with DefaultCipher and get the valid result
 func DecOutput(encX, encY []byte, encWs [][]byte) ([]byte, error) {
-	for i := 0; i < len(encWs); i++ {
-		cipher := encWs[i]
+	return DecOutputWithCipher(encX, encY, encWs, DefaultCipher)
+}
 
-		encYW, err := aes.Decrypt(cipher, encX)
+// DecOutputWithCipher decrypts encrypted outputs and get the valid result
+func DecOutputWithCipher(encX, encY []byte, encWs [][]byte, params *CipherParams) ([]byte, error) {
+	for i := 0; i < len(encWs); i++ {
+		encYW, err := decrypt(params, encX, encWs[i])
 		if err != nil {
 			continue
 		}
-		if len(encYW)%BLOCKLEN != 0 {
-			// to avoid the panic(input not full blocks)
-			continue
-		}
-		encW, err := aes.Decrypt(encYW, encY)
-		if err == nil && verifyEncW(encW) {
+		encW, err := decrypt(params, encY, encYW)
+		if err == nil && len(encW) == ENCLEN && verifyEncW(encW) {
 			return encW, nil
 		}
 	}
 	return nil, fmt.Errorf("did not find any valid encW")
 }
 
+// encrypt encrypt msg using the first KeyLen bytes of label as key
+func encrypt(params *CipherParams, label, msg []byte) ([]byte, error) {
+	if len(label) < params.KeyLen {
+		return nil, fmt.Errorf("label is shorter than key length %d", params.KeyLen)
+	}
+	return params.Encrypt(msg, label[:params.KeyLen], nil)
+}
+
+// decrypt verify and decrypt ciphertext, fails if label is not the key
+func decrypt(params *CipherParams, label, ciphertext []byte) ([]byte, error) {
+	if len(label) < params.KeyLen {
+		return nil, fmt.Errorf("label is shorter than key length %d", params.KeyLen)
+	}
+	return params.Decrypt(ciphertext, label[:params.KeyLen], nil)
+}
+
 // verifyEncW verify if encW is valid
 func verifyEncW(encW []byte) bool {
 	length := len(encW)
@@ -6,6 +6,14 @@ import (
 )
 
 func TestGC(t *testing.T) {
+	testGC(t, DefaultCipher)
+}
+
+func TestGCWithSM4(t *testing.T) {
+	testGC(t, CipherSM4)
+}
+
+func testGC(t *testing.T, params *CipherParams) {
 	// AND gate
 	// 1. create truth gates
 	gate0 := Gate{
@@ -40,7 +48,7 @@ func TestGC(t *testing.T) {
 	encGates := GenerateEncGates(truthGate, encMap)
 
 	// 4. encrypt outputs
-	encWs, err := EncOutputs(encGates)
+	encWs, err := EncOutputsWithCipher(encGates, params)
 	if err != nil {
 		t.Error(err)
 	}
@@ -51,7 +59,7 @@ func TestGC(t *testing.T) {
 	// 6. suppose bob got enc input1 = enc(1) using ot scheme
 	encInput1 := encMap.Ymap[1]
 
-	encW, err := DecOutput(encInput0, encInput1, encWs)
+	encW, err := DecOutputWithCipher(encInput0, encInput1, encWs, params)
 	if err != nil {
 		t.Error(err)
 	}
@@ -53,7 +53,7 @@ func Sign(partnerPubkeys []*rsa.PublicKey, privkey *rsa.PrivateKey, msg []byte)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate random k, err: %v", err)
 	}
-	hashPr := sha256.Sum256(privkey.E.Bytes())
+	hashPr := hashPublicKey(&privkey.PublicKey)
 	kHashPr := new(big.Int).Mul(k, new(big.Int).SetBytes(hashPr[:]))
 	kHashPr = kHashPr.Add(kHashPr, new(big.Int).SetBytes(msg))
 	kHashPr = kHashPr.Mod(kHashPr, minN)
@@ -82,7 +82,7 @@ func Sign(partnerPubkeys []*rsa.PublicKey, privkey *rsa.PrivateKey, msg []byte)
 		}
 		ss[i] = s
 
-		hashPi := sha256.Sum256(allPubkeys[i].E.Bytes())
+		hashPi := hashPublicKey(allPubkeys[i])
 		sHashPi := new(big.Int).Mul(s, new(big.Int).SetBytes(hashPi[:]))
 		eiLink := new(big.Int).Mul(es[i], linkKey)
 		add := new(big.Int).Add(sHashPi, eiLink)
@@ -115,11 +115,11 @@ func Sign(partnerPubkeys []*rsa.PublicKey, privkey *rsa.PrivateKey, msg []byte)
 	return ret, nil
 }
 
-// getLinkKey get link key based on private key, linkKey = hash(e)*hash(d) mod N
+// getLinkKey get link key based on private key, linkKey = hash(P)*hash(d) mod N
 func getLinkKey(privkey *rsa.PrivateKey, minN *big.Int) *big.Int {
-	hashE := sha256.Sum256(privkey.E.Bytes())
+	hashP := hashPublicKey(&privkey.PublicKey)
 	hashD := sha256.Sum256(privkey.D.Bytes())
-	linkKey := new(big.Int).Mul(new(big.Int).SetBytes(hashE[:]), new(big.Int).SetBytes(hashD[:]))
+	linkKey := new(big.Int).Mul(new(big.Int).SetBytes(hashP[:]), new(big.Int).SetBytes(hashD[:]))
 	linkKey = linkKey.Mod(linkKey, minN)
 	return linkKey
 }
@@ -134,3 +134,13 @@ func minN(keys []*rsa.PublicKey) *big.Int {
 	}
 	return min
 }
+
+// hashPublicKey hash(P_i) = hash(N || E), E alone does not identify a key since most keys share E = 65537
+func hashPublicKey(key *rsa.PublicKey) [sha256.Size]byte {
+	h := sha256.New()
+	h.Write(key.N.Bytes())
+	h.Write(key.E.Bytes())
+	var ret [sha256.Size]byte
+	h.Sum(ret[:0])
+	return ret
+}
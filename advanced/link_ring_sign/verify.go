@@ -23,7 +23,7 @@ func verify(sig, msg []byte) (bool, error) {
 	minN := minN(ringSign.PublicKeys)
 	// E_{i+1} = hash(s*hash(P_i)+E_i*linkKey+m)
 	for i := 0; i < len(ringSign.PublicKeys); i++ {
-		hashPi := sha256.Sum256(ringSign.PublicKeys[i].E.Bytes())
+		hashPi := hashPublicKey(ringSign.PublicKeys[i])
 		sHashPi := new(big.Int).Mul(ringSign.Ss[i], new(big.Int).SetBytes(hashPi[:]))
 		eiLink := new(big.Int).Mul(e, ringSign.LinkKey)
 		add := new(big.Int).Add(sHashPi, eiLink)
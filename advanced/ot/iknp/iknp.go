@@ -1,16 +1,15 @@
 package iknp
 
 import (
+	"crypto/aes"
 	"crypto/ecdsa"
-	"crypto/sha1"
 	"crypto/sha256"
+	"encoding/binary"
 	"fmt"
 	"math"
 	"math/big"
 	"math/rand"
 	"time"
-
-	"github.com/hongyanwang/crypto-lab/symmetric/aes"
 )
 
 // Sender has {(x1_1, x1_2,... x1_2^l)... (xm_1, xm_2,... xm_2^l)} m sets of data
@@ -22,7 +21,7 @@ var (
 	DefaultK   int64 = 28                // column number of Receiver's matrix T,U, 3*secBit< K <4*secBit, secBit defined as 8
 	DefaultMax       = big.NewInt(65536) // max value in the sample code, 2^16
 
-	DefaultAESKey = sha1.Sum([]byte("default aes key")) // shared AES key to calculate error correcting code C, key length is 128 bytes
+	DefaultAESKey = sha256.Sum256([]byte("default aes key")) // shared AES key to calculate error correcting code C, the first 128 bits are used
 )
 
 // CipherM encrypted message structure
@@ -156,37 +155,53 @@ func RO(r []*big.Int) []*big.Int {
 }
 
 // Cr calculates the value of C(r)
-// C is defined as C(x) = AES(1||x)||AES(2||x)||AES(3||x)||AES(4||x)
+// C is defined as C(x) = H(1,x)||H(2,x)||H(3,x)||H(4,x), where H is fixed-key AES in MMO mode:
+// H(i,x) = h_n with h_0 = i and h_j = AES(h_(j-1) xor x_j) xor h_(j-1) xor x_j for the zero padded blocks x_j of x,
+// the key is public and AES is only used as a random permutation
 // k is output length
 func Cr(r []*big.Int, k int64) ([]*big.Int, error) {
 	var rb []byte
 	for i := 0; i < len(r); i++ {
 		rb = append(rb, []byte(r[i].String())...)
 	}
+	if rem := len(rb) % aes.BlockSize; rem != 0 {
+		rb = append(rb, make([]byte, aes.BlockSize-rem)...)
+	}
 
-	var jointCipherHash []byte
+	block, err := aes.NewCipher(DefaultAESKey[:16])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AES cipher, err is: %v", err)
+	}
+	var jointHash []byte
+	c := make([]byte, aes.BlockSize)
 	for i := 1; i < 5; i++ {
-		msg := big.NewInt(int64(i)).Bytes()
-		msg = append(msg, rb...)
-		c, err := aes.Encrypt(msg, DefaultAESKey[:16])
-		if err != nil {
-			return nil, fmt.Errorf("failed to encrypt[%v], err is: %v", msg, err)
+		h := make([]byte, aes.BlockSize)
+		binary.BigEndian.PutUint32(h[aes.BlockSize-4:], uint32(i))
+		for j := 0; j < len(rb); j += aes.BlockSize {
+			xorBytes(h, rb[j:j+aes.BlockSize])
+			block.Encrypt(c, h)
+			xorBytes(h, c)
 		}
-
-		cHash := sha1.Sum(c)
-		jointCipherHash = append(jointCipherHash, cHash[:16]...)
+		jointHash = append(jointHash, h...)
 	}
 
 	byteLen := k / 8
-	if int64(len(jointCipherHash)) < byteLen {
+	if int64(len(jointHash)) < byteLen {
 		return nil, fmt.Errorf("invalid paramters")
 	}
-	finalB := jointCipherHash[:byteLen]
+	finalB := jointHash[:byteLen]
 	res := intToBits(new(big.Int).SetBytes(finalB), int(k))
 
 	return res, nil
 }
 
+// xorBytes dst = dst xor src
+func xorBytes(dst, src []byte) {
+	for i := range dst {
+		dst[i] ^= src[i]
+	}
+}
+
 // retrieve columns from rows
 func colsFromRows(rows [][]*big.Int) [][]*big.Int {
 	colNum := len(rows[0])
@@ -0,0 +1,151 @@
+package paillier_zk
+
+import (
+	"encoding/binary"
+	"math/big"
+
+	"github.com/hongyanwang/crypto-lab/asymmetric/paillier"
+	"github.com/hongyanwang/crypto-lab/common/modular"
+)
+
+// modProofRounds statistical soundness error 2^-modProofRounds
+const modProofRounds = 80
+
+// ModProof Paillier-Blum modulus proof, N = PQ with P = Q = 3 (mod 4) and gcd(N, phi(N)) = 1
+// for y_i = H(N, W, i): Z_i^N = y_i shows that N-th roots exist, i.e. N is square-free,
+// X_i^4 = (-1)^A_i * W^B_i * y_i shows that fourth roots exist up to -1 and W, i.e. N is a Blum integer
+type ModProof struct {
+	W    *big.Int // Jacobi symbol (W/N) = -1
+	X, Z []*big.Int
+	A, B []bool
+}
+
+// ProveModulus prove the modulus of a paillier key generated by paillier.GenerateBlumKey or with safe primes
+func ProveModulus(ssid []byte, prvkey *paillier.PrivateKey) (*ModProof, error) {
+	p, q, n := prvkey.P, prvkey.Q, prvkey.N
+	if p.Bit(0) != 1 || p.Bit(1) != 1 || q.Bit(0) != 1 || q.Bit(1) != 1 || p.Cmp(q) == 0 {
+		return nil, ErrInvalidKey
+	}
+	phi := new(big.Int).Mul(new(big.Int).Sub(p, one), new(big.Int).Sub(q, one))
+	nInv := new(big.Int).ModInverse(n, phi)
+	if nInv == nil {
+		return nil, ErrInvalidKey
+	}
+
+	var w *big.Int
+	for w == nil || big.Jacobi(w, n) != -1 {
+		var err error
+		if w, err = modular.RandomUnit(n); err != nil {
+			return nil, err
+		}
+	}
+
+	// fourth root of a square mod Blum prime p is a^(((p+1)/4)^2)
+	expP := fourthRootExp(p)
+	expQ := fourthRootExp(q)
+	minusOne := new(big.Int).Sub(n, one)
+
+	proof := &ModProof{
+		W: w,
+		X: make([]*big.Int, modProofRounds),
+		Z: make([]*big.Int, modProofRounds),
+		A: make([]bool, modProofRounds),
+		B: make([]bool, modProofRounds),
+	}
+	for i := 0; i < modProofRounds; i++ {
+		y := modChallenge(ssid, n, w, i)
+		if !modular.IsUnit(y, n) {
+			return nil, ErrInvalidKey
+		}
+		proof.Z[i] = new(big.Int).Exp(y, nInv, n)
+
+		// exactly one of y, -y, W*y, -W*y is a square mod both P and Q
+		for k := 0; k < 4; k++ {
+			a, b := k&1 == 1, k&2 == 2
+			yy := new(big.Int).Set(y)
+			if a {
+				yy.Mul(yy, minusOne)
+			}
+			if b {
+				yy.Mul(yy, w)
+			}
+			yy.Mod(yy, n)
+			if big.Jacobi(yy, p) != 1 || big.Jacobi(yy, q) != 1 {
+				continue
+			}
+			xp := new(big.Int).Exp(yy, expP, p)
+			xq := new(big.Int).Exp(yy, expQ, q)
+			proof.X[i] = crt(xp, xq, prvkey)
+			proof.A[i], proof.B[i] = a, b
+			break
+		}
+		if proof.X[i] == nil {
+			return nil, ErrInvalidKey
+		}
+	}
+	return proof, nil
+}
+
+// VerifyModulus verify that N of pubkey is square-free and a Blum integer
+func VerifyModulus(ssid []byte, pubkey *paillier.PublicKey, proof *ModProof) bool {
+	if !validKey(pubkey) || proof == nil {
+		return false
+	}
+	n := pubkey.N
+	if n.Bit(0) == 0 || n.ProbablyPrime(20) {
+		return false
+	}
+	if len(proof.X) != modProofRounds || len(proof.Z) != modProofRounds || len(proof.A) != modProofRounds || len(proof.B) != modProofRounds {
+		return false
+	}
+	if !modular.IsUnit(proof.W, n) || big.Jacobi(proof.W, n) != -1 {
+		return false
+	}
+	four := big.NewInt(4)
+	for i := 0; i < modProofRounds; i++ {
+		if !modular.IsUnit(proof.X[i], n) || !modular.IsUnit(proof.Z[i], n) {
+			return false
+		}
+		y := modChallenge(ssid, n, proof.W, i)
+		if new(big.Int).Exp(proof.Z[i], n, n).Cmp(y) != 0 {
+			return false
+		}
+		yy := new(big.Int).Set(y)
+		if proof.A[i] {
+			yy.Neg(yy)
+		}
+		if proof.B[i] {
+			yy.Mul(yy, proof.W)
+		}
+		yy.Mod(yy, n)
+		if new(big.Int).Exp(proof.X[i], four, n).Cmp(yy) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// modChallenge y_i = H(ssid, N, W, i) in Z_N
+func modChallenge(ssid []byte, n, w *big.Int, i int) *big.Int {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], uint32(i))
+	return hashToZN(append(transcript("mod", ssid, n, w), b[:]...), n)
+}
+
+// fourthRootExp ((p+1)/4)^2 (mod p-1)
+func fourthRootExp(p *big.Int) *big.Int {
+	e := new(big.Int).Add(p, one)
+	e.Rsh(e, 2)
+	e.Mul(e, e)
+	return e.Mod(e, new(big.Int).Sub(p, one))
+}
+
+// crt x = xp (mod P), x = xq (mod Q)
+func crt(xp, xq *big.Int, prvkey *paillier.PrivateKey) *big.Int {
+	x := new(big.Int).Sub(xq, xp)
+	x.Mul(x, prvkey.PinvQ)
+	x.Mod(x, prvkey.Q)
+	x.Mul(x, prvkey.P)
+	x.Add(x, xp)
+	return x.Mod(x, prvkey.N)
+}
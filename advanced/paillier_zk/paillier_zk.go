@@ -0,0 +1,89 @@
+// Package paillier_zk implements non-interactive zero-knowledge proofs for Paillier keys and ciphertexts
+//
+//   - ModProof: N is square-free and a Blum integer (Paillier-Blum modulus proof of CGGMP21)
+//   - PlaintextProof: knowledge of m and r with c = (1+N)^m * r^N
+//   - BitProof: c encrypts 0 or 1
+//   - RangeProof: c encrypts m in [0, 2^l)
+//
+// all proofs are made non-interactive by Fiat-Shamir and bound to a session identifier ssid,
+// multi-party protocols should use a unique ssid per session and prover, e.g. including the party index
+package paillier_zk
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/hongyanwang/crypto-lab/asymmetric/paillier"
+	"github.com/hongyanwang/crypto-lab/common/modular"
+)
+
+// challengeBits bit length of Fiat-Shamir challenges, far below the factors of N
+const challengeBits = 256
+
+var (
+	ErrInvalidKey     = errors.New("paillier_zk: invalid paillier key")
+	ErrInvalidWitness = errors.New("paillier_zk: witness does not match the statement")
+
+	one          = big.NewInt(1)
+	challengeMod = new(big.Int).Lsh(one, challengeBits)
+)
+
+// Encrypt paillier encryption c = G^m * r^N (mod N^2) that also outputs the randomness r needed by provers
+// the ciphertext is the same as paillier.Encrypt
+func Encrypt(pubkey *paillier.PublicKey, m *big.Int) (c, r *big.Int, err error) {
+	if m.Sign() < 0 || m.Cmp(pubkey.N) >= 0 {
+		return nil, nil, errors.New("message must be in [0, N)")
+	}
+	if r, err = modular.RandomUnit(pubkey.N); err != nil {
+		return nil, nil, err
+	}
+	return paillier.EncryptWithNonce(m, r, pubkey), r, nil
+}
+
+// validKey G = N+1 and NN = N^2, which the proofs rely on
+func validKey(pubkey *paillier.PublicKey) bool {
+	if pubkey == nil || pubkey.N == nil || pubkey.G == nil || pubkey.NN == nil || pubkey.N.Cmp(one) <= 0 {
+		return false
+	}
+	return pubkey.G.Cmp(new(big.Int).Add(pubkey.N, one)) == 0 && pubkey.NN.Cmp(new(big.Int).Mul(pubkey.N, pubkey.N)) == 0
+}
+
+// transcript label || len(ssid) || ssid || len(v_1) || v_1 || ... with 4-byte big-endian lengths
+func transcript(label string, ssid []byte, values ...*big.Int) []byte {
+	buf := []byte("paillier_zk:" + label)
+	buf = appendBytes(buf, ssid)
+	for _, v := range values {
+		buf = appendBytes(buf, v.Bytes())
+	}
+	return buf
+}
+
+func appendBytes(buf, data []byte) []byte {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], uint32(len(data)))
+	return append(append(buf, b[:]...), data...)
+}
+
+// challenge Fiat-Shamir challenge in [0, 2^challengeBits)
+func challenge(label string, ssid []byte, values ...*big.Int) *big.Int {
+	h := sha256.Sum256(transcript(label, ssid, values...))
+	return new(big.Int).SetBytes(h[:])
+}
+
+// hashToZN element of Z_N from SHA-256(data || counter) expanded to 128 bits over N
+func hashToZN(data []byte, n *big.Int) *big.Int {
+	size := (n.BitLen()+7)/8 + 16
+	buf := make([]byte, 0, size+sha256.Size)
+	var counter [4]byte
+	for i := uint32(0); len(buf) < size; i++ {
+		binary.BigEndian.PutUint32(counter[:], i)
+		h := sha256.New()
+		h.Write(data)
+		h.Write(counter[:])
+		buf = h.Sum(buf)
+	}
+	x := new(big.Int).SetBytes(buf[:size])
+	return x.Mod(x, n)
+}
@@ -0,0 +1,231 @@
+package paillier_zk
+
+import (
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/hongyanwang/crypto-lab/asymmetric/paillier"
+)
+
+var secbit = 1024
+
+var (
+	keyOnce sync.Once
+	testKey *paillier.PrivateKey
+)
+
+func blumKey(t testing.TB) *paillier.PrivateKey {
+	keyOnce.Do(func() {
+		var err error
+		if testKey, err = paillier.GenerateBlumKey(secbit); err != nil {
+			t.Fatal(err)
+		}
+	})
+	return testKey
+}
+
+func TestModulus(t *testing.T) {
+	prvkey := blumKey(t)
+	ssid := []byte("session-1")
+	proof, err := ProveModulus(ssid, prvkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !VerifyModulus(ssid, &prvkey.PublicKey, proof) {
+		t.Fatal("valid modulus proof rejected")
+	}
+	if VerifyModulus([]byte("session-2"), &prvkey.PublicKey, proof) {
+		t.Fatal("modulus proof accepted for another session")
+	}
+	proof.A[0] = !proof.A[0]
+	if VerifyModulus(ssid, &prvkey.PublicKey, proof) {
+		t.Fatal("tampered modulus proof accepted")
+	}
+	proof.A[0] = !proof.A[0]
+	proof.Z = proof.Z[1:]
+	if VerifyModulus(ssid, &prvkey.PublicKey, proof) {
+		t.Fatal("truncated modulus proof accepted")
+	}
+
+	var other *paillier.PrivateKey
+	for {
+		if other, err = paillier.GenerateKey(secbit); err != nil {
+			t.Fatal(err)
+		}
+		if other.P.Bit(1) == 0 || other.Q.Bit(1) == 0 {
+			break
+		}
+	}
+	if _, err := ProveModulus(ssid, other); err != ErrInvalidKey {
+		t.Fatalf("expected ErrInvalidKey for non-Blum key, got %v", err)
+	}
+}
+
+func TestPlaintext(t *testing.T) {
+	prvkey := blumKey(t)
+	pub := &prvkey.PublicKey
+	ssid := []byte("session-1")
+	m := big.NewInt(123456789)
+	c, r, err := Encrypt(pub, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret, _ := paillier.Decrypt(c, prvkey); ret.Cmp(m) != 0 {
+		t.Fatal("Encrypt is not compatible with paillier.Decrypt")
+	}
+	proof, err := ProvePlaintext(ssid, pub, c, m, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !VerifyPlaintext(ssid, pub, c, proof) {
+		t.Fatal("valid plaintext proof rejected")
+	}
+	if VerifyPlaintext([]byte("session-2"), pub, c, proof) {
+		t.Fatal("plaintext proof accepted for another session")
+	}
+	c2, _, _ := Encrypt(pub, m)
+	if VerifyPlaintext(ssid, pub, c2, proof) {
+		t.Fatal("plaintext proof accepted for another ciphertext")
+	}
+	if _, err := ProvePlaintext(ssid, pub, c, big.NewInt(1), r); err != ErrInvalidWitness {
+		t.Fatalf("expected ErrInvalidWitness, got %v", err)
+	}
+
+	zero, rho, _ := Encrypt(pub, big.NewInt(0))
+	root, err := ProveNthRoot(ssid, pub, zero, rho)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !VerifyNthRoot(ssid, pub, zero, root) {
+		t.Fatal("valid N-th root proof rejected")
+	}
+	if VerifyNthRoot(ssid, pub, c, root) {
+		t.Fatal("N-th root proof accepted for non-zero plaintext")
+	}
+}
+
+func TestBit(t *testing.T) {
+	prvkey := blumKey(t)
+	pub := &prvkey.PublicKey
+	ssid := []byte("session-1")
+	for b := 0; b < 2; b++ {
+		c, r, err := Encrypt(pub, big.NewInt(int64(b)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		proof, err := ProveBit(ssid, pub, c, b, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !VerifyBit(ssid, pub, c, proof) {
+			t.Fatalf("valid bit proof of %d rejected", b)
+		}
+		if VerifyBit([]byte("session-2"), pub, c, proof) {
+			t.Fatal("bit proof accepted for another session")
+		}
+		proof.E[0], proof.E[1] = proof.E[1], proof.E[0]
+		if VerifyBit(ssid, pub, c, proof) {
+			t.Fatal("tampered bit proof accepted")
+		}
+	}
+
+	// a proof for an encryption of 2 cannot be made, and a proof for bit 1 does not transfer
+	c, r, _ := Encrypt(pub, big.NewInt(2))
+	if _, err := ProveBit(ssid, pub, c, 1, r); err != ErrInvalidWitness {
+		t.Fatalf("expected ErrInvalidWitness, got %v", err)
+	}
+	c1, r1, _ := Encrypt(pub, big.NewInt(1))
+	proof, _ := ProveBit(ssid, pub, c1, 1, r1)
+	if VerifyBit(ssid, pub, c, proof) {
+		t.Fatal("bit proof accepted for encryption of 2")
+	}
+}
+
+func TestRange(t *testing.T) {
+	prvkey := blumKey(t)
+	pub := &prvkey.PublicKey
+	ssid := []byte("session-1")
+	bits := 16
+	for _, m := range []int64{0, 1, 40000, 65535} {
+		c, r, err := Encrypt(pub, big.NewInt(m))
+		if err != nil {
+			t.Fatal(err)
+		}
+		proof, err := ProveRange(ssid, pub, c, big.NewInt(m), r, bits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !VerifyRange(ssid, pub, c, bits, proof) {
+			t.Fatalf("valid range proof of %d rejected", m)
+		}
+		if VerifyRange(ssid, pub, c, bits-1, proof) {
+			t.Fatal("range proof accepted for another range")
+		}
+		if VerifyRange([]byte("session-2"), pub, c, bits, proof) {
+			t.Fatal("range proof accepted for another session")
+		}
+	}
+
+	c, r, _ := Encrypt(pub, big.NewInt(65536))
+	if _, err := ProveRange(ssid, pub, c, big.NewInt(65536), r, bits); err != ErrInvalidWitness {
+		t.Fatalf("expected ErrInvalidWitness, got %v", err)
+	}
+
+	// a proof for one ciphertext does not verify for another
+	c1, r1, _ := Encrypt(pub, big.NewInt(7))
+	proof, err := ProveRange(ssid, pub, c1, big.NewInt(7), r1, bits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, _, _ := Encrypt(pub, big.NewInt(7))
+	if VerifyRange(ssid, pub, c2, bits, proof) {
+		t.Fatal("range proof accepted for another ciphertext")
+	}
+	proof.C[0], proof.C[1] = proof.C[1], proof.C[0]
+	if VerifyRange(ssid, pub, c1, bits, proof) {
+		t.Fatal("tampered range proof accepted")
+	}
+}
+
+func BenchmarkProveModulus(b *testing.B) {
+	prvkey := blumKey(b)
+	ssid := []byte("session")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ProveModulus(ssid, prvkey)
+	}
+}
+
+func BenchmarkVerifyModulus(b *testing.B) {
+	prvkey := blumKey(b)
+	ssid := []byte("session")
+	proof, _ := ProveModulus(ssid, prvkey)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		VerifyModulus(ssid, &prvkey.PublicKey, proof)
+	}
+}
+
+func BenchmarkProveRange(b *testing.B) {
+	prvkey := blumKey(b)
+	ssid := []byte("session")
+	m := big.NewInt(12345)
+	c, r, _ := Encrypt(&prvkey.PublicKey, m)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ProveRange(ssid, &prvkey.PublicKey, c, m, r, 32)
+	}
+}
+
+func BenchmarkVerifyRange(b *testing.B) {
+	prvkey := blumKey(b)
+	ssid := []byte("session")
+	m := big.NewInt(12345)
+	c, r, _ := Encrypt(&prvkey.PublicKey, m)
+	proof, _ := ProveRange(ssid, &prvkey.PublicKey, c, m, r, 32)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		VerifyRange(ssid, &prvkey.PublicKey, c, 32, proof)
+	}
+}
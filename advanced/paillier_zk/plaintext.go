@@ -0,0 +1,104 @@
+package paillier_zk
+
+import (
+	"crypto/rand"
+	"math/big"
+
+	"github.com/hongyanwang/crypto-lab/asymmetric/paillier"
+	"github.com/hongyanwang/crypto-lab/common/modular"
+)
+
+// PlaintextProof proof of knowledge of m in Z_N and r in Z_N* with c = (1+N)^m * r^N (mod N^2)
+// A = (1+N)^alpha * beta^N, e = H(N, c, A), Z1 = alpha + e*m (mod N), Z2 = beta * r^e (mod N)
+// the verifier checks (1+N)^Z1 * Z2^N = A * c^e (mod N^2)
+type PlaintextProof struct {
+	A, Z1, Z2 *big.Int
+}
+
+// NthRootProof proof of knowledge of rho with u = rho^N (mod N^2), i.e. u encrypts 0
+// A = s^N, e = H(N, u, A), Z = s * rho^e (mod N), the verifier checks Z^N = A * u^e (mod N^2)
+type NthRootProof struct {
+	A, Z *big.Int
+}
+
+// ProvePlaintext prove knowledge of plaintext m and randomness r of ciphertext c, e.g. output by Encrypt
+func ProvePlaintext(ssid []byte, pubkey *paillier.PublicKey, c, m, r *big.Int) (*PlaintextProof, error) {
+	if !validKey(pubkey) {
+		return nil, ErrInvalidKey
+	}
+	if m.Sign() < 0 || m.Cmp(pubkey.N) >= 0 || !modular.IsUnit(r, pubkey.N) || paillier.EncryptWithNonce(m, r, pubkey).Cmp(c) != 0 {
+		return nil, ErrInvalidWitness
+	}
+	alpha, err := rand.Int(rand.Reader, pubkey.N)
+	if err != nil {
+		return nil, err
+	}
+	beta, err := modular.RandomUnit(pubkey.N)
+	if err != nil {
+		return nil, err
+	}
+	a := paillier.EncryptWithNonce(alpha, beta, pubkey)
+	e := challenge("plaintext", ssid, pubkey.N, c, a)
+
+	// (1+N)^N = 1 (mod N^2), so Z1 is reduced mod N without correcting Z2
+	z1 := new(big.Int).Mul(e, m)
+	z1.Add(z1, alpha)
+	z1.Mod(z1, pubkey.N)
+	z2 := new(big.Int).Exp(r, e, pubkey.N)
+	z2.Mul(z2, beta)
+	z2.Mod(z2, pubkey.N)
+	return &PlaintextProof{A: a, Z1: z1, Z2: z2}, nil
+}
+
+// VerifyPlaintext verify that the prover knows the plaintext of c
+func VerifyPlaintext(ssid []byte, pubkey *paillier.PublicKey, c *big.Int, proof *PlaintextProof) bool {
+	if !validKey(pubkey) || proof == nil || !modular.IsUnit(c, pubkey.NN) || !modular.IsUnit(proof.A, pubkey.NN) {
+		return false
+	}
+	if proof.Z1 == nil || proof.Z1.Sign() < 0 || proof.Z1.Cmp(pubkey.N) >= 0 || !modular.IsUnit(proof.Z2, pubkey.N) {
+		return false
+	}
+	e := challenge("plaintext", ssid, pubkey.N, c, proof.A)
+	lhs := paillier.EncryptWithNonce(proof.Z1, proof.Z2, pubkey)
+	rhs := new(big.Int).Exp(c, e, pubkey.NN)
+	rhs.Mul(rhs, proof.A)
+	rhs.Mod(rhs, pubkey.NN)
+	return lhs.Cmp(rhs) == 0
+}
+
+// ProveNthRoot prove knowledge of rho with u = rho^N (mod N^2)
+func ProveNthRoot(ssid []byte, pubkey *paillier.PublicKey, u, rho *big.Int) (*NthRootProof, error) {
+	if !validKey(pubkey) {
+		return nil, ErrInvalidKey
+	}
+	if !modular.IsUnit(rho, pubkey.N) || new(big.Int).Exp(rho, pubkey.N, pubkey.NN).Cmp(u) != 0 {
+		return nil, ErrInvalidWitness
+	}
+	s, err := modular.RandomUnit(pubkey.N)
+	if err != nil {
+		return nil, err
+	}
+	a := new(big.Int).Exp(s, pubkey.N, pubkey.NN)
+	e := challenge("nthroot", ssid, pubkey.N, u, a)
+	z := new(big.Int).Exp(rho, e, pubkey.N)
+	z.Mul(z, s)
+	return &NthRootProof{A: a, Z: z.Mod(z, pubkey.N)}, nil
+}
+
+// VerifyNthRoot verify that u is an N-th power mod N^2, i.e. an encryption of 0
+func VerifyNthRoot(ssid []byte, pubkey *paillier.PublicKey, u *big.Int, proof *NthRootProof) bool {
+	if !validKey(pubkey) || proof == nil || !modular.IsUnit(u, pubkey.NN) || !modular.IsUnit(proof.A, pubkey.NN) || !modular.IsUnit(proof.Z, pubkey.N) {
+		return false
+	}
+	e := challenge("nthroot", ssid, pubkey.N, u, proof.A)
+	return verifyNthRoot(pubkey, u, proof.A, e, proof.Z)
+}
+
+// verifyNthRoot Z^N = A * u^e (mod N^2)
+func verifyNthRoot(pubkey *paillier.PublicKey, u, a, e, z *big.Int) bool {
+	lhs := new(big.Int).Exp(z, pubkey.N, pubkey.NN)
+	rhs := new(big.Int).Exp(u, e, pubkey.NN)
+	rhs.Mul(rhs, a)
+	rhs.Mod(rhs, pubkey.NN)
+	return lhs.Cmp(rhs) == 0
+}
@@ -0,0 +1,172 @@
+package paillier_zk
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+
+	"github.com/hongyanwang/crypto-lab/asymmetric/paillier"
+	"github.com/hongyanwang/crypto-lab/common/modular"
+)
+
+// BitProof proof that c encrypts 0 or 1, an OR of N-th root proofs for u_0 = c and u_1 = c / (1+N)
+// the branch of the other bit is simulated, challenges satisfy E[0] + E[1] = H(N, c, A[0], A[1]) (mod 2^256)
+type BitProof struct {
+	A, E, Z [2]*big.Int
+}
+
+// RangeProof proof that c encrypts m in [0, 2^l)
+// C[i] encrypts bit i of m with a BitProof, and c / prod( C[i]^(2^i) ) encrypts 0, shown by an NthRootProof
+type RangeProof struct {
+	C    []*big.Int
+	Bits []*BitProof
+	Zero *NthRootProof
+}
+
+// ProveBit prove that c = (1+N)^b * r^N encrypts bit b
+func ProveBit(ssid []byte, pubkey *paillier.PublicKey, c *big.Int, b int, r *big.Int) (*BitProof, error) {
+	if !validKey(pubkey) {
+		return nil, ErrInvalidKey
+	}
+	if (b != 0 && b != 1) || !modular.IsUnit(r, pubkey.N) || paillier.EncryptWithNonce(big.NewInt(int64(b)), r, pubkey).Cmp(c) != 0 {
+		return nil, ErrInvalidWitness
+	}
+	u := bitBranches(pubkey, c)
+	proof := &BitProof{}
+
+	// simulate branch 1-b: A = Z^N * u^-E for random E and Z
+	sim := 1 - b
+	var err error
+	if proof.E[sim], err = rand.Int(rand.Reader, challengeMod); err != nil {
+		return nil, err
+	}
+	if proof.Z[sim], err = modular.RandomUnit(pubkey.N); err != nil {
+		return nil, err
+	}
+	a := new(big.Int).Exp(proof.Z[sim], pubkey.N, pubkey.NN)
+	a.Mul(a, modular.ExpInt(u[sim], new(big.Int).Neg(proof.E[sim]), pubkey.NN))
+	proof.A[sim] = a.Mod(a, pubkey.NN)
+
+	// real branch b: u_b = r^N
+	s, err := modular.RandomUnit(pubkey.N)
+	if err != nil {
+		return nil, err
+	}
+	proof.A[b] = new(big.Int).Exp(s, pubkey.N, pubkey.NN)
+	e := challenge("bit", ssid, pubkey.N, c, proof.A[0], proof.A[1])
+	proof.E[b] = e.Sub(e, proof.E[sim])
+	proof.E[b].Mod(proof.E[b], challengeMod)
+	z := new(big.Int).Exp(r, proof.E[b], pubkey.N)
+	z.Mul(z, s)
+	proof.Z[b] = z.Mod(z, pubkey.N)
+	return proof, nil
+}
+
+// VerifyBit verify that c encrypts 0 or 1
+func VerifyBit(ssid []byte, pubkey *paillier.PublicKey, c *big.Int, proof *BitProof) bool {
+	if !validKey(pubkey) || proof == nil || !modular.IsUnit(c, pubkey.NN) {
+		return false
+	}
+	for j := 0; j < 2; j++ {
+		if !modular.IsUnit(proof.A[j], pubkey.NN) || !modular.IsUnit(proof.Z[j], pubkey.N) || proof.E[j] == nil {
+			return false
+		}
+		if proof.E[j].Sign() < 0 || proof.E[j].Cmp(challengeMod) >= 0 {
+			return false
+		}
+	}
+	e := challenge("bit", ssid, pubkey.N, c, proof.A[0], proof.A[1])
+	sum := new(big.Int).Add(proof.E[0], proof.E[1])
+	if sum.Mod(sum, challengeMod).Cmp(e) != 0 {
+		return false
+	}
+	u := bitBranches(pubkey, c)
+	return verifyNthRoot(pubkey, u[0], proof.A[0], proof.E[0], proof.Z[0]) &&
+		verifyNthRoot(pubkey, u[1], proof.A[1], proof.E[1], proof.Z[1])
+}
+
+// bitBranches u_0 = c, u_1 = c * (1+N)^-1 = c * (1-N) (mod N^2)
+func bitBranches(pubkey *paillier.PublicKey, c *big.Int) [2]*big.Int {
+	u1 := new(big.Int).Sub(pubkey.NN, pubkey.N)
+	u1.Add(u1, one)
+	u1.Mul(u1, c)
+	return [2]*big.Int{c, u1.Mod(u1, pubkey.NN)}
+}
+
+// ProveRange prove that c = (1+N)^m * r^N encrypts m in [0, 2^bits)
+// the proof has bits ciphertexts and bit proofs, the statement is exact without slack
+func ProveRange(ssid []byte, pubkey *paillier.PublicKey, c, m, r *big.Int, bits int) (*RangeProof, error) {
+	if !validKey(pubkey) {
+		return nil, ErrInvalidKey
+	}
+	if bits < 1 || bits >= pubkey.N.BitLen() {
+		return nil, fmt.Errorf("invalid range of %d bits", bits)
+	}
+	if m.Sign() < 0 || m.BitLen() > bits || !modular.IsUnit(r, pubkey.N) || paillier.EncryptWithNonce(m, r, pubkey).Cmp(c) != 0 {
+		return nil, ErrInvalidWitness
+	}
+	proof := &RangeProof{
+		C:    make([]*big.Int, bits),
+		Bits: make([]*BitProof, bits),
+	}
+	// rho = r / prod( r_i^(2^i) ) (mod N)
+	rho := new(big.Int).Set(r)
+	for i := 0; i < bits; i++ {
+		b := big.NewInt(int64(m.Bit(i)))
+		ci, ri, err := Encrypt(pubkey, b)
+		if err != nil {
+			return nil, err
+		}
+		if proof.Bits[i], err = ProveBit(bitSSID(ssid, c, i), pubkey, ci, int(b.Int64()), ri); err != nil {
+			return nil, err
+		}
+		proof.C[i] = ci
+		rho.Mul(rho, modular.ExpInt(ri, new(big.Int).Neg(new(big.Int).Lsh(one, uint(i))), pubkey.N))
+		rho.Mod(rho, pubkey.N)
+	}
+	zero, err := ProveNthRoot(rangeSSID(ssid, proof.C), pubkey, rangeZero(pubkey, c, proof.C), rho)
+	if err != nil {
+		return nil, err
+	}
+	proof.Zero = zero
+	return proof, nil
+}
+
+// VerifyRange verify that c encrypts m in [0, 2^bits)
+func VerifyRange(ssid []byte, pubkey *paillier.PublicKey, c *big.Int, bits int, proof *RangeProof) bool {
+	if !validKey(pubkey) || proof == nil || bits < 1 || bits >= pubkey.N.BitLen() {
+		return false
+	}
+	if len(proof.C) != bits || len(proof.Bits) != bits || !modular.IsUnit(c, pubkey.NN) {
+		return false
+	}
+	for i := 0; i < bits; i++ {
+		if !VerifyBit(bitSSID(ssid, c, i), pubkey, proof.C[i], proof.Bits[i]) {
+			return false
+		}
+	}
+	return VerifyNthRoot(rangeSSID(ssid, proof.C), pubkey, rangeZero(pubkey, c, proof.C), proof.Zero)
+}
+
+// rangeZero c / prod( C[i]^(2^i) ) (mod N^2), which encrypts 0 iff the bits sum up to the plaintext of c
+func rangeZero(pubkey *paillier.PublicKey, c *big.Int, cs []*big.Int) *big.Int {
+	prod := big.NewInt(1)
+	for i := len(cs) - 1; i >= 0; i-- {
+		prod.Mul(prod, prod)
+		prod.Mul(prod, cs[i])
+		prod.Mod(prod, pubkey.NN)
+	}
+	u := new(big.Int).ModInverse(prod, pubkey.NN)
+	u.Mul(u, c)
+	return u.Mod(u, pubkey.NN)
+}
+
+// bitSSID session of the proof of bit i of c
+func bitSSID(ssid []byte, c *big.Int, i int) []byte {
+	return transcript("range-bit", ssid, c, big.NewInt(int64(i)))
+}
+
+// rangeSSID session of the final N-th root proof, bound to all bit ciphertexts
+func rangeSSID(ssid []byte, cs []*big.Int) []byte {
+	return transcript("range", ssid, cs...)
+}
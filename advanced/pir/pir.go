@@ -1,6 +1,7 @@
 package pir
 
 import (
+	"context"
 	"fmt"
 	"math/big"
 
@@ -44,23 +45,19 @@ func GenFx(values map[*big.Int]*big.Int, modulus *big.Int) ([]*big.Int, error) {
 }
 
 // GenSearchMaterial generate material to search target value
-// encrypt target {x_i, x_i^2,...x_i^n} by homomorphic public key
+// encrypt target {x_i, x_i^2,...x_i^n} by homomorphic public key in parallel
 // return the encrypted vector
 func GenSearchMaterial(targetIdx *big.Int, pubkey *paillier.PublicKey, totalItems int64) ([]*big.Int, error) {
-	res := make([]*big.Int, 0, totalItems)
+	powers := make([]*big.Int, 0, totalItems)
 	target := new(big.Int).Set(targetIdx)
 	var i int64 = 0
 	for i < totalItems {
-		enc, err := paillier.Encrypt(target, pubkey)
-		if err != nil {
-			return nil, err
-		}
-		res = append(res, enc)
+		powers = append(powers, new(big.Int).Set(target))
 
 		i++
 		target = target.Mul(target, targetIdx)
 	}
-	return res, nil
+	return paillier.EncryptBatch(context.Background(), powers, pubkey, 0)
 }
 
 // GenEncGxFx generate encrypted Gx and Fx
@@ -23,81 +23,148 @@ func GenerateShares(partyNum, threshold int, secret *big.Int) (map[*big.Int]*big
 		return nil, fmt.Errorf("threshold is too small, at least: %d", MinThreshold)
 	}
 
-	// 1. generate random coefficients to form a polynomial
-	coefficients := make([]*big.Int, threshold)
-	coefficients[0] = new(big.Int).Set(secret)
-	for i := 1; i < threshold; i++ {
-		r, err := rand.Int(rand.Reader, p)
-		if err != nil {
-			return nil, err
-		}
-		coefficients[i] = r
-	}
-
-	// 2. generate random x_i and calculate y_i
-	xFound := make(map[*big.Int]bool)
-	shares := make(map[*big.Int]*big.Int)
+	// 1. generate random x_i
+	xFound := make(map[string]bool)
+	xs := make([]*big.Int, partyNum)
 	for i := 0; i < partyNum; i++ {
-		x := new(big.Int)
-		var err error
 		for {
-			x, err = rand.Int(rand.Reader, p)
+			x, err := rand.Int(rand.Reader, p)
 			if err != nil {
 				return nil, err
 			}
-			// avoid duplicate x
-			if _, ok := xFound[x]; !ok {
-				xFound[x] = true
+			// avoid duplicate and zero x
+			if x.Sign() != 0 && !xFound[x.String()] {
+				xFound[x.String()] = true
+				xs[i] = x
 				break
 			}
 		}
-		shares[x] = calPolyByCoef(coefficients, x)
+	}
+
+	// 2. calculate y_i of a random polynomial
+	ys, err := GenerateSharesModP(xs, threshold, secret, p)
+	if err != nil {
+		return nil, err
+	}
+	shares := make(map[*big.Int]*big.Int)
+	for i, x := range xs {
+		shares[x] = ys[i]
 	}
 
 	return shares, nil
 }
 
 // RecoverSecret recover secret value using (x,y) pairs
+func RecoverSecret(shares map[*big.Int]*big.Int) (*big.Int, error) {
+	return RecoverSecretModP(shares, p)
+}
+
+// GenerateSharesModP generate y_i = f(x_i) (mod P) for a random polynomial f of degree threshold-1 with f(0) = secret
+// xs must be distinct and non-zero (mod P), P must be a prime, e.g. the order of a group for threshold signatures
+func GenerateSharesModP(xs []*big.Int, threshold int, secret, modulus *big.Int) ([]*big.Int, error) {
+	if threshold < 1 || threshold > len(xs) {
+		return nil, fmt.Errorf("invalid threshold %d of %d shares", threshold, len(xs))
+	}
+	xFound := make(map[string]bool)
+	for _, x := range xs {
+		xm := new(big.Int).Mod(x, modulus)
+		if xm.Sign() == 0 || xFound[xm.String()] {
+			return nil, fmt.Errorf("x must be distinct and non-zero")
+		}
+		xFound[xm.String()] = true
+	}
+
+	// 1. generate random coefficients to form a polynomial
+	coefficients := make([]*big.Int, threshold)
+	coefficients[0] = new(big.Int).Mod(secret, modulus)
+	for i := 1; i < threshold; i++ {
+		r, err := rand.Int(rand.Reader, modulus)
+		if err != nil {
+			return nil, err
+		}
+		coefficients[i] = r
+	}
+
+	// 2. calculate y_i
+	ys := make([]*big.Int, len(xs))
+	for i, x := range xs {
+		ys[i] = calPolyByCoef(coefficients, x, modulus)
+	}
+	return ys, nil
+}
+
+// RecoverSecretModP recover secret value f(0) (mod P) using (x,y) pairs
 // use Lagrange Interpolation Polynomial
 // sum( y_i*mul(-x_j)*{mul(x_i-x_j)}^-1 )
-func RecoverSecret(shares map[*big.Int]*big.Int) (*big.Int, error) {
-	result := new(big.Int)
+func RecoverSecretModP(shares map[*big.Int]*big.Int, modulus *big.Int) (*big.Int, error) {
+	xs := make([]*big.Int, 0, len(shares))
+	ys := make([]*big.Int, 0, len(shares))
 	for x, y := range shares {
-		m1 := new(big.Int).Set(y)
-		m2 := big.NewInt(1)
-		m3 := big.NewInt(1)
-		for k := range shares {
-			if k.Cmp(x) != 0 {
-				kNeg := new(big.Int).Neg(k)
-				m2 = new(big.Int).Mul(m2, kNeg)
-				m2 = new(big.Int).Mod(m2, p)
-
-				sub := new(big.Int).Sub(x, k)
-				m3 = new(big.Int).Mul(m3, sub)
-				m3 = new(big.Int).Mod(m3, p)
-			}
+		xs = append(xs, x)
+		ys = append(ys, y)
+	}
+	lambdas, err := LagrangeCoefficients(xs, modulus)
+	if err != nil {
+		return nil, err
+	}
+
+	return InterpolateAtZero(lambdas, ys, modulus), nil
+}
+
+// LagrangeCoefficients Lagrange basis polynomials of all xs evaluated at 0 (mod P)
+// compute them once to interpolate many sharings over the same points by InterpolateAtZero
+func LagrangeCoefficients(xs []*big.Int, modulus *big.Int) ([]*big.Int, error) {
+	lambdas := make([]*big.Int, len(xs))
+	for i := range xs {
+		lambda, err := LagrangeAtZero(xs, i, modulus)
+		if err != nil {
+			return nil, err
 		}
-		// find inverse
-		m3 = new(big.Int).ModInverse(m3, p)
+		lambdas[i] = lambda
+	}
+	return lambdas, nil
+}
 
-		m1 = new(big.Int).Mul(m1, m2)
-		m1 = new(big.Int).Mul(m1, m3)
-		result = new(big.Int).Add(result, m1)
-		result = new(big.Int).Mod(result, p)
+// InterpolateAtZero f(0) = sum( lambda_i*y_i ) (mod P) for y_i = f(x_i) and lambdas of xs from LagrangeCoefficients
+func InterpolateAtZero(lambdas, ys []*big.Int, modulus *big.Int) *big.Int {
+	result := new(big.Int)
+	term := new(big.Int)
+	for i, y := range ys {
+		result.Add(result, term.Mul(y, lambdas[i]))
 	}
+	return result.Mod(result, modulus)
+}
 
-	return result, nil
+// LagrangeAtZero Lagrange basis polynomial of xs[i] evaluated at 0 (mod P), mul( x_j/(x_j-x_i) )
+// f(0) = sum( lambda_i*f(x_i) ), also for f(x_i) hidden in the exponent of a group of order P
+func LagrangeAtZero(xs []*big.Int, i int, modulus *big.Int) (*big.Int, error) {
+	num := big.NewInt(1)
+	den := big.NewInt(1)
+	for j, xj := range xs {
+		if j == i {
+			continue
+		}
+		num.Mul(num, xj)
+		num.Mod(num, modulus)
+		den.Mul(den, new(big.Int).Sub(xj, xs[i]))
+		den.Mod(den, modulus)
+	}
+	// find inverse
+	if den.ModInverse(den, modulus) == nil {
+		return nil, fmt.Errorf("x must be distinct")
+	}
+	num.Mul(num, den)
+	return num.Mod(num, modulus), nil
 }
 
 // calPolyByCoef calculate y value of a polynomial by coefficients
-// coefficients = {a_0, a_1 ...}, return a_0 + a_1*x + a_2*x^2...
-func calPolyByCoef(coefficients []*big.Int, x *big.Int) *big.Int {
-	result := new(big.Int).Set(coefficients[0])
-	tmpX := new(big.Int).Set(x)
-	for i := 1; i < len(coefficients); i++ {
-		m := new(big.Int).Mul(coefficients[i], tmpX)
-		result = new(big.Int).Add(result, m)
-		tmpX = new(big.Int).Mul(tmpX, x)
+// coefficients = {a_0, a_1 ...}, return a_0 + a_1*x + a_2*x^2... (mod P) by Horner's method
+func calPolyByCoef(coefficients []*big.Int, x, modulus *big.Int) *big.Int {
+	result := new(big.Int)
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		result.Mul(result, x)
+		result.Add(result, coefficients[i])
+		result.Mod(result, modulus)
 	}
 
 	return result
@@ -30,6 +30,50 @@ func TestShamir(t *testing.T) {
 	}
 }
 
+func TestShamirModP(t *testing.T) {
+	modulus, _ := new(big.Int).SetString("52435875175126190479447740508185965837690552500527637822603658699938581184513", 10)
+	xs := make([]*big.Int, parties)
+	for i := range xs {
+		xs[i] = big.NewInt(int64(i + 1))
+	}
+	ys, err := GenerateSharesModP(xs, threshold, secret, modulus)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// any threshold shares recover the secret, fewer do not
+	subset := make(map[*big.Int]*big.Int)
+	for i := parties - threshold; i < parties; i++ {
+		subset[xs[i]] = ys[i]
+	}
+	if s, err := RecoverSecretModP(subset, modulus); err != nil || s.Cmp(secret) != 0 {
+		t.Errorf("got: %v, supposed to be: %v", s, secret)
+	}
+	delete(subset, xs[parties-1])
+	if s, _ := RecoverSecretModP(subset, modulus); s.Cmp(secret) == 0 {
+		t.Errorf("secret recovered from fewer than threshold shares")
+	}
+
+	// the same points interpolate other sharings with cached coefficients
+	lambdas, err := LagrangeCoefficients(xs, modulus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := big.NewInt(456)
+	ys, _ = GenerateSharesModP(xs, threshold, other, modulus)
+	if s := InterpolateAtZero(lambdas, ys, modulus); s.Cmp(other) != 0 {
+		t.Errorf("got: %v, supposed to be: %v", s, other)
+	}
+
+	xs[1] = new(big.Int).Add(xs[0], modulus)
+	if _, err := GenerateSharesModP(xs, threshold, secret, modulus); err == nil {
+		t.Errorf("duplicate x accepted")
+	}
+	if _, err := LagrangeAtZero(xs, 0, modulus); err == nil {
+		t.Errorf("duplicate x accepted by LagrangeAtZero")
+	}
+}
+
 func BenchmarkShare(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		shares, _ = GenerateShares(parties, threshold, secret)
@@ -0,0 +1,231 @@
+// Package timelock implements Rivest-Shamir-Wagner time-lock puzzle encryption
+// a message becomes decryptable only after T sequential squarings b = a^(2^T) (mod N)
+//
+// the creator knows phi(N) of an rsa key and computes b = a^(2^T mod phi) with one exponentiation,
+// the message is encrypted under a random data key, which is wrapped under KDF(b) by an AEAD of package symmetric
+package timelock
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/hongyanwang/crypto-lab/advanced/vdf"
+	"github.com/hongyanwang/crypto-lab/asymmetric/rsa"
+	"github.com/hongyanwang/crypto-lab/symmetric/aes"
+	"github.com/hongyanwang/crypto-lab/symmetric/sm4"
+)
+
+// AEAD identifies the symmetric AEAD of a puzzle
+type AEAD byte
+
+const (
+	AES256GCM AEAD = 1
+	SM4GCM    AEAD = 2
+)
+
+// serialized puzzle
+//
+//	puzzle = magic(4) || version(1) || aead(1) || T(8) || len(N)(2) || N || len(a)(2) || a || len(wrappedKey)(2) || wrappedKey || ciphertext
+//
+// magic up to a is the header, authenticated as additional data of both AEAD encryptions
+const (
+	puzzleMagic   = "RSWP"
+	puzzleVersion = 1
+)
+
+var (
+	ErrFormat          = errors.New("timelock: malformed puzzle")
+	ErrUnsupportedAEAD = errors.New("timelock: unsupported AEAD")
+	ErrDecryption      = errors.New("timelock: decryption error")
+
+	one = big.NewInt(1)
+)
+
+// Puzzle time-lock puzzle, anyone can solve it by T squarings
+type Puzzle struct {
+	AEAD       AEAD
+	T          uint64
+	N          *big.Int
+	A          *big.Int // random element, the solution is A^(2^T) in vdf.Group
+	WrappedKey []byte   // data key encrypted under KDF(A^(2^T))
+	Ciphertext []byte   // message encrypted under the data key
+}
+
+// Create lock msg for T squarings, prvkey is only used for its trapdoor phi and must not be given to solvers
+func Create(prvkey *rsa.PrivateKey, msg []byte, T uint64, aead AEAD) (*Puzzle, error) {
+	keySize, err := aead.keySize()
+	if err != nil {
+		return nil, err
+	}
+	if prvkey.Phi == nil {
+		return nil, fmt.Errorf("timelock: missing trapdoor phi")
+	}
+	group := vdf.NewGroup(&prvkey.PublicKey)
+	var a *big.Int
+	for a == nil || !group.IsElement(a) {
+		if a, err = rand.Int(rand.Reader, group.N); err != nil {
+			return nil, err
+		}
+	}
+	p := &Puzzle{AEAD: aead, T: T, N: group.N, A: a}
+
+	// shortcut b = a^(2^T mod phi)
+	e := new(big.Int).Exp(big.NewInt(2), new(big.Int).SetUint64(T), prvkey.Phi)
+	b := new(big.Int).Exp(a, e, group.N)
+
+	dataKey := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, err
+	}
+	header := p.header()
+	if p.WrappedKey, err = aead.encrypt(dataKey, p.kek(b), header); err != nil {
+		return nil, err
+	}
+	if p.Ciphertext, err = aead.encrypt(msg, dataKey, header); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Solve recover the message by T sequential squarings
+// progress may be nil, solving stops with ctx.Err() when ctx is done
+func (p *Puzzle) Solve(ctx context.Context, progress vdf.ProgressFunc) ([]byte, error) {
+	group := &vdf.Group{N: p.N}
+	b, err := group.Eval(ctx, p.A, p.T, progress)
+	if err != nil {
+		return nil, err
+	}
+	return p.Open(b)
+}
+
+// Open decrypt with solution b = A^(2^T), e.g. computed by vdf.Group.Eval with a verifiable proof
+func (p *Puzzle) Open(b *big.Int) ([]byte, error) {
+	if _, err := p.AEAD.keySize(); err != nil {
+		return nil, err
+	}
+	header := p.header()
+	dataKey, err := p.AEAD.decrypt(p.WrappedKey, p.kek(b), header)
+	if err != nil {
+		return nil, err
+	}
+	return p.AEAD.decrypt(p.Ciphertext, dataKey, header)
+}
+
+// kek key encryption key SHA-256("timelock-kek" || b) truncated to the key size
+// b is represented by min(b, N-b) as in vdf.Group and padded to the length of N
+func (p *Puzzle) kek(b *big.Int) []byte {
+	keySize, _ := p.AEAD.keySize()
+	if neg := new(big.Int).Sub(p.N, b); neg.Cmp(b) < 0 {
+		b = neg
+	}
+	h := sha256.New()
+	h.Write([]byte("timelock-kek"))
+	h.Write(b.FillBytes(make([]byte, (p.N.BitLen()+7)/8)))
+	return h.Sum(nil)[:keySize]
+}
+
+// header magic || version || aead || T || len(N) || N || len(a) || a
+func (p *Puzzle) header() []byte {
+	buf := append([]byte(puzzleMagic), puzzleVersion, byte(p.AEAD))
+	buf = appendUint64(buf, p.T)
+	buf = appendBytes(buf, p.N.Bytes())
+	return appendBytes(buf, p.A.Bytes())
+}
+
+// Marshal serialize puzzle
+func (p *Puzzle) Marshal() []byte {
+	buf := appendBytes(p.header(), p.WrappedKey)
+	return append(buf, p.Ciphertext...)
+}
+
+// Parse deserialize puzzle, the header is authenticated later by Solve
+func Parse(data []byte) (*Puzzle, error) {
+	if len(data) < len(puzzleMagic)+2+8 || string(data[:len(puzzleMagic)]) != puzzleMagic {
+		return nil, ErrFormat
+	}
+	data = data[len(puzzleMagic):]
+	if data[0] != puzzleVersion {
+		return nil, fmt.Errorf("%w: version %d", ErrFormat, data[0])
+	}
+	p := &Puzzle{AEAD: AEAD(data[1])}
+	if _, err := p.AEAD.keySize(); err != nil {
+		return nil, err
+	}
+	p.T = binary.BigEndian.Uint64(data[2:10])
+	data = data[10:]
+
+	fields := make([][]byte, 3)
+	for i := range fields {
+		if len(data) < 2 {
+			return nil, ErrFormat
+		}
+		n := int(binary.BigEndian.Uint16(data))
+		if len(data) < 2+n {
+			return nil, ErrFormat
+		}
+		fields[i], data = data[2:2+n], data[2+n:]
+	}
+	p.N = new(big.Int).SetBytes(fields[0])
+	p.A = new(big.Int).SetBytes(fields[1])
+	p.WrappedKey = fields[2]
+	p.Ciphertext = data
+	if p.N.Cmp(one) <= 0 || !(&vdf.Group{N: p.N}).IsElement(p.A) {
+		return nil, ErrFormat
+	}
+	return p, nil
+}
+
+// keySize key length of the AEAD
+func (aead AEAD) keySize() (int, error) {
+	switch aead {
+	case AES256GCM:
+		return 32, nil
+	case SM4GCM:
+		return 16, nil
+	default:
+		return 0, fmt.Errorf("%w: %d", ErrUnsupportedAEAD, aead)
+	}
+}
+
+// encrypt nonce || ciphertext || tag
+func (aead AEAD) encrypt(msg, key, additionalData []byte) ([]byte, error) {
+	if aead == SM4GCM {
+		return sm4.EncryptGCM(msg, key, additionalData)
+	}
+	return aes.EncryptGCM(msg, key, additionalData)
+}
+
+// decrypt nonce || ciphertext || tag, any failure is ErrDecryption
+func (aead AEAD) decrypt(ciphertext, key, additionalData []byte) ([]byte, error) {
+	var msg []byte
+	var err error
+	if aead == SM4GCM {
+		msg, err = sm4.DecryptGCM(ciphertext, key, additionalData)
+	} else {
+		msg, err = aes.DecryptGCM(ciphertext, key, additionalData)
+	}
+	if err != nil {
+		return nil, ErrDecryption
+	}
+	return msg, nil
+}
+
+// appendUint64 8-byte big-endian x
+func appendUint64(buf []byte, x uint64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], x)
+	return append(buf, b[:]...)
+}
+
+// appendBytes 2-byte big-endian length || data
+func appendBytes(buf, data []byte) []byte {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], uint16(len(data)))
+	return append(append(buf, b[:]...), data...)
+}
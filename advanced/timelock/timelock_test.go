@@ -0,0 +1,151 @@
+package timelock
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/hongyanwang/crypto-lab/asymmetric/rsa"
+)
+
+var (
+	secbit        = 1024
+	T      uint64 = 1 << 14
+	msg           = []byte("sealed bid: 42 coins")
+)
+
+func newKey(t testing.TB) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(secbit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestSolve(t *testing.T) {
+	key := newKey(t)
+	for _, aead := range []AEAD{AES256GCM, SM4GCM} {
+		p, err := Create(key, msg, T, aead)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed, err := Parse(p.Marshal())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(parsed.Marshal(), p.Marshal()) {
+			t.Fatal("serialization round trip mismatch")
+		}
+
+		var last uint64
+		ret, err := parsed.Solve(context.Background(), func(done, total uint64) {
+			last = done
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(ret, msg) {
+			t.Fatal("solved message mismatch")
+		}
+		if last != T {
+			t.Fatalf("progress ends at %d, expect %d", last, T)
+		}
+
+		// the solution as computed by the creator
+		e := new(big.Int).Exp(big.NewInt(2), new(big.Int).SetUint64(T), key.Phi)
+		ret, err = p.Open(new(big.Int).Exp(p.A, e, p.N))
+		if err != nil || !bytes.Equal(ret, msg) {
+			t.Fatal("open with trapdoor solution failed")
+		}
+		if _, err := p.Open(big.NewInt(2)); err != ErrDecryption {
+			t.Fatalf("expect ErrDecryption, got %v", err)
+		}
+	}
+}
+
+func TestTamper(t *testing.T) {
+	p, err := Create(newKey(t), msg, 1000, AES256GCM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a shorter delay in the header gives a wrong solution, and fails authentication anyway
+	data := p.Marshal()
+	data[len(puzzleMagic)+2+7]--
+	tampered, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tampered.Solve(context.Background(), nil); err != ErrDecryption {
+		t.Fatalf("expect ErrDecryption, got %v", err)
+	}
+
+	data = p.Marshal()
+	data[len(data)-1] ^= 1
+	tampered, err = Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tampered.Solve(context.Background(), nil); err != ErrDecryption {
+		t.Fatalf("expect ErrDecryption, got %v", err)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	p, err := Create(newKey(t), msg, 1<<40, AES256GCM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := p.Solve(ctx, nil); err != context.Canceled {
+		t.Fatalf("expect context.Canceled, got %v", err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	p, err := Create(newKey(t), msg, 10, SM4GCM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := p.Marshal()
+	for _, n := range []int{0, 4, 14, 20, len(p.header()) + 1} {
+		if _, err := Parse(data[:n]); !errors.Is(err, ErrFormat) {
+			t.Fatalf("truncated at %d: expect ErrFormat, got %v", n, err)
+		}
+	}
+	bad := append([]byte{}, data...)
+	bad[0] = 'X'
+	if _, err := Parse(bad); !errors.Is(err, ErrFormat) {
+		t.Fatalf("expect ErrFormat, got %v", err)
+	}
+	bad = append([]byte{}, data...)
+	bad[len(puzzleMagic)] = 2
+	if _, err := Parse(bad); !errors.Is(err, ErrFormat) {
+		t.Fatalf("expect ErrFormat, got %v", err)
+	}
+	bad = append([]byte{}, data...)
+	bad[len(puzzleMagic)+1] = 9
+	if _, err := Parse(bad); !errors.Is(err, ErrUnsupportedAEAD) {
+		t.Fatalf("expect ErrUnsupportedAEAD, got %v", err)
+	}
+	if _, err := Create(newKey(t), msg, 10, AEAD(9)); !errors.Is(err, ErrUnsupportedAEAD) {
+		t.Fatalf("expect ErrUnsupportedAEAD, got %v", err)
+	}
+}
+
+func BenchmarkCreate(b *testing.B) {
+	key := newKey(b)
+	for i := 0; i < b.N; i++ {
+		Create(key, msg, T, AES256GCM)
+	}
+}
+
+func BenchmarkSolve(b *testing.B) {
+	p, _ := Create(newKey(b), msg, T, AES256GCM)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.Solve(context.Background(), nil)
+	}
+}
@@ -0,0 +1,72 @@
+package vdf
+
+import (
+	"context"
+	"math/big"
+)
+
+// ProvePietrzak proof of y = x^(2^T) by recursive halving
+// each round sends mu = x^(2^(T/2)) and continues with x' = x^r * mu, y' = mu^r * y, T' = T/2 for r = H(x, y, mu, T)
+// an odd T is first made even by y' = y^2, T' = T+1, the proof has about log2(T) elements
+func (g *Group) ProvePietrzak(ctx context.Context, x, y *big.Int, T uint64, progress ProgressFunc) ([]*big.Int, error) {
+	if !g.IsElement(x) || !g.IsElement(y) {
+		return nil, ErrInvalidElement
+	}
+	// total squarings of all rounds
+	var total uint64
+	for t := T; t > 1; t = (t + 1) / 2 {
+		total += (t + 1) / 2
+	}
+	pr := &tracker{ctx: ctx, total: total, fn: progress}
+
+	x, y = g.normalize(x), g.normalize(y)
+	var proof []*big.Int
+	for t := T; t > 1; {
+		if t%2 == 1 {
+			y = g.mul(y, y)
+			t++
+		}
+		mu, err := g.square(pr, x, t/2)
+		if err != nil {
+			return nil, err
+		}
+		proof = append(proof, mu)
+		x, y, t = g.halve(x, y, mu, t)
+	}
+	if err := pr.finish(); err != nil {
+		return nil, err
+	}
+	return proof, nil
+}
+
+// VerifyPietrzak replay the halving rounds and check y = x^(2^T) for the final T <= 1
+func (g *Group) VerifyPietrzak(x, y *big.Int, T uint64, proof []*big.Int) bool {
+	if !g.IsElement(x) || !g.IsElement(y) {
+		return false
+	}
+	x, y = g.normalize(x), g.normalize(y)
+	t := T
+	for _, mu := range proof {
+		if t <= 1 || !g.IsElement(mu) {
+			return false
+		}
+		if t%2 == 1 {
+			y = g.mul(y, y)
+			t++
+		}
+		x, y, t = g.halve(x, y, g.normalize(mu), t)
+	}
+	if t > 1 {
+		return false
+	}
+	if t == 1 {
+		x = g.mul(x, x)
+	}
+	return x.Cmp(y) == 0
+}
+
+// halve one round x' = x^r * mu, y' = mu^r * y for even t
+func (g *Group) halve(x, y, mu *big.Int, t uint64) (*big.Int, *big.Int, uint64) {
+	r := hashToInt(transcript("pietrzak", t, x, y, mu), 0)
+	return g.mul(g.exp(x, r), mu), g.mul(g.exp(mu, r), y), t / 2
+}
@@ -0,0 +1,181 @@
+// Package vdf implements verifiable delay functions by repeated squaring y = x^(2^T) in an RSA group
+// the group is Z_N^*/{+-1} for the modulus of an rsa key, whose factorization must be unknown to the evaluator,
+// e.g. the private key is discarded after setup or the modulus comes from distributed key generation
+//
+// Wesolowski proofs are a single group element, Pietrzak proofs have about log2(T) elements,
+// both cost about T more squarings to compute and a few short exponentiations to verify
+package vdf
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/hongyanwang/crypto-lab/asymmetric/rsa"
+)
+
+const (
+	// progressInterval squarings between two progress reports and cancellation checks
+	progressInterval = 1 << 12
+	// challengeBits bit length of Wesolowski challenge primes and Pietrzak challenges
+	challengeBits = 128
+	// primality test rounds of challenge primes
+	primeRounds = 20
+)
+
+var (
+	ErrInvalidElement = errors.New("vdf: element is not in the group")
+
+	one = big.NewInt(1)
+)
+
+// ProgressFunc receives the number of squarings done out of total during evaluation or proving
+type ProgressFunc func(done, total uint64)
+
+// Group RSA group Z_N^*/{+-1}, elements are represented by min(x, N-x)
+type Group struct {
+	N *big.Int
+}
+
+// NewGroup group of the modulus of an rsa key
+func NewGroup(pub *rsa.PublicKey) *Group {
+	return &Group{N: new(big.Int).Set(pub.N)}
+}
+
+// HashToElement map data to a group element, SHA-256(data || counter) expanded to 128 bits over N reduced mod N
+func (g *Group) HashToElement(data []byte) *big.Int {
+	size := (g.N.BitLen()+7)/8 + 16
+	var counter [4]byte
+	for i := uint32(0); ; i++ {
+		buf := make([]byte, 0, size+sha256.Size)
+		for j := uint32(0); len(buf) < size; j++ {
+			h := sha256.New()
+			h.Write(data)
+			binary.BigEndian.PutUint32(counter[:], i)
+			h.Write(counter[:])
+			binary.BigEndian.PutUint32(counter[:], j)
+			h.Write(counter[:])
+			buf = h.Sum(buf)
+		}
+		x := new(big.Int).SetBytes(buf[:size])
+		x.Mod(x, g.N)
+		if g.IsElement(x) {
+			return g.normalize(x)
+		}
+	}
+}
+
+// IsElement 0 < x < N and gcd(x, N) = 1
+func (g *Group) IsElement(x *big.Int) bool {
+	if x == nil || x.Sign() <= 0 || x.Cmp(g.N) >= 0 {
+		return false
+	}
+	return new(big.Int).GCD(nil, nil, x, g.N).Cmp(one) == 0
+}
+
+// Eval y = x^(2^T) by T sequential squarings
+// progress may be nil, evaluation stops with ctx.Err() when ctx is done
+func (g *Group) Eval(ctx context.Context, x *big.Int, T uint64, progress ProgressFunc) (*big.Int, error) {
+	if !g.IsElement(x) {
+		return nil, ErrInvalidElement
+	}
+	pr := &tracker{ctx: ctx, total: T, fn: progress}
+	y, err := g.square(pr, x, T)
+	if err != nil {
+		return nil, err
+	}
+	return y, pr.finish()
+}
+
+// square x^(2^steps), reporting each step to pr
+func (g *Group) square(pr *tracker, x *big.Int, steps uint64) (*big.Int, error) {
+	y := new(big.Int).Set(x)
+	for i := uint64(0); i < steps; i++ {
+		y.Mul(y, y)
+		y.Mod(y, g.N)
+		if err := pr.step(); err != nil {
+			return nil, err
+		}
+	}
+	return g.normalize(y), nil
+}
+
+// mul x*y in the group
+func (g *Group) mul(x, y *big.Int) *big.Int {
+	z := new(big.Int).Mul(x, y)
+	return g.normalize(z.Mod(z, g.N))
+}
+
+// exp x^e in the group
+func (g *Group) exp(x, e *big.Int) *big.Int {
+	return g.normalize(new(big.Int).Exp(x, e, g.N))
+}
+
+// normalize representative min(x, N-x) of the class {x, -x}
+func (g *Group) normalize(x *big.Int) *big.Int {
+	neg := new(big.Int).Sub(g.N, x)
+	if neg.Cmp(x) < 0 {
+		return neg
+	}
+	return x
+}
+
+// tracker counts squarings, reports progress and checks cancellation every progressInterval squarings
+type tracker struct {
+	ctx   context.Context
+	done  uint64
+	total uint64
+	fn    ProgressFunc
+}
+
+func (pr *tracker) step() error {
+	pr.done++
+	if pr.done%progressInterval != 0 {
+		return nil
+	}
+	if err := pr.ctx.Err(); err != nil {
+		return err
+	}
+	if pr.fn != nil {
+		pr.fn(pr.done, pr.total)
+	}
+	return nil
+}
+
+// finish report completion unless it was just reported
+func (pr *tracker) finish() error {
+	if err := pr.ctx.Err(); err != nil {
+		return err
+	}
+	if pr.fn != nil && (pr.done%progressInterval != 0 || pr.done == 0) {
+		pr.fn(pr.done, pr.total)
+	}
+	return nil
+}
+
+// transcript label || T || len(v_1) || v_1 || ... with 8-byte big-endian T and 4-byte big-endian lengths
+func transcript(label string, T uint64, values ...*big.Int) []byte {
+	buf := []byte("vdf:" + label)
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], T)
+	buf = append(buf, b[:]...)
+	for _, v := range values {
+		vb := v.Bytes()
+		binary.BigEndian.PutUint32(b[:4], uint32(len(vb)))
+		buf = append(buf, b[:4]...)
+		buf = append(buf, vb...)
+	}
+	return buf
+}
+
+// hashToInt challengeBits bits integer from SHA-256(data || counter)
+func hashToInt(data []byte, counter uint32) *big.Int {
+	var c [4]byte
+	binary.BigEndian.PutUint32(c[:], counter)
+	h := sha256.New()
+	h.Write(data)
+	h.Write(c[:])
+	return new(big.Int).SetBytes(h.Sum(nil)[:challengeBits/8])
+}
@@ -0,0 +1,178 @@
+package vdf
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/hongyanwang/crypto-lab/asymmetric/rsa"
+)
+
+var (
+	secbit        = 1024
+	T      uint64 = 1 << 14
+)
+
+func newGroup(t testing.TB) (*Group, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(secbit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewGroup(&key.PublicKey), key
+}
+
+func TestEval(t *testing.T) {
+	g, key := newGroup(t)
+	x := g.HashToElement([]byte("lottery round 1"))
+	if !g.IsElement(x) || x.Cmp(g.HashToElement([]byte("lottery round 1"))) != 0 {
+		t.Fatal("HashToElement is not a deterministic group element")
+	}
+
+	var calls int
+	var last uint64
+	y, err := g.Eval(context.Background(), x, T, func(done, total uint64) {
+		if total != T || done <= last {
+			t.Fatalf("unexpected progress %d/%d", done, total)
+		}
+		calls++
+		last = done
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last != T || calls != int(T/progressInterval) {
+		t.Fatalf("expect %d progress reports up to %d, got %d up to %d", T/progressInterval, T, calls, last)
+	}
+
+	// the trapdoor shortcut x^(2^T mod phi) gives the same output
+	e := new(big.Int).Exp(big.NewInt(2), new(big.Int).SetUint64(T), key.Phi)
+	if g.normalize(new(big.Int).Exp(x, e, g.N)).Cmp(y) != 0 {
+		t.Fatal("evaluation differs from trapdoor computation")
+	}
+}
+
+func TestCancel(t *testing.T) {
+	g, _ := newGroup(t)
+	x := g.HashToElement([]byte("cancelled"))
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := g.Eval(ctx, x, 1<<40, func(done, total uint64) {
+		if done >= 4*progressInterval {
+			cancel()
+		}
+	})
+	if err != context.Canceled {
+		t.Fatalf("expect context.Canceled, got %v", err)
+	}
+	if _, err := g.ProveWesolowski(ctx, x, x, T, nil); err != context.Canceled {
+		t.Fatalf("expect context.Canceled, got %v", err)
+	}
+	if _, err := g.ProvePietrzak(ctx, x, x, T, nil); err != context.Canceled {
+		t.Fatalf("expect context.Canceled, got %v", err)
+	}
+}
+
+func TestWesolowski(t *testing.T) {
+	g, _ := newGroup(t)
+	for _, steps := range []uint64{0, 1, 1000, T} {
+		x := g.HashToElement([]byte("wesolowski"))
+		y, err := g.Eval(context.Background(), x, steps, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pi, err := g.ProveWesolowski(context.Background(), x, y, steps, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !g.VerifyWesolowski(x, y, steps, pi) {
+			t.Fatalf("T = %d: verification failed", steps)
+		}
+		// -y is the same element of Z_N^*/{+-1}
+		if !g.VerifyWesolowski(x, new(big.Int).Sub(g.N, y), steps, pi) {
+			t.Fatalf("T = %d: verification failed for -y", steps)
+		}
+		if g.VerifyWesolowski(x, y, steps+1, pi) {
+			t.Fatalf("T = %d: verified for a wrong delay", steps)
+		}
+		if g.VerifyWesolowski(x, g.mul(y, big.NewInt(2)), steps, pi) {
+			t.Fatalf("T = %d: verified for a wrong output", steps)
+		}
+	}
+}
+
+func TestPietrzak(t *testing.T) {
+	g, _ := newGroup(t)
+	for _, steps := range []uint64{0, 1, 2, 3, 1000, 1023, T} {
+		x := g.HashToElement([]byte("pietrzak"))
+		y, err := g.Eval(context.Background(), x, steps, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var last, total uint64
+		proof, err := g.ProvePietrzak(context.Background(), x, y, steps, func(done, tot uint64) {
+			last, total = done, tot
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if last != total {
+			t.Fatalf("T = %d: progress ends at %d/%d", steps, last, total)
+		}
+		if !g.VerifyPietrzak(x, y, steps, proof) {
+			t.Fatalf("T = %d: verification failed", steps)
+		}
+		if g.VerifyPietrzak(x, g.mul(y, big.NewInt(2)), steps, proof) {
+			t.Fatalf("T = %d: verified for a wrong output", steps)
+		}
+		if len(proof) > 0 {
+			if g.VerifyPietrzak(x, y, steps, proof[1:]) {
+				t.Fatalf("T = %d: verified a truncated proof", steps)
+			}
+			proof[0] = g.mul(proof[0], big.NewInt(2))
+			if g.VerifyPietrzak(x, y, steps, proof) {
+				t.Fatalf("T = %d: verified a tampered proof", steps)
+			}
+		}
+	}
+}
+
+func TestInvalidElement(t *testing.T) {
+	g, key := newGroup(t)
+	for _, x := range []*big.Int{big.NewInt(0), g.N, key.P} {
+		if _, err := g.Eval(context.Background(), x, T, nil); err != ErrInvalidElement {
+			t.Fatalf("expect ErrInvalidElement, got %v", err)
+		}
+		if g.VerifyWesolowski(x, x, 0, big.NewInt(1)) || g.VerifyPietrzak(x, x, 0, nil) {
+			t.Fatal("verified an invalid element")
+		}
+	}
+}
+
+func BenchmarkEval(b *testing.B) {
+	g, _ := newGroup(b)
+	x := g.HashToElement([]byte("benchmark"))
+	for i := 0; i < b.N; i++ {
+		g.Eval(context.Background(), x, T, nil)
+	}
+}
+
+func BenchmarkVerifyWesolowski(b *testing.B) {
+	g, _ := newGroup(b)
+	x := g.HashToElement([]byte("benchmark"))
+	y, _ := g.Eval(context.Background(), x, T, nil)
+	pi, _ := g.ProveWesolowski(context.Background(), x, y, T, nil)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g.VerifyWesolowski(x, y, T, pi)
+	}
+}
+
+func BenchmarkVerifyPietrzak(b *testing.B) {
+	g, _ := newGroup(b)
+	x := g.HashToElement([]byte("benchmark"))
+	y, _ := g.Eval(context.Background(), x, T, nil)
+	proof, _ := g.ProvePietrzak(context.Background(), x, y, T, nil)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g.VerifyPietrzak(x, y, T, proof)
+	}
+}
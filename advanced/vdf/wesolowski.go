@@ -0,0 +1,58 @@
+package vdf
+
+import (
+	"context"
+	"math/big"
+)
+
+// ProveWesolowski proof pi = x^floor(2^T / l) of y = x^(2^T) for challenge prime l = H(x, y, T)
+// pi is computed by T steps of long division of 2^T by l, each a squaring and at most one multiplication
+func (g *Group) ProveWesolowski(ctx context.Context, x, y *big.Int, T uint64, progress ProgressFunc) (*big.Int, error) {
+	if !g.IsElement(x) || !g.IsElement(y) {
+		return nil, ErrInvalidElement
+	}
+	x, y = g.normalize(x), g.normalize(y)
+	l := challengePrime(transcript("wesolowski", T, x, y))
+	pr := &tracker{ctx: ctx, total: T, fn: progress}
+	pi := big.NewInt(1)
+	r := big.NewInt(1)
+	for i := uint64(0); i < T; i++ {
+		// next bit of the quotient is floor(2r / l), remainder 2r mod l
+		r.Lsh(r, 1)
+		pi.Mul(pi, pi)
+		if r.Cmp(l) >= 0 {
+			r.Sub(r, l)
+			pi.Mul(pi, x)
+		}
+		pi.Mod(pi, g.N)
+		if err := pr.step(); err != nil {
+			return nil, err
+		}
+	}
+	if err := pr.finish(); err != nil {
+		return nil, err
+	}
+	return g.normalize(pi), nil
+}
+
+// VerifyWesolowski check pi^l * x^(2^T mod l) = y, two exponentiations by about challengeBits bits
+func (g *Group) VerifyWesolowski(x, y *big.Int, T uint64, pi *big.Int) bool {
+	if !g.IsElement(x) || !g.IsElement(y) || !g.IsElement(pi) {
+		return false
+	}
+	x, y = g.normalize(x), g.normalize(y)
+	l := challengePrime(transcript("wesolowski", T, x, y))
+	r := new(big.Int).Exp(big.NewInt(2), new(big.Int).SetUint64(T), l)
+	return g.mul(g.exp(pi, l), g.exp(x, r)).Cmp(y) == 0
+}
+
+// challengePrime smallest prime at or after a challengeBits bits hash with top bit set
+func challengePrime(data []byte) *big.Int {
+	l := hashToInt(data, 0)
+	l.SetBit(l, challengeBits-1, 1)
+	l.SetBit(l, 0, 1)
+	for !l.ProbablyPrime(primeRounds) {
+		l.Add(l, big.NewInt(2))
+	}
+	return l
+}
@@ -0,0 +1,136 @@
+package bls
+
+import (
+	"errors"
+
+	bls12_381_ecc "github.com/consensys/gnark-crypto/ecc/bls12-381"
+)
+
+// popDST domain separation tag of proof of possession, draft section 4.2.3
+const popDST = "BLS_POP_BLS12381G1_XMD:SHA-256_SSWU_RO_POP_"
+
+var (
+	ErrEmptyAggregate    = errors.New("bls: nothing to aggregate")
+	ErrLengthMismatch    = errors.New("bls: number of public keys and messages mismatch")
+	ErrDuplicateMessages = errors.New("bls: messages are not distinct")
+)
+
+// Aggregate aggregate signatures into one, sig = sig_1 + ... + sig_n
+// signatures from untrusted sources should be decoded by SignatureFromBytes which checks they are in G1
+func Aggregate(sigs []*bls12_381_ecc.G1Affine) (*bls12_381_ecc.G1Affine, error) {
+	if len(sigs) == 0 {
+		return nil, ErrEmptyAggregate
+	}
+	var acc, p bls12_381_ecc.G1Jac
+	for _, sig := range sigs {
+		if sig == nil || !sig.IsOnCurve() {
+			return nil, ErrInvalidSignature
+		}
+		p.FromAffine(sig)
+		acc.AddAssign(&p)
+	}
+	return new(bls12_381_ecc.G1Affine).FromJacobian(&acc), nil
+}
+
+// AggregatePublicKeys aggregate public keys into one, pk = pk_1 + ... + pk_n
+func AggregatePublicKeys(pubs []*PublicKey) (*PublicKey, error) {
+	if len(pubs) == 0 {
+		return nil, ErrEmptyAggregate
+	}
+	var acc, p bls12_381_ecc.G2Jac
+	for _, pub := range pubs {
+		if pub == nil || pub.P == nil || !pub.P.IsOnCurve() {
+			return nil, ErrInvalidPublicKey
+		}
+		p.FromAffine(pub.P)
+		acc.AddAssign(&p)
+	}
+	return &PublicKey{P: new(bls12_381_ecc.G2Affine).FromJacobian(&acc)}, nil
+}
+
+// AggregateVerify verify aggregate signature of distinct messages using DefaultCiphersuite
+func AggregateVerify(pubs []*PublicKey, msgs [][]byte, sig *bls12_381_ecc.G1Affine) (bool, error) {
+	return DefaultCiphersuite.AggregateVerify(pubs, msgs, sig)
+}
+
+// AggregateVerify verify aggregate signature, msgs[i] is signed by pubs[i]
+// basic scheme requires the messages to be distinct, message augmentation scheme prepends public keys to messages
+// e(sig, g2) = e(H(m_1), pk_1) * ... * e(H(m_n), pk_n)
+func (cs *Ciphersuite) AggregateVerify(pubs []*PublicKey, msgs [][]byte, sig *bls12_381_ecc.G1Affine) (bool, error) {
+	if len(pubs) == 0 {
+		return false, ErrEmptyAggregate
+	}
+	if len(pubs) != len(msgs) {
+		return false, ErrLengthMismatch
+	}
+	if cs == BLS_SIG_BASIC {
+		seen := make(map[string]bool, len(msgs))
+		for _, m := range msgs {
+			if seen[string(m)] {
+				return false, ErrDuplicateMessages
+			}
+			seen[string(m)] = true
+		}
+	}
+	if cs.augment {
+		augmented := make([][]byte, len(msgs))
+		for i := range msgs {
+			// public keys are validated by coreAggregateVerify
+			if pubs[i] == nil || pubs[i].P == nil {
+				return false, ErrInvalidPublicKey
+			}
+			augmented[i] = augment(pubs[i], msgs[i])
+		}
+		msgs = augmented
+	}
+	return coreAggregateVerify(pubs, msgs, sig, cs.ID)
+}
+
+// FastAggregateVerify verify aggregate signature of the same message, only for proof of possession scheme
+// every public key must have a valid proof of possession checked by PopVerify
+func FastAggregateVerify(pubs []*PublicKey, msg []byte, sig *bls12_381_ecc.G1Affine) (bool, error) {
+	aggPub, err := AggregatePublicKeys(pubs)
+	if err != nil {
+		return false, err
+	}
+	return coreVerify(sig, aggPub, msg, BLS_SIG_POP.ID)
+}
+
+// PopProve proof of possession of private key, signature of public key under the POP tag
+func PopProve(key *PrivateKey) *bls12_381_ecc.G1Affine {
+	return coreSign(key, key.Public().Bytes(), popDST)
+}
+
+// PopVerify verify proof of possession of public key
+func PopVerify(pub *PublicKey, proof *bls12_381_ecc.G1Affine) (bool, error) {
+	if !KeyValidate(pub) {
+		return false, ErrInvalidPublicKey
+	}
+	return coreVerify(proof, pub, pub.Bytes(), popDST)
+}
+
+// coreAggregateVerify CoreAggregateVerify of draft section 2.9
+// check e(sig, -g2) * e(H(m_1), pk_1) * ... * e(H(m_n), pk_n) = 1 with a single multi-pairing
+func coreAggregateVerify(pubs []*PublicKey, msgs [][]byte, sig *bls12_381_ecc.G1Affine, dst string) (bool, error) {
+	if sig == nil || !sig.IsOnCurve() || !isInG1(sig) {
+		return false, ErrInvalidSignature
+	}
+	P := make([]bls12_381_ecc.G1Affine, 0, len(pubs)+1)
+	Q := make([]bls12_381_ecc.G2Affine, 0, len(pubs)+1)
+	var negG2 bls12_381_ecc.G2Affine
+	negG2.Neg(&g2Gen)
+	P = append(P, *sig)
+	Q = append(Q, negG2)
+	for i, pub := range pubs {
+		if !KeyValidate(pub) {
+			return false, ErrInvalidPublicKey
+		}
+		q, err := hashToG1(msgs[i], dst)
+		if err != nil {
+			return false, err
+		}
+		P = append(P, q)
+		Q = append(Q, *pub.P)
+	}
+	return bls12_381_ecc.PairingCheck(P, Q)
+}
@@ -1,19 +1,50 @@
 // Package bls implements BLS signature
+// following draft-irtf-cfrg-bls-signature-05, signatures are in G1 and public keys are in G2 (minimal-signature-size)
 package bls
 
 import (
 	"crypto/rand"
 	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
 	"math/big"
 
 	bls12_381_ecc "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	bls12_381_fp "github.com/consensys/gnark-crypto/ecc/bls12-381/fp"
 	bls12_381_fr "github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/hongyanwang/crypto-lab/common/hash_to_point"
+)
+
+const (
+	// PrivateKeySize size of serialized private key
+	PrivateKeySize = 32
+	// PublicKeySize size of compressed public key in G2
+	PublicKeySize = bls12_381_ecc.SizeOfG2AffineCompressed
+	// SignatureSize size of compressed signature in G1
+	SignatureSize = bls12_381_ecc.SizeOfG1AffineCompressed
+
+	keyGenSalt = "BLS-SIG-KEYGEN-SALT-"
+	// L = ceil((3 * ceil(log2(r))) / 16)
+	keyGenLen = 48
+	minIKMLen = 32
+
+	// flags in the most significant byte of ZCash serialization
+	flagCompressed = 0x80
+	flagInfinity   = 0x40
+	flagSign       = 0x20
 )
 
 var (
 	g1Gen bls12_381_ecc.G1Affine
 	g2Gen bls12_381_ecc.G2Affine
 	order *big.Int
+
+	ErrShortIKM         = errors.New("bls: IKM must be at least 32 bytes")
+	ErrInvalidPublicKey = errors.New("bls: invalid public key")
+	ErrInvalidSignature = errors.New("bls: invalid signature")
 )
 
 func init() {
@@ -21,6 +52,23 @@ func init() {
 	order = bls12_381_fr.Modulus()
 }
 
+// Ciphersuite BLS signature ciphersuite, the signing DST and how messages are bound to public keys
+type Ciphersuite struct {
+	ID      string
+	augment bool // message augmentation, sign PK || msg
+}
+
+var (
+	// BLS_SIG_BASIC basic scheme, messages of aggregate signatures must be distinct
+	BLS_SIG_BASIC = &Ciphersuite{ID: "BLS_SIG_BLS12381G1_XMD:SHA-256_SSWU_RO_NUL_"}
+	// BLS_SIG_AUG message augmentation scheme, the public key is prepended to the message
+	BLS_SIG_AUG = &Ciphersuite{ID: "BLS_SIG_BLS12381G1_XMD:SHA-256_SSWU_RO_AUG_", augment: true}
+	// BLS_SIG_POP proof of possession scheme, public keys must come with valid proofs of possession
+	BLS_SIG_POP = &Ciphersuite{ID: "BLS_SIG_BLS12381G1_XMD:SHA-256_SSWU_RO_POP_"}
+
+	DefaultCiphersuite = BLS_SIG_POP
+)
+
 // PrivateKey client private key
 type PrivateKey struct {
 	X *big.Int
@@ -31,59 +79,253 @@ type PublicKey struct {
 	P *bls12_381_ecc.G2Affine
 }
 
+// KeyGen derive private key from secret input key material of at least 32 bytes, KeyGen of draft section 2.3
+//
+//	PRK = HKDF-Extract(salt, IKM || I2OSP(0, 1))
+//	OKM = HKDF-Expand(PRK, key_info || I2OSP(L, 2), L)
+//	SK = OS2IP(OKM) mod r, repeat with salt = H(salt) while SK = 0
+func KeyGen(ikm, keyInfo []byte) (*PrivateKey, error) {
+	if len(ikm) < minIKMLen {
+		return nil, ErrShortIKM
+	}
+	secret := append(append(make([]byte, 0, len(ikm)+1), ikm...), 0)
+	info := append(append(make([]byte, 0, len(keyInfo)+2), keyInfo...), 0, keyGenLen)
+
+	salt := []byte(keyGenSalt)
+	for {
+		okm := make([]byte, keyGenLen)
+		if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, info), okm); err != nil {
+			return nil, err
+		}
+		sk := new(big.Int).SetBytes(okm)
+		sk.Mod(sk, order)
+		if sk.Sign() != 0 {
+			return &PrivateKey{X: sk}, nil
+		}
+		h := sha256.Sum256(salt)
+		salt = h[:]
+	}
+}
+
 // GenRandomKeyPair generate a random BLS private/public key pair for client
 func GenRandomKeyPair() (*PrivateKey, *PublicKey, error) {
-	sk, err := randomWithinOrder()
+	ikm := make([]byte, minIKMLen)
+	if _, err := io.ReadFull(rand.Reader, ikm); err != nil {
+		return nil, nil, err
+	}
+	privkey, err := KeyGen(ikm, nil)
 	if err != nil {
 		return nil, nil, err
 	}
+	return privkey, privkey.Public(), nil
+}
 
-	pk := new(bls12_381_ecc.G2Affine).ScalarMultiplication(&g2Gen, sk)
+// Public compute public key pk = sk * g2
+func (key *PrivateKey) Public() *PublicKey {
+	return &PublicKey{
+		P: new(bls12_381_ecc.G2Affine).ScalarMultiplication(&g2Gen, key.X),
+	}
+}
 
-	privkey := &PrivateKey{
-		X: sk,
+// Bytes serialize private key to 32 bytes big-endian
+func (key *PrivateKey) Bytes() []byte {
+	ret := make([]byte, PrivateKeySize)
+	return key.X.FillBytes(ret)
+}
+
+// PrivateKeyFromBytes deserialize private key, it must be in [1, r-1]
+func PrivateKeyFromBytes(b []byte) (*PrivateKey, error) {
+	if len(b) != PrivateKeySize {
+		return nil, fmt.Errorf("invalid private key length %d", len(b))
 	}
-	pubkey := &PublicKey{
-		P: pk,
+	x := new(big.Int).SetBytes(b)
+	if x.Sign() == 0 || x.Cmp(order) >= 0 {
+		return nil, fmt.Errorf("invalid private key")
 	}
-	return privkey, pubkey, nil
+	return &PrivateKey{X: x}, nil
 }
 
-// Sign generate BLS signature using private key
-// sig = sk * hashToG1(m)
+// Bytes compressed serialization of public key
+func (pub *PublicKey) Bytes() []byte {
+	b := pub.P.Bytes()
+	return b[:]
+}
+
+// PublicKeyFromBytes deserialize compressed public key and validate it by KeyValidate
+func PublicKeyFromBytes(b []byte) (*PublicKey, error) {
+	if len(b) != PublicKeySize {
+		return nil, ErrInvalidPublicKey
+	}
+	if err := checkEncoding(b); err != nil {
+		return nil, ErrInvalidPublicKey
+	}
+	p := new(bls12_381_ecc.G2Affine)
+	if _, err := p.SetBytes(b); err != nil {
+		return nil, ErrInvalidPublicKey
+	}
+	pub := &PublicKey{P: p}
+	if !KeyValidate(pub) {
+		return nil, ErrInvalidPublicKey
+	}
+	return pub, nil
+}
+
+// KeyValidate public key is not the identity and is in G2
+func KeyValidate(pub *PublicKey) bool {
+	if pub == nil || pub.P == nil || pub.P.IsInfinity() || !pub.P.IsOnCurve() {
+		return false
+	}
+	return isInG2(pub.P)
+}
+
+// SignatureToBytes compressed serialization of signature
+func SignatureToBytes(sig *bls12_381_ecc.G1Affine) []byte {
+	b := sig.Bytes()
+	return b[:]
+}
+
+// SignatureFromBytes deserialize compressed signature, it must be in G1
+func SignatureFromBytes(b []byte) (*bls12_381_ecc.G1Affine, error) {
+	if len(b) != SignatureSize {
+		return nil, ErrInvalidSignature
+	}
+	if err := checkEncoding(b); err != nil {
+		return nil, ErrInvalidSignature
+	}
+	sig := new(bls12_381_ecc.G1Affine)
+	if _, err := sig.SetBytes(b); err != nil {
+		return nil, ErrInvalidSignature
+	}
+	if !sig.IsOnCurve() || !isInG1(sig) {
+		return nil, ErrInvalidSignature
+	}
+	return sig, nil
+}
+
+// Sign generate BLS signature using private key and DefaultCiphersuite
 func Sign(key *PrivateKey, msg []byte) *bls12_381_ecc.G1Affine {
-	hash := sha256.Sum256(msg)
-	g1 := hashToG1(hash[:])
-	return new(bls12_381_ecc.G1Affine).ScalarMultiplication(g1, key.X)
+	return DefaultCiphersuite.Sign(key, msg)
 }
 
-// Verify verify BLS signature using public key
-// e(sig, g_2)=e(hashToG1(m), pub)
+// Verify verify BLS signature using public key and DefaultCiphersuite
 func Verify(sig *bls12_381_ecc.G1Affine, pub *PublicKey, msg []byte) (bool, error) {
-	left, err := bls12_381_ecc.Pair([]bls12_381_ecc.G1Affine{*sig}, []bls12_381_ecc.G2Affine{g2Gen})
+	return DefaultCiphersuite.Verify(sig, pub, msg)
+}
+
+// Sign generate BLS signature using private key
+// sig = sk * hash_to_G1(m)
+func (cs *Ciphersuite) Sign(key *PrivateKey, msg []byte) *bls12_381_ecc.G1Affine {
+	if cs.augment {
+		msg = augment(key.Public(), msg)
+	}
+	return coreSign(key, msg, cs.ID)
+}
+
+// Verify verify BLS signature using public key
+// e(sig, g2) = e(hash_to_G1(m), pub)
+func (cs *Ciphersuite) Verify(sig *bls12_381_ecc.G1Affine, pub *PublicKey, msg []byte) (bool, error) {
+	if cs.augment && KeyValidate(pub) {
+		msg = augment(pub, msg)
+	}
+	return coreVerify(sig, pub, msg, cs.ID)
+}
+
+// coreSign CoreSign of draft section 2.6
+func coreSign(key *PrivateKey, msg []byte, dst string) *bls12_381_ecc.G1Affine {
+	q, err := hashToG1(msg, dst)
 	if err != nil {
-		return false, err
+		// never happens, the DST is not empty
+		panic(err)
 	}
-	hash := sha256.Sum256(msg)
-	hashG1 := hashToG1(hash[:])
-	right, err := bls12_381_ecc.Pair([]bls12_381_ecc.G1Affine{*hashG1}, []bls12_381_ecc.G2Affine{*pub.P})
+	return new(bls12_381_ecc.G1Affine).ScalarMultiplication(&q, key.X)
+}
+
+// coreVerify CoreVerify of draft section 2.7, check e(sig, -g2) * e(H(m), pub) = 1
+func coreVerify(sig *bls12_381_ecc.G1Affine, pub *PublicKey, msg []byte, dst string) (bool, error) {
+	if sig == nil || !sig.IsOnCurve() || !isInG1(sig) {
+		return false, ErrInvalidSignature
+	}
+	if !KeyValidate(pub) {
+		return false, ErrInvalidPublicKey
+	}
+	q, err := hashToG1(msg, dst)
 	if err != nil {
 		return false, err
 	}
-	if left.Equal(&right) {
-		return true, nil
+	var negG2 bls12_381_ecc.G2Affine
+	negG2.Neg(&g2Gen)
+	return bls12_381_ecc.PairingCheck(
+		[]bls12_381_ecc.G1Affine{*sig, q},
+		[]bls12_381_ecc.G2Affine{negG2, *pub.P},
+	)
+}
+
+// hashToG1 hash_to_point of draft section 1.3, hash_to_curve of RFC 9380 suite BLS12381G1_XMD:SHA-256_SSWU_RO_
+func hashToG1(msg []byte, dst string) (bls12_381_ecc.G1Affine, error) {
+	return hash_to_point.HashToG1(msg, []byte(dst))
+}
+
+// augment PK || msg
+func augment(pub *PublicKey, msg []byte) []byte {
+	pk := pub.Bytes()
+	return append(append(make([]byte, 0, len(pk)+len(msg)), pk...), msg...)
+}
+
+// checkEncoding only canonical compressed ZCash encodings are accepted
+// the compression flag is set, infinity is all zeros except flags, and x coordinates are smaller than p
+func checkEncoding(b []byte) error {
+	flags := b[0] & (flagCompressed | flagInfinity | flagSign)
+	if flags&flagCompressed == 0 {
+		return fmt.Errorf("point is not compressed")
 	}
-	return false, nil
+	x := append([]byte{}, b...)
+	x[0] &^= flagCompressed | flagInfinity | flagSign
+	if flags&flagInfinity != 0 {
+		if flags&flagSign != 0 {
+			return fmt.Errorf("invalid infinity encoding")
+		}
+		for _, v := range x {
+			if v != 0 {
+				return fmt.Errorf("invalid infinity encoding")
+			}
+		}
+		return nil
+	}
+	// G2 x is encoded as x.A1 || x.A0
+	p := bls12_381_fp.Modulus()
+	for i := 0; i < len(x); i += bls12_381_fp.Bytes {
+		if new(big.Int).SetBytes(x[i:i+bls12_381_fp.Bytes]).Cmp(p) >= 0 {
+			return fmt.Errorf("coordinate is not reduced")
+		}
+	}
+	return nil
 }
 
-// randomWithinOrder generate a random number smaller than the order of G1/G2
-func randomWithinOrder() (*big.Int, error) {
-	return rand.Int(rand.Reader, order)
+// isInG1 check r * p = O
+// gnark subgroup check uses GLV scalar multiplication which assumes the point is already in the subgroup
+func isInG1(p *bls12_381_ecc.G1Affine) bool {
+	var a, res bls12_381_ecc.G1Jac
+	a.FromAffine(p)
+	res.Set(&a)
+	for i := order.BitLen() - 2; i >= 0; i-- {
+		res.DoubleAssign()
+		if order.Bit(i) == 1 {
+			res.AddAssign(&a)
+		}
+	}
+	return res.Z.IsZero()
 }
 
-// hashToG1 define a hash function from big int to point in G1
-func hashToG1(data []byte) *bls12_381_ecc.G1Affine {
-	hash := sha256.Sum256(data)
-	scalar := new(big.Int).SetBytes(hash[:])
-	return new(bls12_381_ecc.G1Affine).ScalarMultiplication(&g1Gen, scalar)
+// isInG2 check r * p = O
+func isInG2(p *bls12_381_ecc.G2Affine) bool {
+	var a, res bls12_381_ecc.G2Jac
+	a.FromAffine(p)
+	res.Set(&a)
+	for i := order.BitLen() - 2; i >= 0; i-- {
+		res.DoubleAssign()
+		if order.Bit(i) == 1 {
+			res.AddAssign(&a)
+		}
+	}
+	return res.Z.IsZero()
 }
@@ -1,6 +1,9 @@
 package bls
 
 import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
 	"testing"
 
 	bls12_381_ecc "github.com/consensys/gnark-crypto/ecc/bls12-381"
@@ -31,6 +34,352 @@ func TestBls(t *testing.T) {
 	}
 }
 
+// sig_g1_basic test vectors of https://github.com/kwantam/bls_sigs_ref, private keys derived by KeyGen(ikm, "")
+var basicVectors = []struct {
+	msg, ikm, sig string
+}{
+	{
+		"ff624d0ba02c7b6370c1622eec3fa2186ea681d1659e0a845448e777b75a8e77a77bb26e5733179d58ef9bc8a4e8b6971aef2539f77ab0963a3415bbd6258339bd1bf55de65db520c63f5b8eab3d55debd05e9494212170f5d65b3286b8b668705b1e2b2b5568610617abb51d2dd0cb450ef59df4b907da90cfa7b268de8c4c2",
+		"708309a7449e156b0db70e5b52e606c7e094ed676ce8953bf6c14757c826f590",
+		"8376eaaae4275ee59263ba2a94c3e664c031bc3177eea3333ba893ab33c8df3f2e8825be3ada8ed6184b2e38367113ab",
+	},
+	{
+		"9155e91fd9155eeed15afd83487ea1a3af04c5998b77c0fe8c43dcc479440a8a9a89efe883d9385cb9edfde10b43bce61fb63669935ad39419cf29ef3a936931733bfc2378e253e73b7ae9a3ec7a6a7932ab10f1e5b94d05160c053988f3bdc9167155d069337d42c9a7056619efc031fa5ec7310d29bd28980b1e3559757578",
+		"90c5386100b137a75b0bb495002b28697a451add2f1f22cb65f735e8aaeace98",
+		"a1c9ab651facbb2687c61320d9e5a4d4ccbfe2f26742ff99ff893bb4eb6eb96bb6f0bbdedb8d3627951762482f7e5338",
+	},
+	{
+		"b242a7586a1383368a33c88264889adfa3be45422fbef4a2df4e3c5325a9c7757017e0d5cf4bbf4de7f99d189f81f1fd2f0dd645574d1eb0d547eead9375677819297c1abe62526ae29fc54cdd11bfe17714f2fbd2d0d0e8d297ff98535980482dd5c1ebdc5a7274aabf1382c9f2315ca61391e3943856e4c5e616c2f1f7be0d",
+		"a3a43cece9c1abeff81099fb344d01f7d8df66447b95a667ee368f924bccf870",
+		"89a0ee09fd60db04f311c603820d1c902d830f32d3d7f7ca3ff08d66b37f7d893de864f9c8f00ca6f4938aa53fdefbe4",
+	},
+}
+
+func TestBasicVectors(t *testing.T) {
+	for i, v := range basicVectors {
+		m, _ := hex.DecodeString(v.msg)
+		ikm, _ := hex.DecodeString(v.ikm)
+		key, err := KeyGen(ikm, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig := BLS_SIG_BASIC.Sign(key, m)
+		if got := hex.EncodeToString(SignatureToBytes(sig)); got != v.sig {
+			t.Errorf("vector %d: signature mismatch, got %s", i, got)
+		}
+		ok, err := BLS_SIG_BASIC.Verify(sig, key.Public(), m)
+		if err != nil || !ok {
+			t.Errorf("vector %d: verification failed: %v", i, err)
+		}
+	}
+}
+
+func TestKeyGen(t *testing.T) {
+	if _, err := KeyGen(make([]byte, 31), nil); err != ErrShortIKM {
+		t.Errorf("short IKM should be rejected")
+	}
+	ikm := bytes.Repeat([]byte{1}, 32)
+	k1, _ := KeyGen(ikm, nil)
+	k2, _ := KeyGen(ikm, nil)
+	k3, _ := KeyGen(ikm, []byte("key info"))
+	if k1.X.Cmp(k2.X) != 0 {
+		t.Errorf("KeyGen is not deterministic")
+	}
+	if k1.X.Cmp(k3.X) == 0 {
+		t.Errorf("key info is ignored")
+	}
+	k4, err := PrivateKeyFromBytes(k1.Bytes())
+	if err != nil || k4.X.Cmp(k1.X) != 0 {
+		t.Errorf("private key serialization failed")
+	}
+	if _, err := PrivateKeyFromBytes(make([]byte, PrivateKeySize)); err == nil {
+		t.Errorf("zero private key should be rejected")
+	}
+}
+
+func TestCiphersuites(t *testing.T) {
+	key, pub, err := GenRandomKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	suites := []*Ciphersuite{BLS_SIG_BASIC, BLS_SIG_AUG, BLS_SIG_POP}
+	for i, cs := range suites {
+		sig := cs.Sign(key, msg)
+		for j, other := range suites {
+			ok, err := other.Verify(sig, pub, msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != (i == j) {
+				t.Errorf("signature of %s verified by %s: %v", cs.ID, other.ID, ok)
+			}
+		}
+		if ok, _ := cs.Verify(sig, pub, []byte("other message")); ok {
+			t.Errorf("%s: signature of another message verified", cs.ID)
+		}
+	}
+}
+
+func TestSerialization(t *testing.T) {
+	g1, _ := hex.DecodeString("97f1d3a73197d7942695638c4fa9ac0fc3688c4f9774b905a14e3a3f171bac586c55e83ff97a1aeffb3af00adb22c6bb")
+	g2, _ := hex.DecodeString("93e02b6052719f607dacd3a088274f65596bd0d09920b61ab5da61bbdc7f5049334cf11213945d57e5ac7d055d042b7e024aa2b2f08f0a91260805272dc51051c6e47ad4fa403b02b4510b647ae3d1770bac0326a805bbefd48056c8c121bdb8")
+	if b := SignatureToBytes(&g1Gen); !bytes.Equal(b, g1) {
+		t.Errorf("G1 generator encoding mismatch: %x", b)
+	}
+	if b := (&PublicKey{P: &g2Gen}).Bytes(); !bytes.Equal(b, g2) {
+		t.Errorf("G2 generator encoding mismatch: %x", b)
+	}
+	if _, err := PublicKeyFromBytes(g2); err != nil {
+		t.Errorf("valid public key rejected: %v", err)
+	}
+
+	key, pub, _ := GenRandomKeyPair()
+	pub2, err := PublicKeyFromBytes(pub.Bytes())
+	if err != nil || !pub2.P.Equal(pub.P) {
+		t.Errorf("public key serialization failed")
+	}
+	sig := Sign(key, msg)
+	sig2, err := SignatureFromBytes(SignatureToBytes(sig))
+	if err != nil || !sig2.Equal(sig) {
+		t.Errorf("signature serialization failed")
+	}
+
+	// infinity is a valid signature encoding but not a valid public key
+	inf1 := make([]byte, SignatureSize)
+	inf1[0] = 0xc0
+	if _, err := SignatureFromBytes(inf1); err != nil {
+		t.Errorf("infinity signature rejected: %v", err)
+	}
+	inf2 := make([]byte, PublicKeySize)
+	inf2[0] = 0xc0
+	if _, err := PublicKeyFromBytes(inf2); err == nil {
+		t.Errorf("infinity public key accepted")
+	}
+}
+
+func TestInvalidEncodings(t *testing.T) {
+	g1, _ := hex.DecodeString("97f1d3a73197d7942695638c4fa9ac0fc3688c4f9774b905a14e3a3f171bac586c55e83ff97a1aeffb3af00adb22c6bb")
+	p, _ := hex.DecodeString("1a0111ea397fe69a4b1ba7b6434bacd764774b84f38512bf6730d2a0f6b0f6241eabfffeb153ffffb9feffffffffaaab")
+
+	invalid := map[string][]byte{
+		"short":             g1[:47],
+		"uncompressed flag": append([]byte{g1[0] & 0x7f}, g1[1:]...),
+		"infinity with x":   append([]byte{0xc0}, g1[1:]...),
+		"infinity sign":     append([]byte{0xe0}, make([]byte, 47)...),
+		"x = p":             append([]byte{p[0] | 0x80}, p[1:]...),
+	}
+	// x = 0 is not on the curve, x = 4 is on E but not in G1
+	notOnCurve := make([]byte, SignatureSize)
+	notOnCurve[0] = 0x80
+	invalid["not on curve"] = notOnCurve
+	notInG1 := make([]byte, SignatureSize)
+	notInG1[0] = 0x80
+	notInG1[47] = 4
+	invalid["not in G1"] = notInG1
+
+	for name, b := range invalid {
+		if _, err := SignatureFromBytes(b); err == nil {
+			t.Errorf("%s: invalid signature accepted", name)
+		}
+	}
+}
+
+// genSigners generate n key pairs with signatures of msgs[i], or of msg if msgs is nil
+func genSigners(t testing.TB, cs *Ciphersuite, n int, msgs [][]byte) ([]*PublicKey, []*bls12_381_ecc.G1Affine) {
+	pubs := make([]*PublicKey, n)
+	sigs := make([]*bls12_381_ecc.G1Affine, n)
+	for i := 0; i < n; i++ {
+		key, pub, err := GenRandomKeyPair()
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := msg
+		if msgs != nil {
+			m = msgs[i]
+		}
+		pubs[i], sigs[i] = pub, cs.Sign(key, m)
+	}
+	return pubs, sigs
+}
+
+func distinctMessages(n int) [][]byte {
+	msgs := make([][]byte, n)
+	for i := range msgs {
+		msgs[i] = []byte(fmt.Sprintf("message %d", i))
+	}
+	return msgs
+}
+
+func TestAggregateVerify(t *testing.T) {
+	msgs := distinctMessages(5)
+	for _, cs := range []*Ciphersuite{BLS_SIG_BASIC, BLS_SIG_AUG, BLS_SIG_POP} {
+		pubs, sigs := genSigners(t, cs, len(msgs), msgs)
+		agg, err := Aggregate(sigs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ok, err := cs.AggregateVerify(pubs, msgs, agg)
+		if err != nil || !ok {
+			t.Errorf("%s: aggregate verification failed: %v", cs.ID, err)
+		}
+		// swap messages of two signers
+		swapped := append([][]byte{msgs[1], msgs[0]}, msgs[2:]...)
+		if ok, _ := cs.AggregateVerify(pubs, swapped, agg); ok {
+			t.Errorf("%s: swapped messages verified", cs.ID)
+		}
+		// drop one signature
+		partial, _ := Aggregate(sigs[1:])
+		if ok, _ := cs.AggregateVerify(pubs, msgs, partial); ok {
+			t.Errorf("%s: incomplete aggregate verified", cs.ID)
+		}
+		if _, err := cs.AggregateVerify(pubs[1:], msgs, agg); err != ErrLengthMismatch {
+			t.Errorf("%s: length mismatch not detected", cs.ID)
+		}
+	}
+
+	// basic scheme rejects repeated messages, augmentation scheme accepts them
+	same := [][]byte{msg, msg}
+	pubs, sigs := genSigners(t, BLS_SIG_BASIC, 2, same)
+	agg, _ := Aggregate(sigs)
+	if _, err := BLS_SIG_BASIC.AggregateVerify(pubs, same, agg); err != ErrDuplicateMessages {
+		t.Errorf("duplicate messages not rejected by basic scheme")
+	}
+	pubs, sigs = genSigners(t, BLS_SIG_AUG, 2, same)
+	agg, _ = Aggregate(sigs)
+	if ok, err := BLS_SIG_AUG.AggregateVerify(pubs, same, agg); err != nil || !ok {
+		t.Errorf("augmentation scheme failed on repeated messages: %v", err)
+	}
+
+	if _, err := Aggregate(nil); err != ErrEmptyAggregate {
+		t.Errorf("empty aggregate not rejected")
+	}
+}
+
+func TestFastAggregateVerify(t *testing.T) {
+	pubs, sigs := genSigners(t, BLS_SIG_POP, 5, nil)
+	agg, err := Aggregate(sigs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, err := FastAggregateVerify(pubs, msg, agg)
+	if err != nil || !ok {
+		t.Errorf("fast aggregate verification failed: %v", err)
+	}
+	if ok, _ := FastAggregateVerify(pubs, []byte("other message"), agg); ok {
+		t.Errorf("signature of another message verified")
+	}
+	if ok, _ := FastAggregateVerify(pubs[1:], msg, agg); ok {
+		t.Errorf("aggregate verified with missing public key")
+	}
+}
+
+func TestPop(t *testing.T) {
+	key, pub, err := GenRandomKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof := PopProve(key)
+	ok, err := PopVerify(pub, proof)
+	if err != nil || !ok {
+		t.Errorf("proof of possession verification failed: %v", err)
+	}
+	// a POP is not a signature of the public key and vice versa
+	if ok, _ := Verify(proof, pub, pub.Bytes()); ok {
+		t.Errorf("proof of possession verified as signature")
+	}
+	if ok, _ := PopVerify(pub, Sign(key, pub.Bytes())); ok {
+		t.Errorf("signature verified as proof of possession")
+	}
+	_, other, _ := GenRandomKeyPair()
+	if ok, _ := PopVerify(other, proof); ok {
+		t.Errorf("proof of possession verified for another key")
+	}
+
+	// rogue key attack: pk_r = pk_a - pk_v lets the attacker forge an aggregate on behalf of pk_v,
+	// but the attacker cannot produce a proof of possession for pk_r
+	keyA, pubA, _ := GenRandomKeyPair()
+	_, pubV, _ := GenRandomKeyPair()
+	var rogue bls12_381_ecc.G2Affine
+	rogue.Neg(pubV.P)
+	rogue.Add(&rogue, pubA.P)
+	pubR := &PublicKey{P: &rogue}
+	forged := Sign(keyA, msg)
+	if ok, _ := FastAggregateVerify([]*PublicKey{pubV, pubR}, msg, forged); !ok {
+		t.Fatalf("rogue key setup failed")
+	}
+	if ok, _ := PopVerify(pubR, PopProve(keyA)); ok {
+		t.Errorf("proof of possession of rogue key verified")
+	}
+}
+
+func TestThreshold(t *testing.T) {
+	const threshold, parties = 3, 5
+	for _, cs := range []*Ciphersuite{BLS_SIG_BASIC, BLS_SIG_AUG, BLS_SIG_POP} {
+		key, pub, err := GenRandomKeyPair()
+		if err != nil {
+			t.Fatal(err)
+		}
+		shares, err := SplitKey(key, threshold, parties)
+		if err != nil {
+			t.Fatal(err)
+		}
+		partials := make([]*PartialSignature, parties)
+		for i, share := range shares {
+			partials[i] = cs.PartialSign(share, msg)
+			ok, err := cs.PartialVerify(partials[i], share.VerificationKey(), pub, msg)
+			if err != nil || !ok {
+				t.Errorf("%s: partial signature %d verification failed: %v", cs.ID, share.Index, err)
+			}
+		}
+		if ok, _ := cs.PartialVerify(partials[0], shares[1].VerificationKey(), pub, msg); ok {
+			t.Errorf("%s: partial signature verified under another share", cs.ID)
+		}
+
+		// any threshold partial signatures combine into the signature of the group key
+		expected := cs.Sign(key, msg)
+		for _, subset := range [][]int{{0, 1, 2}, {4, 2, 0}, {1, 3, 4}, {0, 1, 2, 3, 4}} {
+			selected := make([]*PartialSignature, len(subset))
+			for i, j := range subset {
+				selected[i] = partials[j]
+			}
+			sig, err := Combine(selected)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !sig.Equal(expected) {
+				t.Errorf("%s: combined signature of %v mismatch", cs.ID, subset)
+			}
+			if ok, err := cs.Verify(sig, pub, msg); err != nil || !ok {
+				t.Errorf("%s: combined signature of %v verification failed: %v", cs.ID, subset, err)
+			}
+		}
+
+		// fewer than threshold partial signatures are useless
+		sig, _ := Combine(partials[:threshold-1])
+		if ok, _ := cs.Verify(sig, pub, msg); ok {
+			t.Errorf("%s: signature combined below threshold verified", cs.ID)
+		}
+	}
+}
+
+func TestThresholdInvalid(t *testing.T) {
+	key, _, _ := GenRandomKeyPair()
+	if _, err := SplitKey(key, 4, 3); err == nil {
+		t.Errorf("threshold larger than parties accepted")
+	}
+	if _, err := SplitKey(key, 0, 3); err == nil {
+		t.Errorf("zero threshold accepted")
+	}
+	shares, _ := SplitKey(key, 2, 3)
+	ps := PartialSign(shares[0], msg)
+	if _, err := Combine([]*PartialSignature{ps, ps}); err != ErrDuplicateIndex {
+		t.Errorf("duplicate index not rejected")
+	}
+	if _, err := Combine([]*PartialSignature{{Index: 0, Sig: ps.Sig}}); err != ErrInvalidIndex {
+		t.Errorf("zero index not rejected")
+	}
+}
+
 func BenchmarkKeyGen(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		prvkey, pubkey, _ = GenRandomKeyPair()
@@ -48,3 +397,45 @@ func BenchmarkVerify(b *testing.B) {
 		Verify(sig, pubkey, msg)
 	}
 }
+
+const benchSigners = 1000
+
+func BenchmarkAggregate1k(b *testing.B) {
+	_, sigs := genSigners(b, BLS_SIG_POP, benchSigners, nil)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Aggregate(sigs)
+	}
+}
+
+func BenchmarkAggregateVerify1k(b *testing.B) {
+	msgs := distinctMessages(benchSigners)
+	pubs, sigs := genSigners(b, BLS_SIG_POP, benchSigners, msgs)
+	agg, _ := Aggregate(sigs)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		AggregateVerify(pubs, msgs, agg)
+	}
+}
+
+func BenchmarkFastAggregateVerify1k(b *testing.B) {
+	pubs, sigs := genSigners(b, BLS_SIG_POP, benchSigners, nil)
+	agg, _ := Aggregate(sigs)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		FastAggregateVerify(pubs, msg, agg)
+	}
+}
+
+func BenchmarkCombine(b *testing.B) {
+	key, _, _ := GenRandomKeyPair()
+	shares, _ := SplitKey(key, 7, 10)
+	partials := make([]*PartialSignature, 7)
+	for i := range partials {
+		partials[i] = PartialSign(shares[i], msg)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Combine(partials)
+	}
+}
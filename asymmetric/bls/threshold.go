@@ -0,0 +1,126 @@
+package bls
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	bls12_381_ecc "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/hongyanwang/crypto-lab/advanced/ss/shamir"
+)
+
+var (
+	ErrInvalidIndex   = errors.New("bls: share index must be positive")
+	ErrDuplicateIndex = errors.New("bls: duplicate share index")
+)
+
+// KeyShare Shamir share of a private key over Fr held by party Index
+// Key is f(Index) for a random polynomial f of degree threshold-1 with f(0) = sk, GroupKey is sk * g2
+type KeyShare struct {
+	Index    int
+	Key      *PrivateKey
+	GroupKey *PublicKey
+}
+
+// PartialSignature signature of a message under a key share
+type PartialSignature struct {
+	Index int
+	Sig   *bls12_381_ecc.G1Affine
+}
+
+// SplitKey dealer splits private key into partyNum shares with indices 1..partyNum, any threshold of them can sign
+func SplitKey(key *PrivateKey, threshold, partyNum int) ([]*KeyShare, error) {
+	if threshold < 1 || threshold > partyNum {
+		return nil, fmt.Errorf("invalid threshold %d of %d parties", threshold, partyNum)
+	}
+
+	// share of party i is f(i) for a random polynomial f(x) = sk + a_1*x + ... + a_(t-1)*x^(t-1) over Fr
+	xs := make([]*big.Int, partyNum)
+	for i := range xs {
+		xs[i] = big.NewInt(int64(i + 1))
+	}
+	ys, err := shamir.GenerateSharesModP(xs, threshold, key.X, order)
+	if err != nil {
+		return nil, err
+	}
+	groupKey := key.Public()
+	shares := make([]*KeyShare, partyNum)
+	for i, y := range ys {
+		shares[i] = &KeyShare{Index: i + 1, Key: &PrivateKey{X: y}, GroupKey: groupKey}
+	}
+	return shares, nil
+}
+
+// VerificationKey public key of the share, published by the dealer to verify partial signatures
+func (share *KeyShare) VerificationKey() *PublicKey {
+	return share.Key.Public()
+}
+
+// PartialSign generate partial signature using key share and DefaultCiphersuite
+func PartialSign(share *KeyShare, msg []byte) *PartialSignature {
+	return DefaultCiphersuite.PartialSign(share, msg)
+}
+
+// PartialVerify verify partial signature using DefaultCiphersuite
+func PartialVerify(ps *PartialSignature, verificationKey, groupKey *PublicKey, msg []byte) (bool, error) {
+	return DefaultCiphersuite.PartialVerify(ps, verificationKey, groupKey, msg)
+}
+
+// PartialSign generate partial signature using key share
+// message augmentation scheme binds the message to the group key so that combined signatures verify under it
+func (cs *Ciphersuite) PartialSign(share *KeyShare, msg []byte) *PartialSignature {
+	if cs.augment {
+		msg = augment(share.GroupKey, msg)
+	}
+	return &PartialSignature{
+		Index: share.Index,
+		Sig:   coreSign(share.Key, msg, cs.ID),
+	}
+}
+
+// PartialVerify verify partial signature against verification key of the signing share
+func (cs *Ciphersuite) PartialVerify(ps *PartialSignature, verificationKey, groupKey *PublicKey, msg []byte) (bool, error) {
+	if cs.augment {
+		if !KeyValidate(groupKey) {
+			return false, ErrInvalidPublicKey
+		}
+		msg = augment(groupKey, msg)
+	}
+	return coreVerify(ps.Sig, verificationKey, msg, cs.ID)
+}
+
+// Combine combine at least threshold partial signatures of the same message into a signature under the group key
+// Lagrange interpolation in the exponent, sig = sum( lambda_i * sig_i ), lambda_i = mul( x_j / (x_j - x_i) )
+// invalid partial signatures produce an invalid signature, check them by PartialVerify first
+func Combine(partials []*PartialSignature) (*bls12_381_ecc.G1Affine, error) {
+	if len(partials) == 0 {
+		return nil, ErrEmptyAggregate
+	}
+	xs := make([]*big.Int, len(partials))
+	seen := make(map[int]bool, len(partials))
+	for i, ps := range partials {
+		if ps.Index <= 0 {
+			return nil, ErrInvalidIndex
+		}
+		if seen[ps.Index] {
+			return nil, ErrDuplicateIndex
+		}
+		if ps.Sig == nil || !ps.Sig.IsOnCurve() {
+			return nil, ErrInvalidSignature
+		}
+		seen[ps.Index] = true
+		xs[i] = big.NewInt(int64(ps.Index))
+	}
+
+	var acc, p bls12_381_ecc.G1Jac
+	for i, ps := range partials {
+		p.FromAffine(ps.Sig)
+		lambda, err := shamir.LagrangeAtZero(xs, i, order)
+		if err != nil {
+			return nil, err
+		}
+		p.ScalarMultiplication(&p, lambda)
+		acc.AddAssign(&p)
+	}
+	return new(bls12_381_ecc.G1Affine).FromJacobian(&acc), nil
+}
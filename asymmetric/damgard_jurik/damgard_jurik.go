@@ -0,0 +1,161 @@
+// Package damgard_jurik implements the Damgard-Jurik generalization of Paillier encryption
+// plaintexts are in Z_(N^s) and ciphertexts mod N^(s+1), so the expansion is (s+1)/s instead of 2
+// keys are derived from paillier keys, s = 1 is exactly Paillier with G = N+1
+package damgard_jurik
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/hongyanwang/crypto-lab/asymmetric/paillier"
+	"github.com/hongyanwang/crypto-lab/common/modular"
+)
+
+var one = big.NewInt(1)
+
+// PublicKey Damgard-Jurik public key of degree S
+type PublicKey struct {
+	N   *big.Int
+	S   int
+	NS  *big.Int // NS=N^s, plaintext modulus
+	NS1 *big.Int // NS1=N^(s+1), ciphertext modulus
+}
+
+// PrivateKey Damgard-Jurik private key of degree S
+type PrivateKey struct {
+	PublicKey
+	Lambda *big.Int // Lambda=(P-1)(Q-1)
+	Mu     *big.Int // Mu=Lambda^-1 (mod N^s)
+}
+
+// NewPublicKey public key of degree s for the modulus of a paillier key
+func NewPublicKey(pubkey *paillier.PublicKey, s int) (*PublicKey, error) {
+	if s < 1 {
+		return nil, fmt.Errorf("invalid degree %d", s)
+	}
+	ns := new(big.Int).Exp(pubkey.N, big.NewInt(int64(s)), nil)
+	return &PublicKey{
+		N:   new(big.Int).Set(pubkey.N),
+		S:   s,
+		NS:  ns,
+		NS1: new(big.Int).Mul(ns, pubkey.N),
+	}, nil
+}
+
+// NewPrivateKey private key of degree s reusing a paillier private key
+func NewPrivateKey(prvkey *paillier.PrivateKey, s int) (*PrivateKey, error) {
+	pub, err := NewPublicKey(&prvkey.PublicKey, s)
+	if err != nil {
+		return nil, err
+	}
+	mu := new(big.Int).ModInverse(prvkey.Lambda, pub.NS)
+	if mu == nil {
+		return nil, errors.New("lambda is not invertible mod N^s")
+	}
+	return &PrivateKey{
+		PublicKey: *pub,
+		Lambda:    new(big.Int).Set(prvkey.Lambda),
+		Mu:        mu,
+	}, nil
+}
+
+// GenerateKey generates a paillier key and derives the private key of degree s
+func GenerateKey(secbit, s int) (*PrivateKey, error) {
+	prvkey, err := paillier.GenerateKey(secbit)
+	if err != nil {
+		return nil, err
+	}
+	return NewPrivateKey(prvkey, s)
+}
+
+// Encrypt encrypt message in [0, N^s) using public key
+// c=(1+N)^m*r^(N^s) (mod N^(s+1))
+func Encrypt(m *big.Int, pubkey *PublicKey) (*big.Int, error) {
+	if m.Sign() < 0 || m.Cmp(pubkey.NS) >= 0 {
+		return nil, errors.New("message must be in [0, N^s)")
+	}
+	r, err := modular.RandomUnit(pubkey.N)
+	if err != nil {
+		return nil, err
+	}
+	rn := r.Exp(r, pubkey.NS, pubkey.NS1)
+	c := pubkey.expOnePlusN(m)
+	c.Mul(c, rn)
+	return c.Mod(c, pubkey.NS1), nil
+}
+
+// Decrypt decrypt ciphertext using private key
+// c^Lambda = (1+N)^(m*Lambda), m*Lambda (mod N^s) is extracted digit by digit, then m = (m*Lambda)*Mu (mod N^s)
+func Decrypt(c *big.Int, prvkey *PrivateKey) (*big.Int, error) {
+	if c.Sign() <= 0 || c.Cmp(prvkey.NS1) >= 0 {
+		return nil, errors.New("ciphertext must be in (0, N^(s+1))")
+	}
+	a := new(big.Int).Exp(c, prvkey.Lambda, prvkey.NS1)
+	m := prvkey.logOnePlusN(a)
+	m.Mul(m, prvkey.Mu)
+	return m.Mod(m, prvkey.NS), nil
+}
+
+// Add multiply two ciphertext to get encryption of the addition of two numbers
+// enc(m1) * enc(m2) = enc(m1+m2 mod N^s)
+func Add(cipher1, cipher2 *big.Int, pubkey *PublicKey) *big.Int {
+	return new(big.Int).Mod(new(big.Int).Mul(cipher1, cipher2), pubkey.NS1)
+}
+
+// ScalarMul exponent of ciphertext is encryption of the scalar multiplication of a number
+// enc(s*m) = enc(m)^s
+func ScalarMul(cipher, scalar *big.Int, pubkey *PublicKey) *big.Int {
+	return new(big.Int).Exp(cipher, scalar, pubkey.NS1)
+}
+
+// expOnePlusN (1+N)^m (mod N^(s+1)) by the binomial expansion sum( C(m,k)*N^k ) for k = 0..s
+func (pubkey *PublicKey) expOnePlusN(m *big.Int) *big.Int {
+	ret := big.NewInt(1)
+	binom := big.NewInt(1)
+	nk := big.NewInt(1)
+	for k := 1; k <= pubkey.S; k++ {
+		// C(m,k) = C(m,k-1) * (m-k+1) / k, exact over the integers
+		binom.Mul(binom, new(big.Int).Sub(m, big.NewInt(int64(k-1))))
+		binom.Quo(binom, big.NewInt(int64(k)))
+		nk.Mul(nk, pubkey.N)
+		ret.Add(ret, new(big.Int).Mul(binom, nk))
+	}
+	return ret.Mod(ret, pubkey.NS1)
+}
+
+// logOnePlusN i in [0, N^s) with (1+N)^i = a (mod N^(s+1)), Damgard-Jurik recursive algorithm
+// i_j = i mod N^j is found from L(a mod N^(j+1)) = sum( C(i_j,k)*N^(k-1) ) for k = 1..j and i_(j-1)
+func (pubkey *PublicKey) logOnePlusN(a *big.Int) *big.Int {
+	n := pubkey.N
+	i := new(big.Int)
+	nj := new(big.Int).Set(n)     // N^j
+	nj1 := new(big.Int).Mul(n, n) // N^(j+1)
+	for j := 1; j <= pubkey.S; j++ {
+		// t1 = L(a mod N^(j+1)) = (a mod N^(j+1) - 1) / N
+		t1 := new(big.Int).Mod(a, nj1)
+		t1.Sub(t1, one)
+		t1.Div(t1, n)
+
+		// subtract C(i,k)*N^(k-1) for k = 2..j, where i = i_(j-1) and C(i,k) = i(i-1)...(i-k+1)/k!
+		t2 := new(big.Int).Set(i)
+		ii := new(big.Int).Set(i)
+		nk := big.NewInt(1) // N^(k-1)
+		kfact := big.NewInt(1)
+		for k := 2; k <= j; k++ {
+			ii.Sub(ii, one)
+			t2.Mul(t2, ii)
+			t2.Mod(t2, nj)
+			nk.Mul(nk, n)
+			kfact.Mul(kfact, big.NewInt(int64(k)))
+			term := new(big.Int).Mul(t2, nk)
+			term.Mul(term, new(big.Int).ModInverse(kfact, nj))
+			t1.Sub(t1, term)
+			t1.Mod(t1, nj)
+		}
+		i = t1
+		nj.Mul(nj, n)
+		nj1.Mul(nj1, n)
+	}
+	return i
+}
@@ -0,0 +1,121 @@
+package damgard_jurik
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/hongyanwang/crypto-lab/asymmetric/paillier"
+)
+
+var secbit = 1024
+
+func TestEncDec(t *testing.T) {
+	paillierKey, err := paillier.GenerateKey(secbit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []int{1, 2, 3, 5} {
+		prvkey, err := NewPrivateKey(paillierKey, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if prvkey.NS.BitLen() < s*(secbit-1) {
+			t.Fatalf("s = %d: plaintext space too small", s)
+		}
+		max := new(big.Int).Sub(prvkey.NS, big.NewInt(1))
+		random, _ := rand.Int(rand.Reader, prvkey.NS)
+		for _, m := range []*big.Int{big.NewInt(0), big.NewInt(1), big.NewInt(123456789), max, random} {
+			c, err := Encrypt(m, &prvkey.PublicKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.Cmp(prvkey.NS1) >= 0 {
+				t.Fatalf("s = %d: ciphertext out of range", s)
+			}
+			ret, err := Decrypt(c, prvkey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ret.Cmp(m) != 0 {
+				t.Fatalf("s = %d: expect %s, got %s", s, m, ret)
+			}
+		}
+		if _, err := Encrypt(prvkey.NS, &prvkey.PublicKey); err == nil {
+			t.Fatalf("s = %d: message N^s encrypted", s)
+		}
+		if _, err := Decrypt(prvkey.NS1, prvkey); err == nil {
+			t.Fatalf("s = %d: ciphertext N^(s+1) decrypted", s)
+		}
+	}
+}
+
+func TestHomomorphic(t *testing.T) {
+	prvkey, err := GenerateKey(secbit, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := &prvkey.PublicKey
+	// operands larger than N
+	m1 := new(big.Int).Lsh(big.NewInt(25), uint(secbit+100))
+	m2 := new(big.Int).Lsh(big.NewInt(12), uint(2*secbit))
+	scalar := big.NewInt(10)
+	c1, _ := Encrypt(m1, pub)
+	c2, _ := Encrypt(m2, pub)
+
+	sum, _ := Decrypt(Add(c1, c2, pub), prvkey)
+	if expect := new(big.Int).Add(m1, m2); sum.Cmp(expect) != 0 {
+		t.Fatal("homomorphic addition failed")
+	}
+	mul, _ := Decrypt(ScalarMul(c1, scalar, pub), prvkey)
+	if expect := new(big.Int).Mul(m1, scalar); mul.Cmp(expect) != 0 {
+		t.Fatal("homomorphic scalar multiplication failed")
+	}
+	// results are reduced mod N^s
+	neg, _ := Decrypt(ScalarMul(c2, new(big.Int).Sub(pub.NS, one), pub), prvkey)
+	if expect := new(big.Int).Sub(pub.NS, m2); neg.Cmp(expect) != 0 {
+		t.Fatal("homomorphic negation failed")
+	}
+}
+
+func TestPaillierCompatible(t *testing.T) {
+	paillierKey, err := paillier.GenerateKey(secbit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prvkey, err := NewPrivateKey(paillierKey, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := big.NewInt(987654321)
+	c, _ := Encrypt(m, &prvkey.PublicKey)
+	if ret, _ := paillier.Decrypt(c, paillierKey); ret.Cmp(m) != 0 {
+		t.Fatal("paillier can not decrypt degree 1 ciphertext")
+	}
+	c, _ = paillier.Encrypt(m, &paillierKey.PublicKey)
+	if ret, _ := Decrypt(c, prvkey); ret.Cmp(m) != 0 {
+		t.Fatal("paillier ciphertext can not be decrypted with degree 1")
+	}
+}
+
+func benchmarkDec(b *testing.B, s int) {
+	prvkey, _ := GenerateKey(secbit, s)
+	m, _ := rand.Int(rand.Reader, prvkey.NS)
+	c, _ := Encrypt(m, &prvkey.PublicKey)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Decrypt(c, prvkey)
+	}
+}
+
+func BenchmarkDecS1(b *testing.B) { benchmarkDec(b, 1) }
+func BenchmarkDecS3(b *testing.B) { benchmarkDec(b, 3) }
+
+func BenchmarkEncS3(b *testing.B) {
+	prvkey, _ := GenerateKey(secbit, 3)
+	m, _ := rand.Int(rand.Reader, prvkey.NS)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Encrypt(m, &prvkey.PublicKey)
+	}
+}
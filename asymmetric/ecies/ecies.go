@@ -70,7 +70,9 @@ func GenerateKey(rand io.Reader, curve elliptic.Curve, params *ECIESParams) (prv
 	prv.PublicKey.Y = y
 	prv.PublicKey.Curve = curve
 	prv.D = new(big.Int).SetBytes(pb)
-	params = DefaultParams
+	if params == nil {
+		params = DefaultParams
+	}
 	prv.PublicKey.Params = params
 	return
 }
@@ -196,11 +198,19 @@ func symDecrypt(params *ECIESParams, key, ct []byte) (m []byte, err error) {
 	return
 }
 
-// Encrypt encrypts a message
+// paramsOf returns params of the key, DefaultParams if not set
+func paramsOf(pub *PublicKey) *ECIESParams {
+	if pub.Params == nil {
+		return DefaultParams
+	}
+	return pub.Params
+}
+
+// Encrypt encrypts a message using the curve and params of the public key
 func Encrypt(rand io.Reader, pub *PublicKey, m []byte) (ct []byte, err error) {
-	params := DefaultParams
+	params := paramsOf(pub)
 
-	privkey, err := GenerateKey(rand, DefaultCurve, nil)
+	privkey, err := GenerateKey(rand, pub.Curve, params)
 	if err != nil {
 		return nil, err
 	}
@@ -235,13 +245,13 @@ func Encrypt(rand io.Reader, pub *PublicKey, m []byte) (ct []byte, err error) {
 
 // Decrypt decrypts an ECIES ciphertext
 func (prv *PrivateKey) Decrypt(c []byte) (m []byte, err error) {
-	if len(c) == 0 {
-		return nil, ErrInvalidMessage
-	}
-	params := DefaultParams
+	params := paramsOf(&prv.PublicKey)
 	hash := params.Hash()
 
 	rLen := (prv.Curve.Params().BitSize + 7) / 4
+	if len(c) <= rLen+params.BlockSize {
+		return nil, ErrInvalidMessage
+	}
 
 	R := new(PublicKey)
 	R.Curve = prv.Curve
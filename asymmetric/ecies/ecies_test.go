@@ -2,9 +2,12 @@ package ecies
 
 import (
 	"bytes"
+	"crypto/elliptic"
 	"crypto/rand"
 	"fmt"
 	"testing"
+
+	"github.com/hongyanwang/crypto-lab/asymmetric/sm2"
 )
 
 func TestEcies(t *testing.T) {
@@ -31,3 +34,32 @@ func TestEcies(t *testing.T) {
 		t.Errorf("ecies: plaintext doesn't match message")
 	}
 }
+
+func TestEciesSM4(t *testing.T) {
+	msg := []byte("ecies sm4 test msg")
+	curves := []elliptic.Curve{DefaultCurve, sm2.P256Sm2()}
+	for _, curve := range curves {
+		for _, params := range []*ECIESParams{ECIES_SM4_SHA256, ECIES_SM4_SM3} {
+			testEcies(t, curve, params, msg)
+		}
+	}
+}
+
+func testEcies(t *testing.T, curve elliptic.Curve, params *ECIESParams, msg []byte) {
+	prv, err := GenerateKey(rand.Reader, curve, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ct, err := Encrypt(rand.Reader, &prv.PublicKey, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pt, err := prv.Decrypt(ct)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pt, msg) {
+		t.Errorf("ecies on %s: plaintext doesn't match message", curve.Params().Name)
+	}
+}
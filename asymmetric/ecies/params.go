@@ -7,6 +7,9 @@ import (
 	"crypto/elliptic"
 	"crypto/sha256"
 	"hash"
+
+	"github.com/hongyanwang/crypto-lab/hash/sm3"
+	"github.com/hongyanwang/crypto-lab/symmetric/sm4"
 )
 
 var (
@@ -30,4 +33,20 @@ var (
 		BlockSize: aes.BlockSize,
 		KeyLen:    16,
 	}
+
+	ECIES_SM4_SHA256 = &ECIESParams{
+		Hash:      sha256.New,
+		hashAlgo:  crypto.SHA256,
+		Cipher:    sm4.NewCipher,
+		BlockSize: sm4.BlockSize,
+		KeyLen:    sm4.KeySize,
+	}
+
+	// sm3 is not registered in crypto.Hash
+	ECIES_SM4_SM3 = &ECIESParams{
+		Hash:      sm3.New,
+		Cipher:    sm4.NewCipher,
+		BlockSize: sm4.BlockSize,
+		KeyLen:    sm4.KeySize,
+	}
 )
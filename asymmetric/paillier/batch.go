@@ -0,0 +1,256 @@
+package paillier
+
+import (
+	"context"
+	"math/big"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/hongyanwang/crypto-lab/common/modular"
+)
+
+// Pool precomputes randomizers r^N (mod N^2) in background goroutines
+// encryption with a filled pool only costs a multiplication, an empty pool falls back to computing r^N inline
+type Pool struct {
+	pubkey *PublicKey
+	next   func() (*big.Int, error)
+	ch     chan *big.Int
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewPool starts workers goroutines keeping up to size randomizers of pubkey, until ctx is done or Close is called
+// workers <= 0 uses runtime.NumCPU()
+func NewPool(ctx context.Context, pubkey *PublicKey, size, workers int) *Pool {
+	return newPool(ctx, pubkey, func() (*big.Int, error) { return randomizer(pubkey) }, size, workers)
+}
+
+// NewPoolCRT pool of randomizers computed by CRT with the private key, about twice as fast to fill as NewPool
+func NewPoolCRT(ctx context.Context, prvkey *PrivateKey, size, workers int) *Pool {
+	return newPool(ctx, &prvkey.PublicKey, prvkey.randomizerCRT, size, workers)
+}
+
+func newPool(ctx context.Context, pubkey *PublicKey, next func() (*big.Int, error), size, workers int) *Pool {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	pool := &Pool{
+		pubkey: pubkey,
+		next:   next,
+		ch:     make(chan *big.Int, size),
+		cancel: cancel,
+	}
+	for i := 0; i < workers; i++ {
+		pool.wg.Add(1)
+		go pool.fill(ctx)
+	}
+	return pool
+}
+
+// fill computes randomizers until ctx is done, a worker stops on error and Randomizer computes inline instead
+func (pool *Pool) fill(ctx context.Context) {
+	defer pool.wg.Done()
+	for {
+		rn, err := pool.next()
+		if err != nil {
+			return
+		}
+		select {
+		case pool.ch <- rn:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Close stops the background workers, randomizers already in the pool can still be used
+func (pool *Pool) Close() {
+	pool.cancel()
+	pool.wg.Wait()
+}
+
+// Randomizer r^N (mod N^2) for a fresh random r, taken from the pool if available
+func (pool *Pool) Randomizer() (*big.Int, error) {
+	select {
+	case rn := <-pool.ch:
+		return rn, nil
+	default:
+		return pool.next()
+	}
+}
+
+// Encrypt encrypt message using a precomputed randomizer
+// c=(1+m*N)*r^N (mod N^2)
+func (pool *Pool) Encrypt(m *big.Int) (*big.Int, error) {
+	rn, err := pool.Randomizer()
+	if err != nil {
+		return nil, err
+	}
+	c := pool.pubkey.expG(m)
+	c.Mul(c, rn)
+	return c.Mod(c, pool.pubkey.NN), nil
+}
+
+// EncryptBatch encrypt messages in parallel using precomputed randomizers
+func (pool *Pool) EncryptBatch(ctx context.Context, ms []*big.Int, workers int) ([]*big.Int, error) {
+	cs := make([]*big.Int, len(ms))
+	err := batch(ctx, len(ms), workers, func(i int) (err error) {
+		cs[i], err = pool.Encrypt(ms[i])
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+// EncryptCRT encrypt message with the private key, r^N is computed mod P^2 and Q^2 and combined by CRT
+func EncryptCRT(m *big.Int, prvkey *PrivateKey) (*big.Int, error) {
+	rn, err := prvkey.randomizerCRT()
+	if err != nil {
+		return nil, err
+	}
+	c := prvkey.expG(m)
+	c.Mul(c, rn)
+	return c.Mod(c, prvkey.NN), nil
+}
+
+// EncryptBatch encrypt messages in parallel using workers goroutines, workers <= 0 uses runtime.NumCPU()
+// the first error or the cancellation of ctx stops the batch
+func EncryptBatch(ctx context.Context, ms []*big.Int, pubkey *PublicKey, workers int) ([]*big.Int, error) {
+	cs := make([]*big.Int, len(ms))
+	err := batch(ctx, len(ms), workers, func(i int) (err error) {
+		cs[i], err = Encrypt(ms[i], pubkey)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+// EncryptBatchCRT encrypt messages in parallel with the private key, see EncryptCRT
+func EncryptBatchCRT(ctx context.Context, ms []*big.Int, prvkey *PrivateKey, workers int) ([]*big.Int, error) {
+	cs := make([]*big.Int, len(ms))
+	err := batch(ctx, len(ms), workers, func(i int) error {
+		rn, err := prvkey.randomizerCRT()
+		if err != nil {
+			return err
+		}
+		c := prvkey.expG(ms[i])
+		c.Mul(c, rn)
+		cs[i] = c.Mod(c, prvkey.NN)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+// DecryptBatch decrypt ciphertexts in parallel using workers goroutines, workers <= 0 uses runtime.NumCPU()
+func DecryptBatch(ctx context.Context, cs []*big.Int, prvkey *PrivateKey, workers int) ([]*big.Int, error) {
+	ms := make([]*big.Int, len(cs))
+	err := batch(ctx, len(cs), workers, func(i int) (err error) {
+		ms[i], err = Decrypt(cs[i], prvkey)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ms, nil
+}
+
+// batch runs f(0), ..., f(n-1) on workers goroutines and returns the first error
+func batch(ctx context.Context, n, workers int, f func(i int) error) error {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > n {
+		workers = n
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg   sync.WaitGroup
+		once sync.Once
+		err  error
+		next int64 = -1
+	)
+	fail := func(e error) {
+		once.Do(func() { err = e })
+		cancel()
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= n {
+					return
+				}
+				if e := ctx.Err(); e != nil {
+					fail(e)
+					return
+				}
+				if e := f(i); e != nil {
+					fail(e)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return err
+}
+
+// randomizer r^N (mod N^2) for a random r in Z_N*
+func randomizer(pubkey *PublicKey) (*big.Int, error) {
+	r, err := modular.RandomUnit(pubkey.N)
+	if err != nil {
+		return nil, err
+	}
+	return r.Exp(r, pubkey.N, pubkey.NN), nil
+}
+
+// crtParams exponents and coefficient to compute r^N mod P^2 and Q^2, cached on the private key by NewPrivateKey
+type crtParams struct {
+	expP  *big.Int // N mod P(P-1), the order of Z_(P^2)*
+	expQ  *big.Int // N mod Q(Q-1)
+	ppInv *big.Int // (P^2)^-1 mod Q^2
+}
+
+func newCRTParams(p, q, n, pp, qq *big.Int) *crtParams {
+	ordP := new(big.Int).Mul(p, new(big.Int).Sub(p, one))
+	ordQ := new(big.Int).Mul(q, new(big.Int).Sub(q, one))
+	return &crtParams{
+		expP:  new(big.Int).Mod(n, ordP),
+		expQ:  new(big.Int).Mod(n, ordQ),
+		ppInv: new(big.Int).ModInverse(pp, qq),
+	}
+}
+
+// randomizerCRT r^N (mod N^2) = CRT(r^N mod P^2, r^N mod Q^2)
+// keys not built by NewPrivateKey compute the CRT parameters on every call
+func (prvkey *PrivateKey) randomizerCRT() (*big.Int, error) {
+	crt := prvkey.crt
+	if crt == nil {
+		crt = newCRTParams(prvkey.P, prvkey.Q, prvkey.N, prvkey.PP, prvkey.QQ)
+	}
+	r, err := modular.RandomUnit(prvkey.N)
+	if err != nil {
+		return nil, err
+	}
+	rp := new(big.Int).Exp(r, crt.expP, prvkey.PP)
+	rq := new(big.Int).Exp(r, crt.expQ, prvkey.QQ)
+	rq.Sub(rq, rp)
+	rq.Mul(rq, crt.ppInv)
+	rq.Mod(rq, prvkey.QQ)
+	rq.Mul(rq, prvkey.PP)
+	rq.Add(rq, rp)
+	return rq.Mod(rq, prvkey.NN), nil
+}
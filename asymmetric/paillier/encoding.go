@@ -0,0 +1,361 @@
+package paillier
+
+import (
+	"errors"
+	"math"
+	"math/big"
+)
+
+// Fixed-point encoding of signed numbers, following python-paillier
+//
+//	value = mantissa * Base^Exponent, Encoding = mantissa (mod N)
+//
+// non-negative mantissas are in [0, MaxInt], negative ones in the top half [N-MaxInt, N),
+// MaxInt = N/3 - 1 leaves a gap in the middle, a decrypted encoding in the gap is reported as ErrOverflow
+const (
+	// Base base of the exponent of encoded numbers
+	Base = 16
+
+	log2Base          = 4
+	floatMantissaBits = 53
+)
+
+var (
+	ErrOverflow      = errors.New("paillier: number overflow")
+	ErrInvalidNumber = errors.New("paillier: number is NaN or infinite")
+	ErrNotInteger    = errors.New("paillier: number is not an integer")
+	ErrKeyMismatch   = errors.New("paillier: numbers are encrypted under different keys")
+	ErrExponent      = errors.New("paillier: exponent can only be decreased")
+)
+
+// EncodedNumber plaintext fixed-point number Encoding * Base^Exponent
+type EncodedNumber struct {
+	PublicKey *PublicKey
+	Encoding  *big.Int // mantissa (mod N)
+	Exponent  int
+}
+
+// EncryptedNumber ciphertext of an EncodedNumber, the exponent is public
+type EncryptedNumber struct {
+	PublicKey  *PublicKey
+	Ciphertext *big.Int
+	Exponent   int
+}
+
+// MaxInt largest absolute value of a mantissa, N/3 - 1
+func (pubkey *PublicKey) MaxInt() *big.Int {
+	max := new(big.Int).Div(pubkey.N, big.NewInt(3))
+	return max.Sub(max, one)
+}
+
+// EncodeInt encode integer with exponent 0
+func EncodeInt(pubkey *PublicKey, x *big.Int) (*EncodedNumber, error) {
+	return encodeMantissa(pubkey, x, 0)
+}
+
+// EncodeFloat encode float with the exponent of its least significant mantissa bit, the encoding is exact
+func EncodeFloat(pubkey *PublicKey, f float64) (*EncodedNumber, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, ErrInvalidNumber
+	}
+	_, binExp := math.Frexp(f)
+	exponent := floorDiv(binExp-floatMantissaBits, log2Base)
+	return encodeFloat(pubkey, f, exponent)
+}
+
+// EncodeFloatWithPrecision encode float rounded to a multiple of Base^floor(log_Base(precision))
+// e.g. precision 1e-6 keeps at least 6 decimal digits
+func EncodeFloatWithPrecision(pubkey *PublicKey, f, precision float64) (*EncodedNumber, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, ErrInvalidNumber
+	}
+	if !(precision > 0) || math.IsInf(precision, 0) {
+		return nil, ErrInvalidNumber
+	}
+	exponent := int(math.Floor(math.Log(precision) / math.Log(Base)))
+	return encodeFloat(pubkey, f, exponent)
+}
+
+// encodeFloat mantissa round(f / Base^exponent), half away from zero
+func encodeFloat(pubkey *PublicKey, f float64, exponent int) (*EncodedNumber, error) {
+	r := new(big.Rat).SetFloat64(f)
+	r.Mul(r, powBase(-exponent))
+	mantissa, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	// |rem| / denom >= 1/2
+	if rem.Lsh(rem.Abs(rem), 1).Cmp(r.Denom()) >= 0 {
+		if r.Sign() < 0 {
+			mantissa.Sub(mantissa, one)
+		} else {
+			mantissa.Add(mantissa, one)
+		}
+	}
+	return encodeMantissa(pubkey, mantissa, exponent)
+}
+
+// encodeMantissa check |mantissa| <= MaxInt and reduce it mod N
+func encodeMantissa(pubkey *PublicKey, mantissa *big.Int, exponent int) (*EncodedNumber, error) {
+	if new(big.Int).Abs(mantissa).Cmp(pubkey.MaxInt()) > 0 {
+		return nil, ErrOverflow
+	}
+	return &EncodedNumber{
+		PublicKey: pubkey,
+		Encoding:  new(big.Int).Mod(mantissa, pubkey.N),
+		Exponent:  exponent,
+	}, nil
+}
+
+// Mantissa signed mantissa, ErrOverflow if the encoding is in the gap between positive and negative numbers
+func (enc *EncodedNumber) Mantissa() (*big.Int, error) {
+	n := enc.PublicKey.N
+	max := enc.PublicKey.MaxInt()
+	if enc.Encoding.Sign() < 0 || enc.Encoding.Cmp(n) >= 0 {
+		return nil, ErrOverflow
+	}
+	if enc.Encoding.Cmp(max) <= 0 {
+		return new(big.Int).Set(enc.Encoding), nil
+	}
+	if new(big.Int).Sub(n, enc.Encoding).Cmp(max) <= 0 {
+		return new(big.Int).Sub(enc.Encoding, n), nil
+	}
+	return nil, ErrOverflow
+}
+
+// Rat exact value mantissa * Base^Exponent
+func (enc *EncodedNumber) Rat() (*big.Rat, error) {
+	mantissa, err := enc.Mantissa()
+	if err != nil {
+		return nil, err
+	}
+	r := new(big.Rat).SetInt(mantissa)
+	return r.Mul(r, powBase(enc.Exponent)), nil
+}
+
+// Float64 nearest float64 of the value
+func (enc *EncodedNumber) Float64() (float64, error) {
+	r, err := enc.Rat()
+	if err != nil {
+		return 0, err
+	}
+	f, _ := r.Float64()
+	return f, nil
+}
+
+// Int integer value, ErrNotInteger if it has a fractional part
+func (enc *EncodedNumber) Int() (*big.Int, error) {
+	r, err := enc.Rat()
+	if err != nil {
+		return nil, err
+	}
+	if !r.IsInt() {
+		return nil, ErrNotInteger
+	}
+	return new(big.Int).Set(r.Num()), nil
+}
+
+// DecreaseExponentTo same value with a smaller exponent, Encoding * Base^(Exponent-exponent)
+// the mantissa grows and may overflow, which is only detected on decoding
+func (enc *EncodedNumber) DecreaseExponentTo(exponent int) (*EncodedNumber, error) {
+	if exponent > enc.Exponent {
+		return nil, ErrExponent
+	}
+	factor := new(big.Int).Exp(big.NewInt(Base), big.NewInt(int64(enc.Exponent-exponent)), enc.PublicKey.N)
+	encoding := factor.Mul(factor, enc.Encoding)
+	return &EncodedNumber{
+		PublicKey: enc.PublicKey,
+		Encoding:  encoding.Mod(encoding, enc.PublicKey.N),
+		Exponent:  exponent,
+	}, nil
+}
+
+// EncryptEncoded encrypt encoded number
+func EncryptEncoded(enc *EncodedNumber) (*EncryptedNumber, error) {
+	c, err := Encrypt(enc.Encoding, enc.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return &EncryptedNumber{PublicKey: enc.PublicKey, Ciphertext: c, Exponent: enc.Exponent}, nil
+}
+
+// EncryptInt encode and encrypt integer
+func EncryptInt(pubkey *PublicKey, x *big.Int) (*EncryptedNumber, error) {
+	enc, err := EncodeInt(pubkey, x)
+	if err != nil {
+		return nil, err
+	}
+	return EncryptEncoded(enc)
+}
+
+// EncryptFloat encode and encrypt float exactly
+func EncryptFloat(pubkey *PublicKey, f float64) (*EncryptedNumber, error) {
+	enc, err := EncodeFloat(pubkey, f)
+	if err != nil {
+		return nil, err
+	}
+	return EncryptEncoded(enc)
+}
+
+// DecryptEncoded decrypt encrypted number to encoded number
+func DecryptEncoded(e *EncryptedNumber, prvkey *PrivateKey) (*EncodedNumber, error) {
+	if e.PublicKey.N.Cmp(prvkey.N) != 0 {
+		return nil, ErrKeyMismatch
+	}
+	m, err := Decrypt(e.Ciphertext, prvkey)
+	if err != nil {
+		return nil, err
+	}
+	return &EncodedNumber{PublicKey: &prvkey.PublicKey, Encoding: m, Exponent: e.Exponent}, nil
+}
+
+// DecryptInt decrypt integer, ErrOverflow if the result exceeds MaxInt
+func DecryptInt(e *EncryptedNumber, prvkey *PrivateKey) (*big.Int, error) {
+	enc, err := DecryptEncoded(e, prvkey)
+	if err != nil {
+		return nil, err
+	}
+	return enc.Int()
+}
+
+// DecryptFloat decrypt float, ErrOverflow if the result exceeds MaxInt
+func DecryptFloat(e *EncryptedNumber, prvkey *PrivateKey) (float64, error) {
+	enc, err := DecryptEncoded(e, prvkey)
+	if err != nil {
+		return 0, err
+	}
+	return enc.Float64()
+}
+
+// Add homomorphic addition, exponents are aligned to the smaller one
+func (e *EncryptedNumber) Add(other *EncryptedNumber) (*EncryptedNumber, error) {
+	if e.PublicKey.N.Cmp(other.PublicKey.N) != 0 {
+		return nil, ErrKeyMismatch
+	}
+	a, b, err := alignEncrypted(e, other)
+	if err != nil {
+		return nil, err
+	}
+	return &EncryptedNumber{
+		PublicKey:  e.PublicKey,
+		Ciphertext: Add(a.Ciphertext, b.Ciphertext, e.PublicKey),
+		Exponent:   a.Exponent,
+	}, nil
+}
+
+// AddPlain add plaintext number, c * G^m
+func (e *EncryptedNumber) AddPlain(plain *EncodedNumber) (*EncryptedNumber, error) {
+	if e.PublicKey.N.Cmp(plain.PublicKey.N) != 0 {
+		return nil, ErrKeyMismatch
+	}
+	a, p := e, plain
+	var err error
+	if a.Exponent > p.Exponent {
+		a, err = a.DecreaseExponentTo(p.Exponent)
+	} else if p.Exponent > a.Exponent {
+		p, err = p.DecreaseExponentTo(a.Exponent)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// G^m = 1 + m*N (mod N^2) for G = N+1
+	gm := new(big.Int).Mul(p.Encoding, e.PublicKey.N)
+	gm.Add(gm, one)
+	return &EncryptedNumber{
+		PublicKey:  e.PublicKey,
+		Ciphertext: Add(a.Ciphertext, gm, e.PublicKey),
+		Exponent:   a.Exponent,
+	}, nil
+}
+
+// Sub homomorphic subtraction e - other
+func (e *EncryptedNumber) Sub(other *EncryptedNumber) (*EncryptedNumber, error) {
+	return e.Add(other.Neg())
+}
+
+// Neg encryption of -m, c^-1 (mod N^2)
+func (e *EncryptedNumber) Neg() *EncryptedNumber {
+	return &EncryptedNumber{
+		PublicKey:  e.PublicKey,
+		Ciphertext: new(big.Int).ModInverse(e.Ciphertext, e.PublicKey.NN),
+		Exponent:   e.Exponent,
+	}
+}
+
+// MulPlain multiply by plaintext number, c^m with exponents added
+// a negative mantissa uses (c^-1)^|m| to keep the exponentiation short
+func (e *EncryptedNumber) MulPlain(plain *EncodedNumber) (*EncryptedNumber, error) {
+	if e.PublicKey.N.Cmp(plain.PublicKey.N) != 0 {
+		return nil, ErrKeyMismatch
+	}
+	c, m := e.Ciphertext, plain.Encoding
+	if neg := new(big.Int).Sub(e.PublicKey.N, m); neg.Cmp(e.PublicKey.MaxInt()) <= 0 {
+		c = new(big.Int).ModInverse(c, e.PublicKey.NN)
+		m = neg
+	}
+	return &EncryptedNumber{
+		PublicKey:  e.PublicKey,
+		Ciphertext: ScalarMul(c, m, e.PublicKey),
+		Exponent:   e.Exponent + plain.Exponent,
+	}, nil
+}
+
+// Rerandomize fresh ciphertext of the same number, c * r^N (mod N^2)
+func (e *EncryptedNumber) Rerandomize() (*EncryptedNumber, error) {
+	pubkey := e.PublicKey
+	rn, err := randomizer(pubkey)
+	if err != nil {
+		return nil, err
+	}
+	return &EncryptedNumber{
+		PublicKey:  pubkey,
+		Ciphertext: Add(e.Ciphertext, rn, pubkey),
+		Exponent:   e.Exponent,
+	}, nil
+}
+
+// DecreaseExponentTo same number with a smaller exponent, c^(Base^(Exponent-exponent))
+func (e *EncryptedNumber) DecreaseExponentTo(exponent int) (*EncryptedNumber, error) {
+	if exponent > e.Exponent {
+		return nil, ErrExponent
+	}
+	factor := new(big.Int).Exp(big.NewInt(Base), big.NewInt(int64(e.Exponent-exponent)), e.PublicKey.N)
+	return &EncryptedNumber{
+		PublicKey:  e.PublicKey,
+		Ciphertext: ScalarMul(e.Ciphertext, factor, e.PublicKey),
+		Exponent:   exponent,
+	}, nil
+}
+
+// alignEncrypted decrease the larger exponent of a and b to the smaller one
+func alignEncrypted(a, b *EncryptedNumber) (*EncryptedNumber, *EncryptedNumber, error) {
+	var err error
+	if a.Exponent > b.Exponent {
+		a, err = a.DecreaseExponentTo(b.Exponent)
+	} else if b.Exponent > a.Exponent {
+		b, err = b.DecreaseExponentTo(a.Exponent)
+	}
+	return a, b, err
+}
+
+// powBase Base^exp as rational
+func powBase(exp int) *big.Rat {
+	p := new(big.Int).Exp(big.NewInt(Base), big.NewInt(int64(abs(exp))), nil)
+	if exp < 0 {
+		return new(big.Rat).SetFrac(one, p)
+	}
+	return new(big.Rat).SetInt(p)
+}
+
+// floorDiv floor(a / b) for b > 0
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && a < 0 {
+		q--
+	}
+	return q
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
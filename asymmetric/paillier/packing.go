@@ -0,0 +1,207 @@
+package paillier
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Slot packing of small non-negative integers into one plaintext
+//
+//	m = v_0 + v_1 * 2^SlotBits + ... + v_(k-1) * 2^((k-1)*SlotBits)
+//
+// values are at most ValueBits wide, the SlotBits-ValueBits bits on top of each slot are headroom for
+// homomorphic additions and scalar multiplications, a PackedCiphertext tracks an upper bound of its slots
+// so that an operation which could carry into the next slot is rejected with ErrSlotOverflow
+
+var (
+	ErrCapacity        = errors.New("paillier: too many values for the packed slots")
+	ErrSlotOverflow    = errors.New("paillier: packed slot may overflow")
+	ErrPackingMismatch = errors.New("paillier: ciphertexts are packed with different layouts")
+)
+
+// Packing layout of Slots slots of SlotBits bits for values of ValueBits bits
+type Packing struct {
+	PublicKey *PublicKey
+	ValueBits int
+	SlotBits  int
+	Slots     int // (bitlen(N)-1) / SlotBits, so that packed plaintexts are smaller than N
+}
+
+// PackedCiphertext encryption of packed values, the layout and the slot bound are public
+type PackedCiphertext struct {
+	Packing    *Packing
+	Ciphertext *big.Int
+	Count      int      // number of used slots
+	Bound      *big.Int // upper bound of every slot value
+}
+
+// NewPacking layout for values of valueBits bits in slots of slotBits bits
+// e.g. 32-bit counters in 48-bit slots can be summed 2^16 times, a 2048-bit key holds 42 of them
+func NewPacking(pubkey *PublicKey, valueBits, slotBits int) (*Packing, error) {
+	if valueBits < 1 || slotBits < valueBits {
+		return nil, fmt.Errorf("invalid slot width %d for %d-bit values", slotBits, valueBits)
+	}
+	slots := (pubkey.N.BitLen() - 1) / slotBits
+	if slots < 1 {
+		return nil, fmt.Errorf("slot width %d exceeds the plaintext space", slotBits)
+	}
+	return &Packing{
+		PublicKey: pubkey,
+		ValueBits: valueBits,
+		SlotBits:  slotBits,
+		Slots:     slots,
+	}, nil
+}
+
+// Headroom number of additions of fresh ciphertexts before a slot may overflow, 2^(SlotBits-ValueBits)
+func (pk *Packing) Headroom() *big.Int {
+	return new(big.Int).Lsh(one, uint(pk.SlotBits-pk.ValueBits))
+}
+
+// Pack pack values into a plaintext, each value must be in [0, 2^ValueBits)
+func (pk *Packing) Pack(values []*big.Int) (*big.Int, error) {
+	if len(values) > pk.Slots {
+		return nil, ErrCapacity
+	}
+	m := new(big.Int)
+	for i := len(values) - 1; i >= 0; i-- {
+		v := values[i]
+		if v.Sign() < 0 || v.BitLen() > pk.ValueBits {
+			return nil, ErrOverflow
+		}
+		m.Lsh(m, uint(pk.SlotBits))
+		m.Add(m, v)
+	}
+	return m, nil
+}
+
+// Unpack split a plaintext into count slot values
+func (pk *Packing) Unpack(m *big.Int, count int) ([]*big.Int, error) {
+	if count < 0 || count > pk.Slots {
+		return nil, ErrCapacity
+	}
+	if m.Sign() < 0 || m.BitLen() > pk.Slots*pk.SlotBits {
+		return nil, ErrSlotOverflow
+	}
+	mask := new(big.Int).Sub(new(big.Int).Lsh(one, uint(pk.SlotBits)), one)
+	values := make([]*big.Int, count)
+	rest := new(big.Int).Set(m)
+	for i := range values {
+		values[i] = new(big.Int).And(rest, mask)
+		rest.Rsh(rest, uint(pk.SlotBits))
+	}
+	return values, nil
+}
+
+// Encrypt pack and encrypt values
+func (pk *Packing) Encrypt(values []*big.Int) (*PackedCiphertext, error) {
+	m, err := pk.Pack(values)
+	if err != nil {
+		return nil, err
+	}
+	c, err := Encrypt(m, pk.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return &PackedCiphertext{
+		Packing:    pk,
+		Ciphertext: c,
+		Count:      len(values),
+		Bound:      new(big.Int).Sub(new(big.Int).Lsh(one, uint(pk.ValueBits)), one),
+	}, nil
+}
+
+// Decrypt decrypt and unpack the used slots of a packed ciphertext
+func (pk *Packing) Decrypt(c *PackedCiphertext, prvkey *PrivateKey) ([]*big.Int, error) {
+	if !pk.equal(c.Packing) {
+		return nil, ErrPackingMismatch
+	}
+	if pk.PublicKey.N.Cmp(prvkey.N) != 0 {
+		return nil, ErrKeyMismatch
+	}
+	m, err := Decrypt(c.Ciphertext, prvkey)
+	if err != nil {
+		return nil, err
+	}
+	return pk.Unpack(m, c.Count)
+}
+
+// Add slot-wise addition of two packed ciphertexts
+func (c *PackedCiphertext) Add(other *PackedCiphertext) (*PackedCiphertext, error) {
+	if !c.Packing.equal(other.Packing) {
+		return nil, ErrPackingMismatch
+	}
+	bound := new(big.Int).Add(c.Bound, other.Bound)
+	if !c.Packing.fits(bound) {
+		return nil, ErrSlotOverflow
+	}
+	count := c.Count
+	if other.Count > count {
+		count = other.Count
+	}
+	return &PackedCiphertext{
+		Packing:    c.Packing,
+		Ciphertext: Add(c.Ciphertext, other.Ciphertext, c.Packing.PublicKey),
+		Count:      count,
+		Bound:      bound,
+	}, nil
+}
+
+// AddPlain slot-wise addition of plaintext values, each value must be in [0, 2^ValueBits)
+func (c *PackedCiphertext) AddPlain(values []*big.Int) (*PackedCiphertext, error) {
+	m, err := c.Packing.Pack(values)
+	if err != nil {
+		return nil, err
+	}
+	bound := new(big.Int).Set(c.Bound)
+	largest := new(big.Int)
+	for _, v := range values {
+		if v.Cmp(largest) > 0 {
+			largest = v
+		}
+	}
+	bound.Add(bound, largest)
+	if !c.Packing.fits(bound) {
+		return nil, ErrSlotOverflow
+	}
+	count := c.Count
+	if len(values) > count {
+		count = len(values)
+	}
+	pubkey := c.Packing.PublicKey
+	ct := pubkey.expG(m)
+	ct.Mul(ct, c.Ciphertext)
+	return &PackedCiphertext{
+		Packing:    c.Packing,
+		Ciphertext: ct.Mod(ct, pubkey.NN),
+		Count:      count,
+		Bound:      bound,
+	}, nil
+}
+
+// MulPlain multiply every slot by a non-negative scalar
+func (c *PackedCiphertext) MulPlain(scalar *big.Int) (*PackedCiphertext, error) {
+	if scalar.Sign() < 0 {
+		return nil, ErrOverflow
+	}
+	bound := new(big.Int).Mul(c.Bound, scalar)
+	if !c.Packing.fits(bound) {
+		return nil, ErrSlotOverflow
+	}
+	return &PackedCiphertext{
+		Packing:    c.Packing,
+		Ciphertext: ScalarMul(c.Ciphertext, scalar, c.Packing.PublicKey),
+		Count:      c.Count,
+		Bound:      bound,
+	}, nil
+}
+
+// fits bound < 2^SlotBits, i.e. no carry into the next slot
+func (pk *Packing) fits(bound *big.Int) bool {
+	return bound.BitLen() <= pk.SlotBits
+}
+
+func (pk *Packing) equal(other *Packing) bool {
+	return pk.PublicKey.N.Cmp(other.PublicKey.N) == 0 && pk.ValueBits == other.ValueBits && pk.SlotBits == other.SlotBits
+}
@@ -21,6 +21,8 @@ type PrivateKey struct {
 	PinvQ  *big.Int // P^{-1} mod Q
 	Lambda *big.Int // Lambda=(P-1)(Q-1)
 	Mu     *big.Int // Mu=Lambda^-1 (mod N)
+
+	crt *crtParams // cached parameters of CRT encryption
 }
 
 // PublicKey represents a Paillier public key
@@ -41,6 +43,30 @@ func GenerateKey(secbit int) (*PrivateKey, error) {
 	if err != nil {
 		return nil, err
 	}
+	return NewPrivateKey(p, q), nil
+}
+
+// GenerateBlumKey generates a paillier private key with P = Q = 3 (mod 4), so N is a Blum integer
+func GenerateBlumKey(secbit int) (*PrivateKey, error) {
+	keylen := secbit / 2
+	var primes [2]*big.Int
+	for i := range primes {
+		for {
+			p, err := rand.Prime(rand.Reader, keylen)
+			if err != nil {
+				return nil, err
+			}
+			if p.Bit(1) == 1 && (i == 0 || p.Cmp(primes[0]) != 0) {
+				primes[i] = p
+				break
+			}
+		}
+	}
+	return NewPrivateKey(primes[0], primes[1]), nil
+}
+
+// NewPrivateKey computes a paillier private key from primes P and Q
+func NewPrivateKey(p, q *big.Int) *PrivateKey {
 	pp := new(big.Int).Mul(p, p)
 	qq := new(big.Int).Mul(q, q)
 	pinvq := new(big.Int).ModInverse(p, q)
@@ -62,7 +88,8 @@ func GenerateKey(secbit int) (*PrivateKey, error) {
 		PinvQ:  pinvq,
 		Lambda: lambda,
 		Mu:     mu,
-	}, nil
+		crt:    newCRTParams(p, q, n, pp, qq),
+	}
 }
 
 // Encrypt encrypt message using public key
@@ -75,9 +102,25 @@ func Encrypt(m *big.Int, pubkey *PublicKey) (*big.Int, error) {
 	if new(big.Int).Mod(pubkey.N, r).Cmp(zero) == 0 {
 		return nil, errors.New("encrypt error: improper random number")
 	}
-	gm := new(big.Int).Exp(pubkey.G, m, pubkey.NN)
+	return EncryptWithNonce(m, r, pubkey), nil
+}
+
+// EncryptWithNonce encrypt message using public key and given randomness r, r must be invertible mod N
+// c=G^m*r^N (mod N^2), provers use it to recompute ciphertexts from their witnesses
+func EncryptWithNonce(m, r *big.Int, pubkey *PublicKey) *big.Int {
+	gm := pubkey.expG(m)
 	rn := new(big.Int).Exp(r, pubkey.N, pubkey.NN)
-	return new(big.Int).Mod(new(big.Int).Mul(gm, rn), pubkey.NN), nil
+	return gm.Mod(gm.Mul(gm, rn), pubkey.NN)
+}
+
+// expG G^m (mod N^2), for G = N+1 it is 1+m*N (mod N^2) without exponentiation
+func (pubkey *PublicKey) expG(m *big.Int) *big.Int {
+	if pubkey.G.Cmp(new(big.Int).Add(pubkey.N, one)) != 0 {
+		return new(big.Int).Exp(pubkey.G, m, pubkey.NN)
+	}
+	gm := new(big.Int).Mul(m, pubkey.N)
+	gm.Add(gm, one)
+	return gm.Mod(gm, pubkey.NN)
 }
 
 // DecryptOrig decrypt message using private key
@@ -156,28 +199,7 @@ func PrivateFromString(data string) *PrivateKey {
 	prvkey := strings.Split(data, ",")
 	p, _ := new(big.Int).SetString(prvkey[0], 10)
 	q, _ := new(big.Int).SetString(prvkey[1], 10)
-	pp := new(big.Int).Mul(p, p)
-	qq := new(big.Int).Mul(q, q)
-	pinvq := new(big.Int).ModInverse(p, q)
-	n := new(big.Int).Mul(p, q)
-	nn := new(big.Int).Mul(n, n)
-	lambda := new(big.Int).Mul(new(big.Int).Sub(p, one), new(big.Int).Sub(q, one))
-	mu := new(big.Int).ModInverse(lambda, n)
-	g := new(big.Int).Add(n, one)
-	return &PrivateKey{
-		PublicKey: PublicKey{
-			N:  n,
-			G:  g,
-			NN: nn,
-		},
-		P:      p,
-		Q:      q,
-		PP:     pp,
-		QQ:     qq,
-		PinvQ:  pinvq,
-		Lambda: lambda,
-		Mu:     mu,
-	}
+	return NewPrivateKey(p, q)
 }
 
 // PublicToString export public key to string
@@ -1,7 +1,10 @@
 package paillier
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"math"
 	"math/big"
 	"testing"
 )
@@ -97,3 +100,569 @@ func TestMul(t *testing.T) {
 	plainMul, _ := Decrypt(ciphertextMul, prvkey)
 	fmt.Println(plainMul)
 }
+
+func newEncodingKey(t testing.TB) *PrivateKey {
+	key, err := GenerateKey(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestEncodeInt(t *testing.T) {
+	key := newEncodingKey(t)
+	pub := &key.PublicKey
+	for _, x := range []*big.Int{big.NewInt(0), big.NewInt(-1), big.NewInt(123456789), big.NewInt(-987654321), pub.MaxInt(), new(big.Int).Neg(pub.MaxInt())} {
+		enc, err := EncryptInt(pub, x)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ret, err := DecryptInt(enc, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ret.Cmp(x) != 0 {
+			t.Fatalf("expect %s, got %s", x, ret)
+		}
+	}
+	// negative numbers are in the top half of Z_N
+	enc, _ := EncodeInt(pub, big.NewInt(-5))
+	if enc.Encoding.Cmp(new(big.Int).Sub(pub.N, big.NewInt(5))) != 0 {
+		t.Fatal("unexpected encoding of a negative number")
+	}
+	if _, err := EncodeInt(pub, new(big.Int).Add(pub.MaxInt(), one)); err != ErrOverflow {
+		t.Fatalf("expect ErrOverflow, got %v", err)
+	}
+}
+
+func TestEncodeFloat(t *testing.T) {
+	key := newEncodingKey(t)
+	pub := &key.PublicKey
+	for _, f := range []float64{0, 1, -1, 3.141592653589793, -2.5e-10, 1e20, -123.456, math.SmallestNonzeroFloat64} {
+		enc, err := EncryptFloat(pub, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ret, err := DecryptFloat(enc, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ret != f {
+			t.Fatalf("expect %v, got %v", f, ret)
+		}
+	}
+
+	enc, err := EncodeFloatWithPrecision(pub, -3.14159265, 1e-3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enc.Exponent != -3 {
+		t.Fatalf("expect exponent -3, got %d", enc.Exponent)
+	}
+	f, _ := enc.Float64()
+	if math.Abs(f+3.14159265) > 1.0/4096/2 {
+		t.Fatalf("%v is not rounded to precision", f)
+	}
+	if _, err := enc.Int(); err != ErrNotInteger {
+		t.Fatalf("expect ErrNotInteger, got %v", err)
+	}
+	if _, err := EncodeFloat(pub, math.NaN()); err != ErrInvalidNumber {
+		t.Fatalf("expect ErrInvalidNumber, got %v", err)
+	}
+	// the mantissa 10^600 does not fit into N
+	if _, err := EncodeFloatWithPrecision(pub, 1e300, 1e-300); err != ErrOverflow {
+		t.Fatalf("expect ErrOverflow, got %v", err)
+	}
+}
+
+func TestEncryptedNumber(t *testing.T) {
+	key := newEncodingKey(t)
+	pub := &key.PublicKey
+	a, _ := EncryptFloat(pub, 1.5)
+	b, _ := EncryptInt(pub, big.NewInt(-7))
+	c, _ := EncryptFloat(pub, -0.25)
+	plain, _ := EncodeFloat(pub, -2.125)
+	plainInt, _ := EncodeInt(pub, big.NewInt(3))
+
+	check := func(e *EncryptedNumber, err error, expect float64) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ret, err := DecryptFloat(e, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ret != expect {
+			t.Fatalf("expect %v, got %v", expect, ret)
+		}
+	}
+	sum, err := a.Add(b)
+	check(sum, err, -5.5)
+	sum, err = sum.Add(c)
+	check(sum, err, -5.75)
+	diff, err := b.Sub(a)
+	check(diff, err, -8.5)
+	check(c.Neg(), nil, 0.25)
+	addPlain, err := b.AddPlain(plain)
+	check(addPlain, err, -9.125)
+	addPlain, err = c.AddPlain(plainInt)
+	check(addPlain, err, 2.75)
+	mul, err := a.MulPlain(plain)
+	check(mul, err, -3.1875)
+	mul, err = b.MulPlain(plainInt)
+	check(mul, err, -21)
+	mul, err = c.MulPlain(plain)
+	check(mul, err, 0.53125)
+
+	r, err := a.Rerandomize()
+	check(r, err, 1.5)
+	if r.Ciphertext.Cmp(a.Ciphertext) == 0 {
+		t.Fatal("rerandomized ciphertext is unchanged")
+	}
+}
+
+func TestEncryptedOverflow(t *testing.T) {
+	key := newEncodingKey(t)
+	pub := &key.PublicKey
+	max, _ := EncryptInt(pub, pub.MaxInt())
+	sum, err := max.AddPlain(&EncodedNumber{PublicKey: pub, Encoding: big.NewInt(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecryptInt(sum, key); err != ErrOverflow {
+		t.Fatalf("expect ErrOverflow, got %v", err)
+	}
+	two, _ := EncodeInt(pub, big.NewInt(-2))
+	mul, err := max.MulPlain(two)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecryptInt(mul, key); err != ErrOverflow {
+		t.Fatalf("expect ErrOverflow, got %v", err)
+	}
+
+	other := newEncodingKey(t)
+	x, _ := EncryptInt(&other.PublicKey, big.NewInt(1))
+	if _, err := max.Add(x); !errors.Is(err, ErrKeyMismatch) {
+		t.Fatalf("expect ErrKeyMismatch, got %v", err)
+	}
+	if _, err := DecryptInt(x, key); err != ErrKeyMismatch {
+		t.Fatalf("expect ErrKeyMismatch, got %v", err)
+	}
+}
+
+// thresholdSecbit keeps safe prime generation of tests fast
+var thresholdSecbit = 1024
+
+func TestThreshold(t *testing.T) {
+	for _, tc := range []struct{ threshold, parties int }{{2, 3}, {3, 5}, {1, 1}} {
+		pub, shares, err := GenerateThresholdKey(thresholdSecbit, tc.threshold, tc.parties)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// ciphertexts of the existing API: 3*25 + 12
+		c1, _ := Encrypt(plaintext1, &pub.PublicKey)
+		c2, _ := Encrypt(plaintext2, &pub.PublicKey)
+		c := Add(ScalarMul(c1, big.NewInt(3), &pub.PublicKey), c2, &pub.PublicKey)
+
+		partials := make([]*DecryptionShare, tc.parties)
+		for i, share := range shares {
+			if partials[i], err = share.DecryptShare(pub, c); err != nil {
+				t.Fatal(err)
+			}
+			if !pub.VerifyShare(c, partials[i]) {
+				t.Fatalf("party %d: valid share rejected", i+1)
+			}
+		}
+		// any threshold shares decrypt, e.g. the first and the last ones
+		for _, subset := range [][]*DecryptionShare{partials[:tc.threshold], partials[tc.parties-tc.threshold:]} {
+			m, err := pub.CombineShares(c, subset)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.Int64() != 87 {
+				t.Fatalf("expect 87, got %s", m)
+			}
+		}
+		if tc.threshold > 1 {
+			if _, err := pub.CombineShares(c, partials[:tc.threshold-1]); err != ErrNotEnough {
+				t.Fatalf("expect ErrNotEnough, got %v", err)
+			}
+		}
+	}
+}
+
+func TestThresholdEncryptedNumber(t *testing.T) {
+	pub, shares, err := GenerateThresholdKey(thresholdSecbit, 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := EncryptFloat(&pub.PublicKey, -1.25)
+	b, _ := EncryptInt(&pub.PublicKey, big.NewInt(-40))
+	sum, err := a.Add(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	partials := make([]*DecryptionShare, 2)
+	for i := range partials {
+		if partials[i], err = shares[i+1].DecryptShare(pub, sum.Ciphertext); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m, err := pub.CombineShares(sum.Ciphertext, partials)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := (&EncodedNumber{PublicKey: &pub.PublicKey, Encoding: m, Exponent: sum.Exponent}).Float64()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f != -41.25 {
+		t.Fatalf("expect -41.25, got %v", f)
+	}
+}
+
+func TestThresholdInvalidShare(t *testing.T) {
+	pub, shares, err := GenerateThresholdKey(thresholdSecbit, 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := Encrypt(plaintext1, &pub.PublicKey)
+	partials := make([]*DecryptionShare, 3)
+	for i, share := range shares {
+		if partials[i], err = share.DecryptShare(pub, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a wrong partial decryption, a share of another ciphertext and a share claimed by another party
+	bad := *partials[0]
+	bad.C = new(big.Int).Mod(new(big.Int).Mul(bad.C, pub.G), pub.NN)
+	other, _ := Encrypt(plaintext2, &pub.PublicKey)
+	otherShare, _ := shares[1].DecryptShare(pub, other)
+	stolen := *partials[2]
+	stolen.Index = 1
+	for _, share := range []*DecryptionShare{&bad, otherShare, &stolen} {
+		if pub.VerifyShare(c, share) {
+			t.Fatal("invalid share verified")
+		}
+	}
+
+	// invalid shares are skipped
+	m, err := pub.CombineShares(c, []*DecryptionShare{&bad, otherShare, partials[1], partials[1], &stolen, partials[2]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Cmp(plaintext1) != 0 {
+		t.Fatalf("expect %s, got %s", plaintext1, m)
+	}
+	if _, err := pub.CombineShares(c, []*DecryptionShare{&bad, partials[1], &stolen}); err != ErrNotEnough {
+		t.Fatalf("expect ErrNotEnough, got %v", err)
+	}
+	for _, index := range []int{0, pub.Parties + 1} {
+		if _, err := (&KeyShare{Index: index, S: shares[0].S}).DecryptShare(pub, c); err != ErrInvalidShare {
+			t.Fatalf("index %d: expect ErrInvalidShare, got %v", index, err)
+		}
+	}
+}
+
+func BenchmarkDecryptShare(b *testing.B) {
+	pub, shares, _ := GenerateThresholdKey(thresholdSecbit, 2, 3)
+	c, _ := Encrypt(plaintext1, &pub.PublicKey)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		shares[0].DecryptShare(pub, c)
+	}
+}
+
+func BenchmarkCombineShares(b *testing.B) {
+	pub, shares, _ := GenerateThresholdKey(thresholdSecbit, 2, 3)
+	c, _ := Encrypt(plaintext1, &pub.PublicKey)
+	partials := make([]*DecryptionShare, 2)
+	for i := range partials {
+		partials[i], _ = shares[i].DecryptShare(pub, c)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pub.CombineShares(c, partials)
+	}
+}
+
+func batchMessages(n int, key *PrivateKey) []*big.Int {
+	ms := make([]*big.Int, n)
+	for i := range ms {
+		ms[i] = big.NewInt(int64(i * 1000))
+	}
+	ms[0] = new(big.Int).Sub(key.N, one)
+	return ms
+}
+
+func TestEncryptCRT(t *testing.T) {
+	key := newEncodingKey(t)
+	for _, m := range batchMessages(4, key) {
+		c, err := EncryptCRT(m, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ret, _ := Decrypt(c, key); ret.Cmp(m) != 0 {
+			t.Fatalf("expect %s, got %s", m, ret)
+		}
+	}
+	// the G = N+1 shortcut matches G^m
+	m := big.NewInt(123456789)
+	if key.expG(m).Cmp(new(big.Int).Exp(key.G, m, key.NN)) != 0 {
+		t.Fatal("expG mismatch")
+	}
+}
+
+func TestPool(t *testing.T) {
+	key := newEncodingKey(t)
+	for _, pool := range []*Pool{
+		NewPool(context.Background(), &key.PublicKey, 8, 2),
+		NewPoolCRT(context.Background(), key, 8, 2),
+	} {
+		cs, err := pool.EncryptBatch(context.Background(), batchMessages(32, key), 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pool.Close()
+		// a closed pool computes randomizers inline
+		c, err := pool.Encrypt(plaintext1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cs = append(cs, c)
+		ms, err := DecryptBatch(context.Background(), cs, key, 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, m := range append(batchMessages(32, key), plaintext1) {
+			if ms[i].Cmp(m) != 0 {
+				t.Fatalf("expect %s, got %s", m, ms[i])
+			}
+		}
+	}
+}
+
+func TestBatch(t *testing.T) {
+	key := newEncodingKey(t)
+	ms := batchMessages(50, key)
+	cs, err := EncryptBatch(context.Background(), ms, &key.PublicKey, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs2, err := EncryptBatchCRT(context.Background(), ms, key, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range [][]*big.Int{cs, cs2} {
+		ret, err := DecryptBatch(context.Background(), c, key, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range ms {
+			if ret[i].Cmp(ms[i]) != 0 {
+				t.Fatalf("expect %s, got %s", ms[i], ret[i])
+			}
+		}
+	}
+	if cs, err := EncryptBatch(context.Background(), nil, &key.PublicKey, 0); err != nil || len(cs) != 0 {
+		t.Fatal("empty batch failed")
+	}
+
+	// errors and cancellation stop the batch
+	cs[7] = key.NN
+	if _, err := DecryptBatch(context.Background(), cs, key, 4); err == nil {
+		t.Fatal("invalid ciphertext accepted")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := EncryptBatch(ctx, ms, &key.PublicKey, 4); err != context.Canceled {
+		t.Fatalf("expect context.Canceled, got %v", err)
+	}
+}
+
+func BenchmarkEncrypt(b *testing.B) {
+	key := newEncodingKey(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Encrypt(plaintext1, &key.PublicKey)
+	}
+}
+
+func BenchmarkEncryptCRT(b *testing.B) {
+	key := newEncodingKey(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		EncryptCRT(plaintext1, key)
+	}
+}
+
+func BenchmarkPoolEncrypt(b *testing.B) {
+	key := newEncodingKey(b)
+	// online cost with a filled pool, the worker hands out a fixed randomizer to keep up with b.N
+	rn, _ := randomizer(&key.PublicKey)
+	pool := newPool(context.Background(), &key.PublicKey, func() (*big.Int, error) { return rn, nil }, 1024, 1)
+	defer pool.Close()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pool.Encrypt(plaintext1)
+	}
+}
+
+func BenchmarkEncryptBatch(b *testing.B) {
+	key := newEncodingKey(b)
+	ms := batchMessages(b.N, key)
+	b.ResetTimer()
+	EncryptBatch(context.Background(), ms, &key.PublicKey, 0)
+}
+
+func BenchmarkEncryptBatchCRT(b *testing.B) {
+	key := newEncodingKey(b)
+	ms := batchMessages(b.N, key)
+	b.ResetTimer()
+	EncryptBatchCRT(context.Background(), ms, key, 0)
+}
+
+func BenchmarkDecryptBatch(b *testing.B) {
+	key := newEncodingKey(b)
+	cs, _ := EncryptBatchCRT(context.Background(), batchMessages(b.N, key), key, 0)
+	b.ResetTimer()
+	DecryptBatch(context.Background(), cs, key, 0)
+}
+
+func TestPacking(t *testing.T) {
+	key := newEncodingKey(t)
+	pk, err := NewPacking(&key.PublicKey, 32, 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pk.Slots != (key.N.BitLen()-1)/40 {
+		t.Fatalf("unexpected slot count %d", pk.Slots)
+	}
+	a := make([]*big.Int, pk.Slots)
+	b := make([]*big.Int, pk.Slots-3)
+	for i := range a {
+		a[i] = big.NewInt(int64(i) * 100003)
+	}
+	a[0] = big.NewInt(math.MaxUint32)
+	for i := range b {
+		b[i] = big.NewInt(int64(i) + 7)
+	}
+	ca, err := pk.Encrypt(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb, err := pk.Encrypt(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// (a + b) * 3 + b
+	sum, err := ca.Add(cb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mul, err := sum.MulPlain(big.NewInt(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := mul.AddPlain(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := pk.Decrypt(res, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != len(a) {
+		t.Fatalf("expect %d values, got %d", len(a), len(values))
+	}
+	for i := range a {
+		expect := new(big.Int).Set(a[i])
+		if i < len(b) {
+			expect.Add(expect, b[i])
+			expect.Mul(expect, big.NewInt(3))
+			expect.Add(expect, b[i])
+		} else {
+			expect.Mul(expect, big.NewInt(3))
+		}
+		if values[i].Cmp(expect) != 0 {
+			t.Fatalf("slot %d: expect %s, got %s", i, expect, values[i])
+		}
+	}
+}
+
+func TestPackingCapacity(t *testing.T) {
+	key := newEncodingKey(t)
+	if _, err := NewPacking(&key.PublicKey, 32, 16); err == nil {
+		t.Fatal("slot narrower than values accepted")
+	}
+	if _, err := NewPacking(&key.PublicKey, 32, key.N.BitLen()); err == nil {
+		t.Fatal("slot wider than the plaintext space accepted")
+	}
+	pk, _ := NewPacking(&key.PublicKey, 8, 10)
+	if _, err := pk.Encrypt(make([]*big.Int, pk.Slots+1)); err != ErrCapacity {
+		t.Fatalf("expect ErrCapacity, got %v", err)
+	}
+	if _, err := pk.Encrypt([]*big.Int{big.NewInt(256)}); err != ErrOverflow {
+		t.Fatalf("expect ErrOverflow, got %v", err)
+	}
+	if _, err := pk.Encrypt([]*big.Int{big.NewInt(-1)}); err != ErrOverflow {
+		t.Fatalf("expect ErrOverflow, got %v", err)
+	}
+
+	// 2 bits of headroom: 4 fresh ciphertexts add up, a fifth may carry into the next slot
+	c, _ := pk.Encrypt([]*big.Int{big.NewInt(255), big.NewInt(1)})
+	sum := c
+	for i := int64(1); i < pk.Headroom().Int64(); i++ {
+		var err error
+		if sum, err = sum.Add(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := sum.Add(c); err != ErrSlotOverflow {
+		t.Fatalf("expect ErrSlotOverflow, got %v", err)
+	}
+	if _, err := c.MulPlain(big.NewInt(5)); err != ErrSlotOverflow {
+		t.Fatalf("expect ErrSlotOverflow, got %v", err)
+	}
+	values, _ := pk.Decrypt(sum, key)
+	if values[0].Int64() != 1020 || values[1].Int64() != 4 {
+		t.Fatalf("unexpected sums %v", values)
+	}
+
+	other, _ := NewPacking(&key.PublicKey, 8, 12)
+	d, _ := other.Encrypt([]*big.Int{big.NewInt(1)})
+	if _, err := c.Add(d); err != ErrPackingMismatch {
+		t.Fatalf("expect ErrPackingMismatch, got %v", err)
+	}
+}
+
+func BenchmarkPackedEncrypt(b *testing.B) {
+	key := newEncodingKey(b)
+	pk, _ := NewPacking(&key.PublicKey, 32, 48)
+	values := make([]*big.Int, pk.Slots)
+	for i := range values {
+		values[i] = big.NewInt(int64(i))
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pk.Encrypt(values)
+	}
+}
+
+func BenchmarkPackedAdd(b *testing.B) {
+	key := newEncodingKey(b)
+	pk, _ := NewPacking(&key.PublicKey, 32, 48)
+	values := make([]*big.Int, pk.Slots)
+	for i := range values {
+		values[i] = big.NewInt(int64(i))
+	}
+	c, _ := pk.Encrypt(values)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Add(c)
+	}
+}
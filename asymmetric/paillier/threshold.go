@@ -0,0 +1,245 @@
+package paillier
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/hongyanwang/crypto-lab/common/modular"
+)
+
+var (
+	ErrInvalidShare = errors.New("paillier: invalid decryption share")
+	ErrNotEnough    = errors.New("paillier: not enough valid decryption shares")
+)
+
+// ThresholdPublicKey public key of threshold Paillier, any Threshold of Parties can decrypt
+// ciphertexts are ordinary Paillier ciphertexts under PublicKey, V and VKs verify decryption shares
+type ThresholdPublicKey struct {
+	PublicKey
+	Threshold int
+	Parties   int
+	V         *big.Int   // random square in Z_(N^2)*
+	VKs       []*big.Int // verification keys, VKs[i-1] = V^(Delta*s_i) (mod N^2) of party i
+}
+
+// KeyShare share s_i = f(i) of the decryption exponent d held by party Index
+// f is a random polynomial over Z_(N*m) with f(0) = d, d = 0 (mod m) and d = 1 (mod N)
+type KeyShare struct {
+	Index int
+	S     *big.Int
+}
+
+// DecryptionShare partial decryption c_i = c^(2*Delta*s_i) (mod N^2) with proof of correctness (E, Z)
+type DecryptionShare struct {
+	Index int
+	C     *big.Int
+	E, Z  *big.Int
+}
+
+// GenerateThresholdKey trusted dealer generates a secbit-bit key with safe primes P = 2p'+1, Q = 2q'+1 and
+// splits the decryption exponent among partyNum parties, the dealer must erase P, Q and d afterwards
+// Damgard, Jurik, A Generalisation, a Simplification and some Applications of Paillier's Probabilistic Public-Key System, PKC 2001
+func GenerateThresholdKey(secbit, threshold, partyNum int) (*ThresholdPublicKey, []*KeyShare, error) {
+	if threshold < 1 || threshold > partyNum {
+		return nil, nil, fmt.Errorf("invalid threshold %d of %d parties", threshold, partyNum)
+	}
+
+	// 1. N = PQ with safe primes, m = p'q'
+	var p, q, n *big.Int
+	for {
+		var err error
+		if p, err = modular.GenerateSafePrime(secbit / 2); err != nil {
+			return nil, nil, err
+		}
+		if q, err = modular.GenerateSafePrime(secbit - secbit/2); err != nil {
+			return nil, nil, err
+		}
+		n = new(big.Int).Mul(p, q)
+		if p.Cmp(q) != 0 && n.BitLen() == secbit {
+			break
+		}
+	}
+	m := new(big.Int).Mul(new(big.Int).Rsh(p, 1), new(big.Int).Rsh(q, 1))
+	nm := new(big.Int).Mul(n, m)
+	nn := new(big.Int).Mul(n, n)
+
+	// 2. d = m * (m^-1 mod N), so d = 0 (mod m) and d = 1 (mod N)
+	d := new(big.Int).ModInverse(m, n)
+	if d == nil {
+		return nil, nil, fmt.Errorf("m is not invertible mod N")
+	}
+	d.Mul(d, m)
+
+	// 3. random polynomial f of degree threshold-1 over Z_(N*m) with f(0) = d
+	coefficients := make([]*big.Int, threshold)
+	coefficients[0] = d
+	for i := 1; i < threshold; i++ {
+		a, err := rand.Int(rand.Reader, nm)
+		if err != nil {
+			return nil, nil, err
+		}
+		coefficients[i] = a
+	}
+
+	// 4. verification base V = r^2 generates the squares of Z_(N^2)* with overwhelming probability
+	r, err := modular.RandomUnit(nn)
+	if err != nil {
+		return nil, nil, err
+	}
+	pub := &ThresholdPublicKey{
+		PublicKey: PublicKey{N: n, G: new(big.Int).Add(n, one), NN: nn},
+		Threshold: threshold,
+		Parties:   partyNum,
+		V:         r.Exp(r, big.NewInt(2), nn),
+		VKs:       make([]*big.Int, partyNum),
+	}
+
+	// 5. s_i = f(i) mod N*m, v_i = V^(Delta*s_i)
+	delta := modular.Factorial(partyNum)
+	shares := make([]*KeyShare, partyNum)
+	for i := 1; i <= partyNum; i++ {
+		x := big.NewInt(int64(i))
+		s := new(big.Int)
+		for j := threshold - 1; j >= 0; j-- {
+			s.Mul(s, x)
+			s.Add(s, coefficients[j])
+			s.Mod(s, nm)
+		}
+		shares[i-1] = &KeyShare{Index: i, S: s}
+		pub.VKs[i-1] = new(big.Int).Exp(pub.V, new(big.Int).Mul(delta, s), nn)
+	}
+	return pub, shares, nil
+}
+
+// DecryptShare party partially decrypts ciphertext c, e.g. an output of Add or ScalarMul
+// c_i = c^(2*Delta*s_i), proof that log_V(v_i) = log_(c^4)(c_i^2) = Delta*s_i
+func (share *KeyShare) DecryptShare(pub *ThresholdPublicKey, c *big.Int) (*DecryptionShare, error) {
+	if share.Index < 1 || share.Index > pub.Parties {
+		return nil, ErrInvalidShare
+	}
+	nn := pub.NN
+	if !modular.IsUnit(c, nn) {
+		return nil, errors.New("ciphertext must be invertible mod n square")
+	}
+	ds := new(big.Int).Mul(modular.Factorial(pub.Parties), share.S)
+	ci := new(big.Int).Exp(c, new(big.Int).Lsh(ds, 1), nn)
+
+	// r has |N^2| + 2*L1 bits to hide Delta*s_i*e
+	r, err := rand.Int(rand.Reader, new(big.Int).Lsh(one, uint(nn.BitLen()+2*proofHashBits)))
+	if err != nil {
+		return nil, err
+	}
+	c4 := new(big.Int).Exp(c, big.NewInt(4), nn)
+	ci2 := new(big.Int).Exp(ci, big.NewInt(2), nn)
+	a := new(big.Int).Exp(c4, r, nn)
+	b := new(big.Int).Exp(pub.V, r, nn)
+	e := proofChallenge(c4, ci2, pub.V, pub.VKs[share.Index-1], a, b)
+
+	z := ds.Mul(ds, e)
+	z.Add(z, r)
+	return &DecryptionShare{Index: share.Index, C: ci, E: e, Z: z}, nil
+}
+
+// VerifyShare verify proof of decryption share of ciphertext c
+// e = H(c^4, c_i^2, V, v_i, (c^4)^z * c_i^(-2e), V^z * v_i^-e)
+func (pub *ThresholdPublicKey) VerifyShare(c *big.Int, share *DecryptionShare) bool {
+	if share == nil || share.Index < 1 || share.Index > pub.Parties || share.E == nil || share.Z == nil {
+		return false
+	}
+	nn := pub.NN
+	if !modular.IsUnit(c, nn) || !modular.IsUnit(share.C, nn) || share.Z.Sign() < 0 {
+		return false
+	}
+	vi := pub.VKs[share.Index-1]
+	c4 := new(big.Int).Exp(c, big.NewInt(4), nn)
+	ci2 := new(big.Int).Exp(share.C, big.NewInt(2), nn)
+
+	a := new(big.Int).Exp(c4, share.Z, nn)
+	a.Mul(a, new(big.Int).Exp(new(big.Int).ModInverse(ci2, nn), share.E, nn))
+	a.Mod(a, nn)
+	b := new(big.Int).Exp(pub.V, share.Z, nn)
+	b.Mul(b, new(big.Int).Exp(new(big.Int).ModInverse(vi, nn), share.E, nn))
+	b.Mod(b, nn)
+
+	e := proofChallenge(c4, ci2, pub.V, vi, a, b)
+	return e.Cmp(share.E) == 0
+}
+
+// CombineShares decrypt c from Threshold shares with valid proofs, invalid shares are skipped
+// c' = mul( c_i^(2*mu_i) ) = c^(4*Delta^2*d) = (1+N)^(4*Delta^2*m) with mu_i = Delta*lambda_i,
+// so m = L(c') * (4*Delta^2)^-1 (mod N)
+func (pub *ThresholdPublicKey) CombineShares(c *big.Int, shares []*DecryptionShare) (*big.Int, error) {
+	var valid []*DecryptionShare
+	seen := make(map[int]bool, len(shares))
+	for _, share := range shares {
+		if len(valid) == pub.Threshold {
+			break
+		}
+		if share == nil || seen[share.Index] || !pub.VerifyShare(c, share) {
+			continue
+		}
+		seen[share.Index] = true
+		valid = append(valid, share)
+	}
+	if len(valid) < pub.Threshold {
+		return nil, ErrNotEnough
+	}
+	indices := make([]int, len(valid))
+	for i, share := range valid {
+		indices[i] = share.Index
+	}
+
+	nn := pub.NN
+	delta := modular.Factorial(pub.Parties)
+	cc := big.NewInt(1)
+	for i, share := range valid {
+		mu := lagrangeDelta(delta, indices, i)
+		mu.Lsh(mu, 1)
+		cc.Mul(cc, modular.ExpInt(share.C, mu, nn))
+		cc.Mod(cc, nn)
+	}
+
+	inv := new(big.Int).Mul(delta, delta)
+	inv.Lsh(inv, 2)
+	if inv.ModInverse(inv, pub.N) == nil {
+		return nil, fmt.Errorf("4*Delta^2 is not invertible mod N")
+	}
+	m := L(cc, pub.N)
+	m.Mul(m, inv)
+	return m.Mod(m, pub.N), nil
+}
+
+// proof hash output size L1 in bits
+const proofHashBits = sha256.Size * 8
+
+// proofChallenge Fiat-Shamir challenge, SHA-256 of length-prefixed values
+func proofChallenge(values ...*big.Int) *big.Int {
+	h := sha256.New()
+	for _, v := range values {
+		b := v.Bytes()
+		var l [4]byte
+		binary.BigEndian.PutUint32(l[:], uint32(len(b)))
+		h.Write(l[:])
+		h.Write(b)
+	}
+	return new(big.Int).SetBytes(h.Sum(nil))
+}
+
+// lagrangeDelta integer coefficient Delta * lambda_(0,j) = Delta * mul( j' / (j' - j) ) for j = indices[i]
+func lagrangeDelta(delta *big.Int, indices []int, i int) *big.Int {
+	num := new(big.Int).Set(delta)
+	den := big.NewInt(1)
+	for k, jj := range indices {
+		if k == i {
+			continue
+		}
+		num.Mul(num, big.NewInt(int64(jj)))
+		den.Mul(den, big.NewInt(int64(jj-indices[i])))
+	}
+	// the division is exact
+	return num.Quo(num, den)
+}
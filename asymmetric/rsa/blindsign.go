@@ -0,0 +1,151 @@
+package rsa
+
+import (
+	"crypto/rand"
+	"crypto/sha512"
+	"errors"
+	"hash"
+	"io"
+	"math/big"
+)
+
+// prepare prefix length of randomized variants
+const blindPrefixLen = 32
+
+var (
+	ErrInvalidMessage   = errors.New("rsa: message is not invertible mod N")
+	ErrInvalidBlind     = errors.New("rsa: invalid blinded message")
+	ErrSigningFailure   = errors.New("rsa: signing failure")
+	ErrInvalidBlindSign = errors.New("rsa: invalid blind signature")
+)
+
+// BlindSignVariant RSABSSA variant of RFC 9474 section 5
+// the signer must accept only one variant for each key, randomized variants are recommended
+type BlindSignVariant struct {
+	Name       string
+	Hash       func() hash.Hash
+	SaltLen    int  // PSS salt length, hash output length or 0 for PSSZERO
+	Randomized bool // prepend a random prefix to the message in Prepare
+}
+
+var (
+	RSABSSA_SHA384_PSS_RANDOMIZED = &BlindSignVariant{
+		Name:       "RSABSSA-SHA384-PSS-Randomized",
+		Hash:       sha512.New384,
+		SaltLen:    sha512.Size384,
+		Randomized: true,
+	}
+	RSABSSA_SHA384_PSSZERO_RANDOMIZED = &BlindSignVariant{
+		Name:       "RSABSSA-SHA384-PSSZERO-Randomized",
+		Hash:       sha512.New384,
+		SaltLen:    0,
+		Randomized: true,
+	}
+	RSABSSA_SHA384_PSS_DETERMINISTIC = &BlindSignVariant{
+		Name:    "RSABSSA-SHA384-PSS-Deterministic",
+		Hash:    sha512.New384,
+		SaltLen: sha512.Size384,
+	}
+	RSABSSA_SHA384_PSSZERO_DETERMINISTIC = &BlindSignVariant{
+		Name:    "RSABSSA-SHA384-PSSZERO-Deterministic",
+		Hash:    sha512.New384,
+		SaltLen: 0,
+	}
+)
+
+// Prepare client prepares the message to sign, randomized variants prepend 32 random bytes
+// the prepared message is what gets blinded, finalized and verified
+func (v *BlindSignVariant) Prepare(msg []byte) ([]byte, error) {
+	if !v.Randomized {
+		return append([]byte{}, msg...), nil
+	}
+	prepared := make([]byte, blindPrefixLen+len(msg))
+	if _, err := io.ReadFull(rand.Reader, prepared[:blindPrefixLen]); err != nil {
+		return nil, err
+	}
+	copy(prepared[blindPrefixLen:], msg)
+	return prepared, nil
+}
+
+// Blind client blinds the prepared message, the blinded message is sent to the signer and inv is kept secret
+// blinded_msg = EMSA-PSS-ENCODE(msg) * r^E (mod N), inv = r^-1 (mod N)
+func (v *BlindSignVariant) Blind(pubkey *PublicKey, msg []byte) ([]byte, *big.Int, error) {
+	salt := make([]byte, v.SaltLen)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, nil, err
+	}
+	var r, inv *big.Int
+	for {
+		var err error
+		r, err = rand.Int(rand.Reader, pubkey.N)
+		if err != nil {
+			return nil, nil, err
+		}
+		if inv = new(big.Int).ModInverse(r, pubkey.N); inv != nil {
+			break
+		}
+	}
+	blinded, err := v.blind(pubkey, msg, salt, r)
+	if err != nil {
+		return nil, nil, err
+	}
+	return blinded, inv, nil
+}
+
+// blind Blind of RFC 9474 section 4.2 with given salt and blinding factor r
+func (v *BlindSignVariant) blind(pubkey *PublicKey, msg, salt []byte, r *big.Int) ([]byte, error) {
+	m, err := EncodePSS(v.Hash, pubkey, msg, salt)
+	if err != nil {
+		return nil, err
+	}
+	if new(big.Int).GCD(nil, nil, m, pubkey.N).Cmp(one) != 0 {
+		return nil, ErrInvalidMessage
+	}
+	z := new(big.Int).Exp(r, pubkey.E, pubkey.N)
+	z.Mul(z, m)
+	z.Mod(z, pubkey.N)
+	return z.FillBytes(make([]byte, byteLen(pubkey.N))), nil
+}
+
+// BlindSign signer signs the blinded message without learning the message, RFC 9474 section 4.3
+// the result is checked against the public key to detect faults in the private key operation
+func BlindSign(prvkey *PrivateKey, blindedMsg []byte) ([]byte, error) {
+	k := byteLen(prvkey.N)
+	if len(blindedMsg) != k {
+		return nil, ErrInvalidBlind
+	}
+	m := new(big.Int).SetBytes(blindedMsg)
+	if m.Cmp(prvkey.N) >= 0 {
+		return nil, ErrInvalidBlind
+	}
+	s, err := RSASign(m, prvkey)
+	if err != nil {
+		return nil, err
+	}
+	if new(big.Int).Exp(s, prvkey.E, prvkey.N).Cmp(m) != 0 {
+		return nil, ErrSigningFailure
+	}
+	return s.FillBytes(make([]byte, k)), nil
+}
+
+// Finalize client unblinds the blind signature into a signature of the prepared message, RFC 9474 section 4.4
+// sig = blind_sig * inv (mod N), the signature is verified before it is returned
+func (v *BlindSignVariant) Finalize(pubkey *PublicKey, msg, blindSig []byte, inv *big.Int) ([]byte, error) {
+	if len(blindSig) != byteLen(pubkey.N) {
+		return nil, ErrInvalidBlindSign
+	}
+	s := new(big.Int).SetBytes(blindSig)
+	s.Mul(s, inv)
+	s.Mod(s, pubkey.N)
+	sig := s.FillBytes(make([]byte, byteLen(pubkey.N)))
+	ok, err := v.Verify(pubkey, msg, sig)
+	if err != nil || !ok {
+		return nil, ErrInvalidBlindSign
+	}
+	return sig, nil
+}
+
+// Verify verify signature of the prepared message, a standard RSASSA-PSS signature with the variant's salt length
+func (v *BlindSignVariant) Verify(pubkey *PublicKey, msg, sig []byte) (bool, error) {
+	return verifyPSS(v.Hash, pubkey, msg, sig, v.SaltLen)
+}
@@ -0,0 +1,280 @@
+package rsa
+
+import (
+	stdrsa "crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+const (
+	pemPKCS1PrivateKey = "RSA PRIVATE KEY"
+	pemPKCS8PrivateKey = "PRIVATE KEY"
+	pemPKCS1PublicKey  = "RSA PUBLIC KEY"
+	pemPKIXPublicKey   = "PUBLIC KEY"
+
+	// primality test rounds of Validate
+	validateRounds = 20
+)
+
+var (
+	ErrInvalidKey   = errors.New("rsa: invalid key")
+	ErrNotRSAKey    = errors.New("rsa: not an RSA key")
+	ErrLargeE       = errors.New("rsa: public exponent does not fit into crypto/rsa")
+	ErrPEMNotFound  = errors.New("rsa: no PEM block found")
+	ErrPEMBlockType = errors.New("rsa: unsupported PEM block type")
+)
+
+// Validate check consistency of private key
+// primes are distinct probable primes, N is their product, 1 < E < N and D*E = 1 mod (r_i-1) for every prime
+func (prvkey *PrivateKey) Validate() error {
+	if err := prvkey.PublicKey.Validate(); err != nil {
+		return err
+	}
+	if prvkey.P == nil || prvkey.Q == nil || prvkey.D == nil {
+		return fmt.Errorf("%w: missing private values", ErrInvalidKey)
+	}
+	primes := prvkey.primes()
+	n := new(big.Int).Set(one)
+	phi := new(big.Int).Set(one)
+	for i, prime := range primes {
+		if prime.Cmp(one) <= 0 || !prime.ProbablyPrime(validateRounds) {
+			return fmt.Errorf("%w: prime %d is not prime", ErrInvalidKey, i)
+		}
+		for _, r := range primes[:i] {
+			if r.Cmp(prime) == 0 {
+				return fmt.Errorf("%w: primes are not distinct", ErrInvalidKey)
+			}
+		}
+		n.Mul(n, prime)
+		pminus1 := new(big.Int).Sub(prime, one)
+		phi.Mul(phi, pminus1)
+
+		de := new(big.Int).Mul(prvkey.D, prvkey.E)
+		if de.Mod(de, pminus1).Cmp(one) != 0 {
+			return fmt.Errorf("%w: D is not the inverse of E", ErrInvalidKey)
+		}
+	}
+	if n.Cmp(prvkey.N) != 0 {
+		return fmt.Errorf("%w: N is not the product of primes", ErrInvalidKey)
+	}
+	if prvkey.Phi != nil && prvkey.Phi.Cmp(phi) != 0 {
+		return fmt.Errorf("%w: Phi mismatch", ErrInvalidKey)
+	}
+	return nil
+}
+
+// Validate check public key, N is odd and 1 < E < N
+func (pubkey *PublicKey) Validate() error {
+	if pubkey.N == nil || pubkey.E == nil {
+		return fmt.Errorf("%w: missing public values", ErrInvalidKey)
+	}
+	if pubkey.N.Bit(0) == 0 || pubkey.N.Cmp(one) <= 0 {
+		return fmt.Errorf("%w: N must be odd and greater than 1", ErrInvalidKey)
+	}
+	if pubkey.E.Cmp(one) <= 0 || pubkey.E.Cmp(pubkey.N) >= 0 {
+		return fmt.Errorf("%w: E out of range", ErrInvalidKey)
+	}
+	return nil
+}
+
+// primes all primes of private key, P, Q and AdditionalPrimes
+func (prvkey *PrivateKey) primes() []*big.Int {
+	return append([]*big.Int{prvkey.P, prvkey.Q}, prvkey.AdditionalPrimes...)
+}
+
+// FromStdPrivateKey convert crypto/rsa private key, CRT values are computed by Precompute
+func FromStdPrivateKey(key *stdrsa.PrivateKey) (*PrivateKey, error) {
+	if len(key.Primes) < 2 {
+		return nil, fmt.Errorf("%w: at least 2 primes are required", ErrInvalidKey)
+	}
+	prvkey := &PrivateKey{
+		PublicKey: *FromStdPublicKey(&key.PublicKey),
+		P:         new(big.Int).Set(key.Primes[0]),
+		Q:         new(big.Int).Set(key.Primes[1]),
+		D:         new(big.Int).Set(key.D),
+	}
+	for _, prime := range key.Primes[2:] {
+		prvkey.AdditionalPrimes = append(prvkey.AdditionalPrimes, new(big.Int).Set(prime))
+	}
+	prvkey.Phi = new(big.Int).Set(one)
+	for _, prime := range prvkey.primes() {
+		prvkey.Phi.Mul(prvkey.Phi, new(big.Int).Sub(prime, one))
+	}
+	if err := prvkey.Validate(); err != nil {
+		return nil, err
+	}
+	prvkey.Precompute()
+	return prvkey, nil
+}
+
+// FromStdPublicKey convert crypto/rsa public key
+func FromStdPublicKey(key *stdrsa.PublicKey) *PublicKey {
+	return &PublicKey{
+		N: new(big.Int).Set(key.N),
+		E: big.NewInt(int64(key.E)),
+	}
+}
+
+// ToStd convert to crypto/rsa private key, E must fit into int
+func (prvkey *PrivateKey) ToStd() (*stdrsa.PrivateKey, error) {
+	pub, err := prvkey.PublicKey.ToStd()
+	if err != nil {
+		return nil, err
+	}
+	key := &stdrsa.PrivateKey{
+		PublicKey: *pub,
+		D:         new(big.Int).Set(prvkey.D),
+	}
+	for _, prime := range prvkey.primes() {
+		key.Primes = append(key.Primes, new(big.Int).Set(prime))
+	}
+	if err := key.Validate(); err != nil {
+		return nil, err
+	}
+	key.Precompute()
+	return key, nil
+}
+
+// ToStd convert to crypto/rsa public key, E must fit into int
+func (pubkey *PublicKey) ToStd() (*stdrsa.PublicKey, error) {
+	if pubkey.E.BitLen() > 31 {
+		return nil, ErrLargeE
+	}
+	return &stdrsa.PublicKey{
+		N: new(big.Int).Set(pubkey.N),
+		E: int(pubkey.E.Int64()),
+	}, nil
+}
+
+// MarshalPKCS1PrivateKey encode private key in PKCS#1 ASN.1 DER form
+func MarshalPKCS1PrivateKey(prvkey *PrivateKey) ([]byte, error) {
+	key, err := prvkey.ToStd()
+	if err != nil {
+		return nil, err
+	}
+	return x509.MarshalPKCS1PrivateKey(key), nil
+}
+
+// ParsePKCS1PrivateKey decode private key in PKCS#1 ASN.1 DER form
+func ParsePKCS1PrivateKey(der []byte) (*PrivateKey, error) {
+	key, err := x509.ParsePKCS1PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	return FromStdPrivateKey(key)
+}
+
+// MarshalPKCS8PrivateKey encode private key in PKCS#8 ASN.1 DER form
+func MarshalPKCS8PrivateKey(prvkey *PrivateKey) ([]byte, error) {
+	key, err := prvkey.ToStd()
+	if err != nil {
+		return nil, err
+	}
+	return x509.MarshalPKCS8PrivateKey(key)
+}
+
+// ParsePKCS8PrivateKey decode private key in PKCS#8 ASN.1 DER form, the key must be RSA
+func ParsePKCS8PrivateKey(der []byte) (*PrivateKey, error) {
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*stdrsa.PrivateKey)
+	if !ok {
+		return nil, ErrNotRSAKey
+	}
+	return FromStdPrivateKey(rsaKey)
+}
+
+// MarshalPKCS1PublicKey encode public key in PKCS#1 ASN.1 DER form
+func MarshalPKCS1PublicKey(pubkey *PublicKey) ([]byte, error) {
+	key, err := pubkey.ToStd()
+	if err != nil {
+		return nil, err
+	}
+	return x509.MarshalPKCS1PublicKey(key), nil
+}
+
+// ParsePKCS1PublicKey decode public key in PKCS#1 ASN.1 DER form
+func ParsePKCS1PublicKey(der []byte) (*PublicKey, error) {
+	key, err := x509.ParsePKCS1PublicKey(der)
+	if err != nil {
+		return nil, err
+	}
+	return FromStdPublicKey(key), nil
+}
+
+// MarshalPKIXPublicKey encode public key in SubjectPublicKeyInfo ASN.1 DER form
+func MarshalPKIXPublicKey(pubkey *PublicKey) ([]byte, error) {
+	key, err := pubkey.ToStd()
+	if err != nil {
+		return nil, err
+	}
+	return x509.MarshalPKIXPublicKey(key)
+}
+
+// ParsePKIXPublicKey decode public key in SubjectPublicKeyInfo ASN.1 DER form, the key must be RSA
+func ParsePKIXPublicKey(der []byte) (*PublicKey, error) {
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*stdrsa.PublicKey)
+	if !ok {
+		return nil, ErrNotRSAKey
+	}
+	return FromStdPublicKey(rsaKey), nil
+}
+
+// EncodePrivateKeyPEM encode private key as PKCS#8 "PRIVATE KEY" PEM block
+func EncodePrivateKeyPEM(prvkey *PrivateKey) ([]byte, error) {
+	der, err := MarshalPKCS8PrivateKey(prvkey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: pemPKCS8PrivateKey, Bytes: der}), nil
+}
+
+// ParsePrivateKeyPEM decode the first PEM block, either PKCS#1 "RSA PRIVATE KEY" or PKCS#8 "PRIVATE KEY"
+func ParsePrivateKeyPEM(data []byte) (*PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrPEMNotFound
+	}
+	switch block.Type {
+	case pemPKCS1PrivateKey:
+		return ParsePKCS1PrivateKey(block.Bytes)
+	case pemPKCS8PrivateKey:
+		return ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrPEMBlockType, block.Type)
+	}
+}
+
+// EncodePublicKeyPEM encode public key as SubjectPublicKeyInfo "PUBLIC KEY" PEM block
+func EncodePublicKeyPEM(pubkey *PublicKey) ([]byte, error) {
+	der, err := MarshalPKIXPublicKey(pubkey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: pemPKIXPublicKey, Bytes: der}), nil
+}
+
+// ParsePublicKeyPEM decode the first PEM block, either SubjectPublicKeyInfo "PUBLIC KEY" or PKCS#1 "RSA PUBLIC KEY"
+func ParsePublicKeyPEM(data []byte) (*PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrPEMNotFound
+	}
+	switch block.Type {
+	case pemPKIXPublicKey:
+		return ParsePKIXPublicKey(block.Bytes)
+	case pemPKCS1PublicKey:
+		return ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrPEMBlockType, block.Type)
+	}
+}
@@ -0,0 +1,127 @@
+package rsa
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"hash"
+	"io"
+	"math/big"
+)
+
+var (
+	ErrMessageTooLong = errors.New("rsa: message too long for RSA key size")
+	ErrDecryption     = errors.New("rsa: decryption error")
+)
+
+// EncryptOAEP RSAES-OAEP-ENCRYPT of RFC 8017 section 7.1.1, h is used for both label hash and MGF1
+// the ciphertext is randomized and as long as N, msg is at most k - 2*hLen - 2 bytes
+func EncryptOAEP(h func() hash.Hash, pubkey *PublicKey, msg, label []byte) ([]byte, error) {
+	hh := h()
+	k := byteLen(pubkey.N)
+	hLen := hh.Size()
+	if len(msg) > k-2*hLen-2 {
+		return nil, ErrMessageTooLong
+	}
+
+	// EM = 0x00 || maskedSeed || maskedDB, DB = lHash || PS || 0x01 || M
+	hh.Write(label)
+	em := make([]byte, k)
+	seed := em[1 : 1+hLen]
+	db := em[1+hLen:]
+	copy(db, hh.Sum(nil))
+	db[len(db)-len(msg)-1] = 1
+	copy(db[len(db)-len(msg):], msg)
+	if _, err := io.ReadFull(rand.Reader, seed); err != nil {
+		return nil, err
+	}
+	mgf1XOR(db, h, seed)
+	mgf1XOR(seed, h, db)
+
+	c, err := RSAEncrypt(new(big.Int).SetBytes(em), pubkey)
+	if err != nil {
+		return nil, err
+	}
+	return c.FillBytes(em), nil
+}
+
+// DecryptOAEP RSAES-OAEP-DECRYPT of RFC 8017 section 7.1.2
+// all padding errors are reported as ErrDecryption without revealing which check failed
+func DecryptOAEP(h func() hash.Hash, prvkey *PrivateKey, ciphertext, label []byte) ([]byte, error) {
+	hh := h()
+	k := byteLen(prvkey.N)
+	hLen := hh.Size()
+	if len(ciphertext) != k || k < 2*hLen+2 {
+		return nil, ErrDecryption
+	}
+	c := new(big.Int).SetBytes(ciphertext)
+	if c.Cmp(prvkey.N) >= 0 {
+		return nil, ErrDecryption
+	}
+	m, err := RSADecrypt(c, prvkey)
+	if err != nil {
+		return nil, ErrDecryption
+	}
+	hh.Write(label)
+	lHash := hh.Sum(nil)
+
+	em := m.FillBytes(make([]byte, k))
+	firstByteIsZero := subtle.ConstantTimeByteEq(em[0], 0)
+	seed := em[1 : 1+hLen]
+	db := em[1+hLen:]
+	mgf1XOR(seed, h, db)
+	mgf1XOR(db, h, seed)
+	lHashGood := subtle.ConstantTimeCompare(lHash, db[:hLen])
+
+	// find the 0x01 separator after PS in constant time
+	// lookingForIndex is 1 until the first nonzero byte, invalid is set if that byte is not 0x01
+	var lookingForIndex, index, invalid int
+	lookingForIndex = 1
+	rest := db[hLen:]
+	for i := 0; i < len(rest); i++ {
+		equals0 := subtle.ConstantTimeByteEq(rest[i], 0)
+		equals1 := subtle.ConstantTimeByteEq(rest[i], 1)
+		index = subtle.ConstantTimeSelect(lookingForIndex&equals1, i, index)
+		lookingForIndex = subtle.ConstantTimeSelect(equals1, 0, lookingForIndex)
+		invalid = subtle.ConstantTimeSelect(lookingForIndex&^equals0, 1, invalid)
+	}
+	if firstByteIsZero&lHashGood&^invalid&^lookingForIndex != 1 {
+		return nil, ErrDecryption
+	}
+	return rest[index+1:], nil
+}
+
+// mgf1XOR xor out with MGF1 of RFC 8017 appendix B.2.1 on seed
+// T = Hash(seed || I2OSP(0, 4)) || Hash(seed || I2OSP(1, 4)) || ...
+func mgf1XOR(out []byte, h func() hash.Hash, seed []byte) {
+	hh := h()
+	var counter [4]byte
+	var digest []byte
+	done := 0
+	for done < len(out) {
+		hh.Reset()
+		hh.Write(seed)
+		hh.Write(counter[:])
+		digest = hh.Sum(digest[:0])
+		for i := 0; i < len(digest) && done < len(out); i++ {
+			out[done] ^= digest[i]
+			done++
+		}
+		incCounter(&counter)
+	}
+}
+
+// incCounter increase big-endian 32-bit counter
+func incCounter(c *[4]byte) {
+	for i := 3; i >= 0; i-- {
+		c[i]++
+		if c[i] != 0 {
+			return
+		}
+	}
+}
+
+// byteLen length of N in bytes
+func byteLen(n *big.Int) int {
+	return (n.BitLen() + 7) / 8
+}
@@ -0,0 +1,136 @@
+package rsa
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"hash"
+	"io"
+	"math/big"
+)
+
+// pssSaltLengthAuto salt length is recovered from the encoding on verification
+const pssSaltLengthAuto = -1
+
+var ErrVerification = errors.New("rsa: verification error")
+
+// SignPSS RSASSA-PSS-SIGN of RFC 8017 section 8.1.1, h is used for message hash and MGF1
+// the salt is as long as the hash output, the signature is as long as N
+func SignPSS(h func() hash.Hash, prvkey *PrivateKey, msg []byte) ([]byte, error) {
+	salt := make([]byte, h().Size())
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	m, err := EncodePSS(h, &prvkey.PublicKey, msg, salt)
+	if err != nil {
+		return nil, err
+	}
+	s, err := RSASign(m, prvkey)
+	if err != nil {
+		return nil, err
+	}
+	return s.FillBytes(make([]byte, byteLen(prvkey.N))), nil
+}
+
+// EncodePSS message representative of RSASSA-PSS with given salt, RSASign of it is a PSS signature
+// it lets parties that only hold shares of the private key sign the same representative, e.g. with an empty salt
+func EncodePSS(h func() hash.Hash, pubkey *PublicKey, msg, salt []byte) (*big.Int, error) {
+	hh := h()
+	hh.Write(msg)
+	em, err := emsaPSSEncode(h, hh.Sum(nil), salt, pubkey.N.BitLen()-1)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(em), nil
+}
+
+// VerifyPSS RSASSA-PSS-VERIFY of RFC 8017 section 8.1.2, the salt length is recovered from the encoding
+func VerifyPSS(h func() hash.Hash, pubkey *PublicKey, msg, sig []byte) (bool, error) {
+	return verifyPSS(h, pubkey, msg, sig, pssSaltLengthAuto)
+}
+
+// verifyPSS RSASSA-PSS-VERIFY with salt length saltLen or pssSaltLengthAuto
+func verifyPSS(h func() hash.Hash, pubkey *PublicKey, msg, sig []byte, saltLen int) (bool, error) {
+	if len(sig) != byteLen(pubkey.N) {
+		return false, ErrVerification
+	}
+	s := new(big.Int).SetBytes(sig)
+	if s.Cmp(pubkey.N) >= 0 {
+		return false, ErrVerification
+	}
+	m := new(big.Int).Exp(s, pubkey.E, pubkey.N)
+
+	emBits := pubkey.N.BitLen() - 1
+	emLen := (emBits + 7) / 8
+	if m.BitLen() > emBits {
+		return false, nil
+	}
+	hh := h()
+	hh.Write(msg)
+	mHash := hh.Sum(nil)
+	return emsaPSSVerify(h, mHash, m.FillBytes(make([]byte, emLen)), emBits, saltLen), nil
+}
+
+// emsaPSSEncode EMSA-PSS-ENCODE of RFC 8017 section 9.1.1
+// EM = maskedDB || H || 0xbc, H = Hash(0x00*8 || mHash || salt), DB = PS || 0x01 || salt
+func emsaPSSEncode(h func() hash.Hash, mHash, salt []byte, emBits int) ([]byte, error) {
+	hLen := len(mHash)
+	emLen := (emBits + 7) / 8
+	if emLen < hLen+len(salt)+2 {
+		return nil, ErrMessageTooLong
+	}
+	em := make([]byte, emLen)
+	db := em[:emLen-hLen-1]
+	H := em[emLen-hLen-1 : emLen-1]
+
+	hh := h()
+	hh.Write(make([]byte, 8))
+	hh.Write(mHash)
+	hh.Write(salt)
+	hh.Sum(H[:0])
+
+	db[len(db)-len(salt)-1] = 1
+	copy(db[len(db)-len(salt):], salt)
+	mgf1XOR(db, h, H)
+	db[0] &= 0xff >> uint(8*emLen-emBits)
+	em[emLen-1] = 0xbc
+	return em, nil
+}
+
+// emsaPSSVerify EMSA-PSS-VERIFY of RFC 8017 section 9.1.2, salt length is detected if saltLen is pssSaltLengthAuto
+func emsaPSSVerify(h func() hash.Hash, mHash, em []byte, emBits, saltLen int) bool {
+	hLen := len(mHash)
+	emLen := len(em)
+	if emLen < hLen+2 || em[emLen-1] != 0xbc {
+		return false
+	}
+	db := em[:emLen-hLen-1]
+	H := em[emLen-hLen-1 : emLen-1]
+	bitMask := byte(0xff >> uint(8*emLen-emBits))
+	if db[0]&^bitMask != 0 {
+		return false
+	}
+	mgf1XOR(db, h, H)
+	db[0] &= bitMask
+
+	// DB = 0x00 ... 0x00 || 0x01 || salt
+	sep := bytes.IndexByte(db, 1)
+	if sep < 0 {
+		return false
+	}
+	for _, b := range db[:sep] {
+		if b != 0 {
+			return false
+		}
+	}
+	salt := db[sep+1:]
+	if saltLen != pssSaltLengthAuto && len(salt) != saltLen {
+		return false
+	}
+
+	hh := h()
+	hh.Write(make([]byte, 8))
+	hh.Write(mHash)
+	hh.Write(salt)
+	return bytes.Equal(hh.Sum(nil), H)
+}
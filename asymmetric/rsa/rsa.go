@@ -3,63 +3,151 @@ package rsa
 import (
 	"crypto/rand"
 	"errors"
+	"fmt"
 	"math/big"
+	"sync"
 )
 
 var one = big.NewInt(1)
 var zero = big.NewInt(0)
 
+// ErrFault private key operation gave a wrong result, e.g. a faulty CRT half, it is not released
+var ErrFault = errors.New("rsa: private key operation failed verification")
+
+const (
+	// DefaultE public exponent of generated keys, F4 = 2^16 + 1
+	DefaultE = 65537
+	// minPrimeBits minimal size of each prime of multi-prime keys
+	minPrimeBits = 256
+)
+
 // PrivateKey represents a RSA private key
 type PrivateKey struct {
 	PublicKey
 	P   *big.Int // P and Q are primes with same length
 	Q   *big.Int
-	Phi *big.Int // Phi=(P-1)(Q-1)
+	Phi *big.Int // Phi=(P-1)(Q-1)...(r_i-1)
 	D   *big.Int // D = e^-1 (mod phi)
+
+	// AdditionalPrimes primes other than P and Q of multi-prime RSA, N = P*Q*r_3*...*r_u
+	AdditionalPrimes []*big.Int
+	// Precomputed CRT values to speed up private key operations, filled by Precompute
+	Precomputed PrecomputedValues
+
+	blinding *blinding
+}
+
+// PrecomputedValues CRT values of RFC 8017 section 3.2
+type PrecomputedValues struct {
+	Dp, Dq *big.Int // D mod (P-1), D mod (Q-1)
+	Qinv   *big.Int // Q^-1 mod P
+	// CRTValues for AdditionalPrimes
+	CRTValues []CRTValue
+}
+
+// CRTValue CRT values of an additional prime r_i
+type CRTValue struct {
+	Exp   *big.Int // D mod (r_i-1)
+	Coeff *big.Int // R^-1 mod r_i
+	R     *big.Int // product of primes prior to r_i, P*Q*r_3*...*r_(i-1)
 }
 
 // PublicKey represents a RSA public key
 type PublicKey struct {
 	N *big.Int // N=P*Q
-	E *big.Int // 0 < E < phi，&& e and phi are coprime
+	E *big.Int // 1 < E < phi，&& e and phi are coprime
 }
 
-// GenerateKey generates a rsa private key
+// GenerateKey generates a rsa private key of secbit-bit N with E = 65537
 func GenerateKey(secbit int) (*PrivateKey, error) {
-	keylen := secbit / 2
-	p, err := rand.Prime(rand.Reader, keylen)
-	if err != nil {
-		return nil, err
+	return GenerateMultiPrimeKey(2, secbit)
+}
+
+// GenerateMultiPrimeKey generates a rsa private key with nprimes distinct primes
+// more primes make private key operations faster, but the key is easier to factor if primes are too small
+func GenerateMultiPrimeKey(nprimes, secbit int) (*PrivateKey, error) {
+	if nprimes < 2 {
+		return nil, errors.New("at least 2 primes are required")
 	}
-	q, err := rand.Prime(rand.Reader, keylen)
-	if err != nil {
-		return nil, err
+	if secbit/nprimes < minPrimeBits {
+		return nil, fmt.Errorf("too many primes for %d-bit key, each prime should have at least %d bits", secbit, minPrimeBits)
 	}
-	n := new(big.Int).Mul(p, q)
-	phi := new(big.Int).Mul(new(big.Int).Sub(p, one), new(big.Int).Sub(q, one))
-	// e cannot be zero, e and phi must be coprime
-	var e *big.Int
+
+	e := big.NewInt(DefaultE)
+	var primes []*big.Int
+	var n, phi *big.Int
+NextSetOfPrimes:
 	for {
-		e, err = rand.Int(rand.Reader, phi)
-		if err != nil {
-			return nil, err
+		primes = make([]*big.Int, nprimes)
+		todo := secbit
+		for i := 0; i < nprimes; i++ {
+			keylen := todo / (nprimes - i)
+			prime, err := rand.Prime(rand.Reader, keylen)
+			if err != nil {
+				return nil, err
+			}
+			primes[i] = prime
+			todo -= keylen
 		}
-		gcd := new(big.Int).GCD(nil, nil, e, phi)
-		if e.Cmp(zero) != 0 && gcd.Cmp(one) == 0 {
+
+		// primes must be distinct, N must be exactly secbit long, and e and phi must be coprime
+		n = new(big.Int).Set(one)
+		phi = new(big.Int).Set(one)
+		for i, prime := range primes {
+			for _, r := range primes[:i] {
+				if r.Cmp(prime) == 0 {
+					continue NextSetOfPrimes
+				}
+			}
+			n.Mul(n, prime)
+			phi.Mul(phi, new(big.Int).Sub(prime, one))
+		}
+		if n.BitLen() != secbit {
+			continue
+		}
+		if new(big.Int).GCD(nil, nil, e, phi).Cmp(one) == 0 {
 			break
 		}
 	}
 	d := new(big.Int).ModInverse(e, phi)
-	return &PrivateKey{
+	key := &PrivateKey{
 		PublicKey: PublicKey{
 			N: n,
 			E: e,
 		},
-		P:   p,
-		Q:   q,
+		P:   primes[0],
+		Q:   primes[1],
 		Phi: phi,
 		D:   d,
-	}, nil
+	}
+	if nprimes > 2 {
+		key.AdditionalPrimes = primes[2:]
+	}
+	key.Precompute()
+	return key, nil
+}
+
+// Precompute precompute CRT values, private key operations without them use the full-size D
+func (prvkey *PrivateKey) Precompute() {
+	if prvkey.Precomputed.Dp != nil {
+		return
+	}
+	p, q, d := prvkey.P, prvkey.Q, prvkey.D
+	prvkey.Precomputed.Dp = new(big.Int).Mod(d, new(big.Int).Sub(p, one))
+	prvkey.Precomputed.Dq = new(big.Int).Mod(d, new(big.Int).Sub(q, one))
+	prvkey.Precomputed.Qinv = new(big.Int).ModInverse(q, p)
+
+	r := new(big.Int).Mul(p, q)
+	prvkey.Precomputed.CRTValues = make([]CRTValue, len(prvkey.AdditionalPrimes))
+	for i, prime := range prvkey.AdditionalPrimes {
+		prvkey.Precomputed.CRTValues[i] = CRTValue{
+			Exp:   new(big.Int).Mod(d, new(big.Int).Sub(prime, one)),
+			Coeff: new(big.Int).ModInverse(r, prime),
+			R:     new(big.Int).Set(r),
+		}
+		r.Mul(r, prime)
+	}
+	prvkey.blinding = new(blinding)
 }
 
 // RSA encryption and decryption
@@ -77,8 +165,7 @@ func RSADecrypt(c *big.Int, prvkey *PrivateKey) (*big.Int, error) {
 	if c.Cmp(prvkey.N) > 0 {
 		return nil, errors.New("message must be smaller than N")
 	}
-	m := new(big.Int).Exp(c, prvkey.D, prvkey.N)
-	return m, nil
+	return decrypt(c, prvkey)
 }
 
 // homomorphic encryption
@@ -92,8 +179,7 @@ func RSASign(m *big.Int, prvkey *PrivateKey) (*big.Int, error) {
 	if m.Cmp(prvkey.N) > 0 {
 		return nil, errors.New("message must be smaller than N")
 	}
-	sig := new(big.Int).Exp(m, prvkey.D, prvkey.N)
-	return sig, nil
+	return decrypt(m, prvkey)
 }
 
 // msg = sig^E (mod N)
@@ -110,3 +196,93 @@ func RSAVerify(m *big.Int, sig *big.Int, pubkey *PublicKey) (bool, error) {
 	}
 	return false, nil
 }
+
+// decrypt private key operation c^D (mod N) with blinding
+// CRT is used if precomputed, RFC 8017 section 5.1.2
+func decrypt(c *big.Int, prvkey *PrivateKey) (*big.Int, error) {
+	b, err := prvkey.blind()
+	if err != nil {
+		return nil, err
+	}
+	// c' = c * r^E, m' = c'^D = m * r
+	cc := new(big.Int).Mul(c, b.r)
+	cc.Mod(cc, prvkey.N)
+
+	var m *big.Int
+	if prvkey.Precomputed.Dp == nil {
+		m = new(big.Int).Exp(cc, prvkey.D, prvkey.N)
+	} else {
+		pre := &prvkey.Precomputed
+		// m_1 = c^dP mod p, m_2 = c^dQ mod q, h = (m_1 - m_2) * qInv mod p, m = m_2 + q * h
+		m = new(big.Int).Exp(new(big.Int).Mod(cc, prvkey.P), pre.Dp, prvkey.P)
+		m2 := new(big.Int).Exp(new(big.Int).Mod(cc, prvkey.Q), pre.Dq, prvkey.Q)
+		m.Sub(m, m2)
+		m.Mul(m, pre.Qinv)
+		m.Mod(m, prvkey.P)
+		m.Mul(m, prvkey.Q)
+		m.Add(m, m2)
+
+		// m_i = c^d_i mod r_i, h = (m_i - m) * t_i mod r_i, m = m + R * h
+		for i, prime := range prvkey.AdditionalPrimes {
+			values := &pre.CRTValues[i]
+			mi := new(big.Int).Exp(new(big.Int).Mod(cc, prime), values.Exp, prime)
+			mi.Sub(mi, m)
+			mi.Mul(mi, values.Coeff)
+			mi.Mod(mi, prime)
+			mi.Mul(mi, values.R)
+			m.Add(m, mi)
+		}
+	}
+
+	// check m'^E = c' before unblinding, a result wrong modulo one prime only would leak that prime by gcd(m'^E - c', N)
+	if new(big.Int).Exp(m, prvkey.E, prvkey.N).Cmp(cc) != 0 {
+		return nil, ErrFault
+	}
+
+	m.Mul(m, b.rInv)
+	return m.Mod(m, prvkey.N), nil
+}
+
+// blinding blinding factors of a private key, r = v^E and rInv = v^-1 for random v
+// after each use both are squared so that they stay paired without another exponentiation by E
+type blinding struct {
+	sync.Mutex
+	r, rInv *big.Int
+}
+
+// blind get blinding factors for one private key operation
+// keys without precomputed values get fresh factors every time
+func (prvkey *PrivateKey) blind() (*blinding, error) {
+	b := prvkey.blinding
+	if b == nil {
+		return newBlinding(&prvkey.PublicKey)
+	}
+	b.Lock()
+	defer b.Unlock()
+	if b.r == nil {
+		fresh, err := newBlinding(&prvkey.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		b.r, b.rInv = fresh.r, fresh.rInv
+	}
+	ret := &blinding{r: new(big.Int).Set(b.r), rInv: new(big.Int).Set(b.rInv)}
+	b.r.Mul(b.r, b.r).Mod(b.r, prvkey.N)
+	b.rInv.Mul(b.rInv, b.rInv).Mod(b.rInv, prvkey.N)
+	return ret, nil
+}
+
+// newBlinding random blinding factors
+func newBlinding(pubkey *PublicKey) (*blinding, error) {
+	for {
+		v, err := rand.Int(rand.Reader, pubkey.N)
+		if err != nil {
+			return nil, err
+		}
+		vInv := new(big.Int).ModInverse(v, pubkey.N)
+		if vInv == nil {
+			continue
+		}
+		return &blinding{r: new(big.Int).Exp(v, pubkey.E, pubkey.N), rInv: vInv}, nil
+	}
+}
@@ -1,20 +1,33 @@
 package rsa
 
 import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	stdrsa "crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
 	"fmt"
 	"math/big"
 	"testing"
+
+	"github.com/hongyanwang/crypto-lab/hash/sm3"
 )
 
 var (
-	secbit        = 4096
-	prvkey        *PrivateKey
-	plaintext1    = big.NewInt(25)
-	plaintext2    = big.NewInt(12)
-	ciphertext1   *big.Int
-	ciphertext2   *big.Int
-	ciphertextMul *big.Int
-	signature     *big.Int
+	secbit          = 4096
+	thresholdSecbit = 1024
+	prvkey          *PrivateKey
+	plaintext1      = big.NewInt(25)
+	plaintext2      = big.NewInt(12)
+	ciphertext1     *big.Int
+	ciphertext2     *big.Int
+	ciphertextMul   *big.Int
+	signature       *big.Int
 )
 
 func BenchmarkKeyGen(b *testing.B) {
@@ -47,6 +60,80 @@ func BenchmarkVerify(b *testing.B) {
 		testVerify()
 	}
 }
+func BenchmarkEncryptOAEP(b *testing.B) {
+	msg := []byte("test oaep")
+	for i := 0; i < b.N; i++ {
+		EncryptOAEP(sha256.New, &prvkey.PublicKey, msg, nil)
+	}
+}
+func BenchmarkDecryptOAEP(b *testing.B) {
+	ct, _ := EncryptOAEP(sha256.New, &prvkey.PublicKey, []byte("test oaep"), nil)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		DecryptOAEP(sha256.New, prvkey, ct, nil)
+	}
+}
+func BenchmarkSignPSS(b *testing.B) {
+	msg := []byte("test pss")
+	for i := 0; i < b.N; i++ {
+		SignPSS(sha256.New, prvkey, msg)
+	}
+}
+func BenchmarkVerifyPSS(b *testing.B) {
+	msg := []byte("test pss")
+	sig, _ := SignPSS(sha256.New, prvkey, msg)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		VerifyPSS(sha256.New, &prvkey.PublicKey, msg, sig)
+	}
+}
+
+// BenchmarkDecNoCRT decryption with full-size D, compare with BenchmarkDec
+func BenchmarkDecNoCRT(b *testing.B) {
+	noCRT := *prvkey
+	noCRT.Precomputed = PrecomputedValues{}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		RSADecrypt(ciphertext1, &noCRT)
+	}
+}
+
+func BenchmarkDecMultiPrime(b *testing.B) {
+	for _, nprimes := range []int{3, 4} {
+		key, err := GenerateMultiPrimeKey(nprimes, secbit)
+		if err != nil {
+			b.Fatal(err)
+		}
+		c, _ := RSAEncrypt(plaintext1, &key.PublicKey)
+		b.Run(fmt.Sprintf("%d-primes", nprimes), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				RSADecrypt(c, key)
+			}
+		})
+	}
+}
+
+func BenchmarkThresholdSignShare(b *testing.B) {
+	pub, shares, _ := GenerateThresholdKey(thresholdSecbit, 3, 5)
+	x, _ := EncodePSS(sha256.New, &pub.PublicKey, []byte("test threshold rsa"), nil)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		shares[0].SignShare(pub, x)
+	}
+}
+
+func BenchmarkThresholdCombine(b *testing.B) {
+	pub, shares, _ := GenerateThresholdKey(thresholdSecbit, 3, 5)
+	x, _ := EncodePSS(sha256.New, &pub.PublicKey, []byte("test threshold rsa"), nil)
+	sigShares := make([]*SignatureShare, 3)
+	for i := range sigShares {
+		sigShares[i], _ = shares[i].SignShare(pub, x)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pub.CombineShares(x, sigShares)
+	}
+}
 
 func testKeyGen() {
 	prvkey, _ = GenerateKey(secbit)
@@ -75,6 +162,12 @@ func TestKeyGen(t *testing.T) {
 		t.Error(err)
 	}
 	prvkey = privateKey
+	if prvkey.E.Int64() != DefaultE || prvkey.N.BitLen() != secbit {
+		t.Errorf("unexpected key parameters, E: %v, N bits: %d", prvkey.E, prvkey.N.BitLen())
+	}
+	if err := prvkey.Validate(); err != nil {
+		t.Error(err)
+	}
 	fmt.Println(prvkey)
 }
 
@@ -126,3 +219,726 @@ func TestSig(t *testing.T) {
 	}
 	fmt.Println(v)
 }
+
+func TestOAEP(t *testing.T) {
+	stdKey, err := stdrsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := FromStdPrivateKey(stdKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("test oaep")
+	label := []byte("label")
+
+	// interoperability with crypto/rsa in both directions
+	ct, err := EncryptOAEP(sha256.New, &key.PublicKey, msg, label)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pt, err := stdrsa.DecryptOAEP(sha256.New(), nil, stdKey, ct, label)
+	if err != nil || !bytes.Equal(pt, msg) {
+		t.Errorf("crypto/rsa failed to decrypt: %v", err)
+	}
+	ct, err = stdrsa.EncryptOAEP(sha256.New(), rand.Reader, &stdKey.PublicKey, msg, label)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pt, err = DecryptOAEP(sha256.New, key, ct, label)
+	if err != nil || !bytes.Equal(pt, msg) {
+		t.Errorf("failed to decrypt crypto/rsa ciphertext: %v", err)
+	}
+
+	// SM3, empty and longest messages
+	k := byteLen(key.N)
+	for _, m := range [][]byte{{}, msg, bytes.Repeat([]byte{0xff}, k-2*sm3.SIZE-2)} {
+		ct, err := EncryptOAEP(sm3.New, &key.PublicKey, m, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pt, err := DecryptOAEP(sm3.New, key, ct, nil)
+		if err != nil || !bytes.Equal(pt, m) {
+			t.Errorf("SM3 OAEP failed for %d-byte message: %v", len(m), err)
+		}
+	}
+	if _, err := EncryptOAEP(sm3.New, &key.PublicKey, make([]byte, k-2*sm3.SIZE-1), nil); err != ErrMessageTooLong {
+		t.Errorf("too long message accepted")
+	}
+
+	// encryption is randomized, tampering and wrong label are detected
+	ct1, _ := EncryptOAEP(sha256.New, &key.PublicKey, msg, label)
+	ct2, _ := EncryptOAEP(sha256.New, &key.PublicKey, msg, label)
+	if bytes.Equal(ct1, ct2) {
+		t.Errorf("OAEP encryption is deterministic")
+	}
+	if _, err := DecryptOAEP(sha256.New, key, ct1, []byte("other label")); err != ErrDecryption {
+		t.Errorf("wrong label accepted")
+	}
+	ct1[len(ct1)-1] ^= 1
+	if _, err := DecryptOAEP(sha256.New, key, ct1, label); err != ErrDecryption {
+		t.Errorf("tampered ciphertext accepted")
+	}
+	if _, err := DecryptOAEP(sm3.New, key, ct2, label); err != ErrDecryption {
+		t.Errorf("ciphertext decrypted with wrong hash")
+	}
+}
+
+func TestPSS(t *testing.T) {
+	// odd modulus size exercises emBits % 8 != 0
+	for _, bits := range []int{2048, 2047} {
+		stdKey, err := stdrsa.GenerateKey(rand.Reader, bits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, err := FromStdPrivateKey(stdKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg := []byte("test pss")
+		digest := sha256.Sum256(msg)
+
+		sig, err := SignPSS(sha256.New, key, msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts := &stdrsa.PSSOptions{SaltLength: stdrsa.PSSSaltLengthEqualsHash}
+		if err := stdrsa.VerifyPSS(&stdKey.PublicKey, crypto.SHA256, digest[:], sig, opts); err != nil {
+			t.Errorf("%d bits: crypto/rsa failed to verify: %v", bits, err)
+		}
+		// crypto/rsa uses the longest salt by default, salt length is recovered by VerifyPSS
+		stdSig, err := stdrsa.SignPSS(rand.Reader, stdKey, crypto.SHA256, digest[:], nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := VerifyPSS(sha256.New, &key.PublicKey, msg, stdSig); err != nil || !ok {
+			t.Errorf("%d bits: failed to verify crypto/rsa signature: %v", bits, err)
+		}
+
+		sig, err = SignPSS(sm3.New, key, msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := VerifyPSS(sm3.New, &key.PublicKey, msg, sig); err != nil || !ok {
+			t.Errorf("%d bits: SM3 PSS verification failed: %v", bits, err)
+		}
+		if ok, _ := VerifyPSS(sm3.New, &key.PublicKey, []byte("other message"), sig); ok {
+			t.Errorf("%d bits: signature of another message verified", bits)
+		}
+		if ok, _ := VerifyPSS(sha256.New, &key.PublicKey, msg, sig); ok {
+			t.Errorf("%d bits: signature verified with wrong hash", bits)
+		}
+		sig[0] ^= 1
+		if ok, _ := VerifyPSS(sm3.New, &key.PublicKey, msg, sig); ok {
+			t.Errorf("%d bits: tampered signature verified", bits)
+		}
+	}
+}
+
+func TestCRT(t *testing.T) {
+	for _, nprimes := range []int{2, 3, 4} {
+		key, err := GenerateMultiPrimeKey(nprimes, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(key.AdditionalPrimes) != nprimes-2 || len(key.Precomputed.CRTValues) != nprimes-2 {
+			t.Fatalf("%d primes: wrong number of additional primes", nprimes)
+		}
+		for i := 0; i < 5; i++ {
+			m, _ := rand.Int(rand.Reader, key.N)
+			c, err := RSAEncrypt(m, &key.PublicKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// CRT result equals c^D and is unchanged across blinding updates
+			expected := new(big.Int).Exp(c, key.D, key.N)
+			got, err := RSADecrypt(c, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Cmp(m) != 0 || got.Cmp(expected) != 0 {
+				t.Errorf("%d primes: CRT decryption mismatch", nprimes)
+			}
+			sig, err := RSASign(m, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v, _ := RSAVerify(m, sig, &key.PublicKey); !v {
+				t.Errorf("%d primes: CRT signature verification failed", nprimes)
+			}
+		}
+	}
+	if _, err := GenerateMultiPrimeKey(1, 2048); err == nil {
+		t.Errorf("single prime key accepted")
+	}
+	if _, err := GenerateMultiPrimeKey(16, 2048); err == nil {
+		t.Errorf("too many primes accepted")
+	}
+
+	// keys without precomputed values use D with fresh blinding factors
+	key, _ := GenerateKey(2048)
+	noCRT := *key
+	noCRT.Precomputed = PrecomputedValues{}
+	noCRT.blinding = nil
+	c, _ := RSAEncrypt(plaintext1, &key.PublicKey)
+	if m, _ := RSADecrypt(c, &noCRT); m.Cmp(plaintext1) != 0 {
+		t.Errorf("decryption without CRT failed")
+	}
+	noCRT.Precompute()
+	if m, _ := RSADecrypt(c, &noCRT); m.Cmp(plaintext1) != 0 {
+		t.Errorf("decryption after Precompute failed")
+	}
+
+	// a faulty CRT half is detected instead of returning a result which leaks a factor of N
+	faulty := *key
+	faulty.Precomputed.Dp = new(big.Int).Add(key.Precomputed.Dp, one)
+	faulty.blinding = nil
+	if _, err := RSADecrypt(c, &faulty); err != ErrFault {
+		t.Errorf("expected ErrFault, got %v", err)
+	}
+	if _, err := RSASign(plaintext1, &faulty); err != ErrFault {
+		t.Errorf("expected ErrFault for signing, got %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	key, err := GenerateMultiPrimeKey(3, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := key.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	corrupt := map[string]func(k *PrivateKey){
+		"P = Q":   func(k *PrivateKey) { k.Q = k.P },
+		"wrong D": func(k *PrivateKey) { k.D = new(big.Int).Add(k.D, one) },
+		"wrong N": func(k *PrivateKey) { k.N = new(big.Int).Add(k.N, big.NewInt(2)) },
+		"composite": func(k *PrivateKey) {
+			k.AdditionalPrimes = []*big.Int{new(big.Int).Mul(k.AdditionalPrimes[0], big.NewInt(3))}
+		},
+		"E = 1":      func(k *PrivateKey) { k.E = big.NewInt(1) },
+		"wrong Phi":  func(k *PrivateKey) { k.Phi = new(big.Int).Add(k.Phi, one) },
+		"missing D":  func(k *PrivateKey) { k.D = nil },
+		"missing Q":  func(k *PrivateKey) { k.Q = nil },
+		"even N":     func(k *PrivateKey) { k.N = new(big.Int).Lsh(k.N, 1) },
+		"E too big":  func(k *PrivateKey) { k.E = new(big.Int).Set(k.N) },
+		"missing N":  func(k *PrivateKey) { k.N = nil },
+		"only P = 1": func(k *PrivateKey) { k.P = big.NewInt(1) },
+	}
+	for name, f := range corrupt {
+		k := *key
+		f(&k)
+		if err := k.Validate(); err == nil {
+			t.Errorf("%s: invalid key accepted", name)
+		}
+	}
+}
+
+func TestEncoding(t *testing.T) {
+	key, err := GenerateKey(2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equalKeys := func(a, b *PrivateKey) bool {
+		return a.N.Cmp(b.N) == 0 && a.E.Cmp(b.E) == 0 && a.D.Cmp(b.D) == 0 &&
+			a.P.Cmp(b.P) == 0 && a.Q.Cmp(b.Q) == 0 && a.Phi.Cmp(b.Phi) == 0
+	}
+
+	der, err := MarshalPKCS1PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k, err := ParsePKCS1PrivateKey(der); err != nil || !equalKeys(k, key) {
+		t.Errorf("PKCS#1 private key round trip failed: %v", err)
+	}
+	der, err = MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k, err := ParsePKCS8PrivateKey(der); err != nil || !equalKeys(k, key) {
+		t.Errorf("PKCS#8 private key round trip failed: %v", err)
+	}
+	der, err = MarshalPKCS1PublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k, err := ParsePKCS1PublicKey(der); err != nil || k.N.Cmp(key.N) != 0 || k.E.Cmp(key.E) != 0 {
+		t.Errorf("PKCS#1 public key round trip failed: %v", err)
+	}
+
+	// keys in PEM written by crypto/x509 drive private key operations
+	stdKey, err := key.ToStd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs1PEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(stdKey)})
+	spki, _ := x509.MarshalPKIXPublicKey(&stdKey.PublicKey)
+	spkiPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: spki})
+	prv, err := ParsePrivateKeyPEM(pkcs1PEM)
+	if err != nil || !equalKeys(prv, key) {
+		t.Fatalf("failed to parse PKCS#1 PEM: %v", err)
+	}
+	pub, err := ParsePublicKeyPEM(spkiPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := RSAEncrypt(plaintext1, pub)
+	if m, _ := RSADecrypt(c, prv); m.Cmp(plaintext1) != 0 {
+		t.Errorf("decryption with PEM keys failed")
+	}
+
+	prvPEM, err := EncodePrivateKeyPEM(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k, err := ParsePrivateKeyPEM(prvPEM); err != nil || !equalKeys(k, key) {
+		t.Errorf("private key PEM round trip failed: %v", err)
+	}
+	pubPEM, err := EncodePublicKeyPEM(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pubPEM, spkiPEM) {
+		t.Errorf("public key PEM differs from crypto/x509")
+	}
+
+	// errors
+	if _, err := ParsePrivateKeyPEM([]byte("not a pem")); err != ErrPEMNotFound {
+		t.Errorf("missing PEM block not detected")
+	}
+	if _, err := ParsePublicKeyPEM(prvPEM); err == nil {
+		t.Errorf("private key parsed as public key")
+	}
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ecDER, _ := x509.MarshalPKCS8PrivateKey(ecKey)
+	if _, err := ParsePKCS8PrivateKey(ecDER); err != ErrNotRSAKey {
+		t.Errorf("non-RSA key not rejected")
+	}
+	large := &PublicKey{N: key.N, E: new(big.Int).Lsh(one, 40)}
+	if _, err := EncodePublicKeyPEM(large); err != ErrLargeE {
+		t.Errorf("large E not rejected")
+	}
+}
+
+// RFC 9474 appendix A, all variants use the same key and message
+var (
+	blindTestP = "e1f4d7a34802e27c7392a3cea32a262a34dc3691bd87f3f310dc75673488930559c120fd0410194fb8a0da55bd0b8122" +
+		"7e843fdca6692ae80e5a5d414116d4803fca7d8c30eaaae57e44a1816ebb5c5b0606c536246c7f11985d731684150b63" +
+		"c9a3ad9e41b04c0b5b27cb188a692c84696b742a80d3cd00ab891f2457443dadfeba6d6daf108602be26d7071803c671" +
+		"05a5426838e6889d77e8474b29244cefaf418e381b312048b457d73419213063c60ee7b0d81820165864fef93523c963" +
+		"5c22210956e53a8d96322493ffc58d845368e2416e078e5bcb5d2fd68ae6acfa54f9627c42e84a9d3f2774017e32ebca" +
+		"06308a12ecc290c7cd1156dcccfb2311"
+	blindTestQ = "c601a9caea66dc3835827b539db9df6f6f5ae77244692780cd334a006ab353c806426b60718c05245650821d39445d3a" +
+		"b591ed10a7339f15d83fe13f6a3dfb20b9452c6a9b42eaa62a68c970df3cadb2139f804ad8223d56108dfde30ba7d367" +
+		"e9b0a7a80c4fdba2fd9dde6661fc73fc2947569d2029f2870fc02d8325acf28c9afa19ecf962daa7916e21afad09eb62" +
+		"fe9f1cf91b77dc879b7974b490d3ebd2e95426057f35d0a3c9f45f79ac727ab81a519a8b9285932d9b2e5ccd347e59f3" +
+		"f32ad9ca359115e7da008ab7406707bd0e8e185a5ed8758b5ba266e8828f8d863ae133846304a2936ad7bc7c9803879d" +
+		"2fc4a28e69291d73dbd799f8bc238385"
+	blindTestD = "0d43242aefe1fb2c13fbc66e20b678c4336d20b1808c558b6e62ad16a287077180b177e1f01b12f9c6cd6c52630257cc" +
+		"ef26a45135a990928773f3bd2fc01a313f1dac97a51cec71cb1fd7efc7adffdeb05f1fb04812c924ed7f4a8269925dad" +
+		"88bd7dcfbc4ef01020ebfc60cb3e04c54f981fdbd273e69a8a58b8ceb7c2d83fbcbd6f784d052201b88a9848186f2a45" +
+		"c0d2826870733e6fd9aa46983e0a6e82e35ca20a439c5ee7b502a9062e1066493bdadf8b49eb30d9558ed85abc7afb29" +
+		"b3c9bc644199654a4676681af4babcea4e6f71fe4565c9c1b85d9985b84ec1abf1a820a9bbebee0df1398aae2c85ab58" +
+		"0a9f13e7743afd3108eb32100b870648fa6bc17e8abac4d3c99246b1f0ea9f7f93a5dd5458c56d9f3f81ff2216b3c368" +
+		"0a13591673c43194d8e6fc93fc1e37ce2986bd628ac48088bc723d8fbe293861ca7a9f4a73e9fa63b1b6d0074f5dea2a" +
+		"624c5249ff3ad811b6255b299d6bc5451ba7477f19c5a0db690c3e6476398b1483d10314afd38bbaf6e2fbdbcd62c3ca" +
+		"9797a420ca6034ec0a83360a3ee2adf4b9d4ba29731d131b099a38d6a23cc463db754603211260e99d19affc902c915d" +
+		"7854554aabf608e3ac52c19b8aa26ae042249b17b2d29669b5c859103ee53ef9bdc73ba3c6b537d5c34b6d8f034671d7" +
+		"f3a8a6966cc4543df223565343154140fd7391c7e7be03e241f4ecfeb877a051"
+	blindTestMsg = "8f3dc6fb8c4a02f4d6352edf0907822c1210a9b32f9bdda4c45a698c80023aa6b59f8cfec5fdbb36331372ebefedae7d"
+
+	blindVectors = []struct {
+		variant                                         *BlindSignVariant
+		msgPrefix, salt, inv, blindedMsg, blindSig, sig string
+	}{
+		{
+			RSABSSA_SHA384_PSS_RANDOMIZED,
+			"8417e699b219d583fb6216ae0c53ca0e9723442d02f1d1a34295527e7d929e8b",
+			"051722b35f458781397c3a671a7d3bd3096503940e4c4f1aaa269d60300ce449555cd7340100df9d46944c5356825abf",
+			"80682c48982407b489d53d1261b19ec8627d02b8cda5336750b8cee332ae260de57b02d72609c1e0e9f28e2040fc65b6" +
+				"f02d56dbd6aa9af8fde656f70495dfb723ba01173d4707a12fddac628ca29f3e32340bd8f7ddb557cf819f6b01e445ad" +
+				"96f874ba235584ee71f6581f62d4f43bf03f910f6510deb85e8ef06c7f09d9794a008be7ff2529f0ebb69decef646387" +
+				"dc767b74939265fec0223aa6d84d2a8a1cc912d5ca25b4e144ab8f6ba054b54910176d5737a2cff011da431bd5f2a0d2" +
+				"d66b9e70b39f4b050e45c0d9c16f02deda9ddf2d00f3e4b01037d7029cd49c2d46a8e1fc2c0c17520af1f4b5e25ba396" +
+				"afc4cd60c494a4c426448b35b49635b337cfb08e7c22a39b256dd032c00adddafb51a627f99a0e1704170ac1f1912e49" +
+				"d9db10ec04c19c58f420212973e0cb329524223a6aa56c7937c5dffdb5d966b6cd4cbc26f3201dd25c80960a1a111b32" +
+				"947bb78973d269fac7f5186530930ed19f68507540eed9e1bab8b00f00d8ca09b3f099aae46180e04e3584bd7ca054df" +
+				"18a1504b89d1d1675d0966c4ae1407be325cdf623cf13ff13e4a28b594d59e3eadbadf6136eee7a59d6a444c9eb4e219" +
+				"8e8a974f27a39eb63af2c9af3870488b8adaad444674f512133ad80b9220e09158521614f1faadfe8505ef57b7df6813" +
+				"048603f0dd04f4280177a11380fbfc861dbcbd7418d62155248dad5fdec0991f",
+			"aa3ee045138d874669685ffaef962c7694a9450aa9b4fd6465db9b3b75a522bb921c4c0fdcdfae9667593255099cff51" +
+				"f5d3fd65e8ffb9d3b3036252a6b51b6edfb3f40382b2bbf34c0055e4cbcc422850e586d84f190cd449af11dc65545f5f" +
+				"e26fd89796eb87da4bda0c545f397cddfeeb56f06e28135ec74fd477949e7677f6f36cfae8fd5c1c5898b03b9c244cf6" +
+				"d1a4fb7ad1cb43aff5e80cb462fac541e72f67f0a50f1843d1759edfaae92d1a916d3f0efaf4d650db416c3bf8abdb54" +
+				"14a78cebc97de676723cb119e77aea489f2bbf530c440ebc5a75dccd3ebf5a412a5f346badd61bee588e5917bdcce9dc" +
+				"33c882e39826951b0b8276c6203971947072b726e935816056ff5cb11a71ca2946478584126bb877acdf87255f26e6cc" +
+				"a4e0878801307485d3b7bb89b289551a8b65a7a6b93db010423d1406e149c87731910306e5e410b41d4da3234624e74f" +
+				"92845183e323cf7eb244f212a695f8856c675fbc3a021ce649e22c6f0d053a9d238841cf3afdc2739f99672a419ae13c" +
+				"17f1f8a3bc302ec2e7b98e8c353898b7150ad8877ec841ea6e4b288064c254fefd0d049c3ad196bf7ffa535e74585d01" +
+				"20ce728036ed500942fbd5e6332c298f1ffebe9ff60c1e117b274cf0cb9d70c36ee4891528996ec1ed0b178e9f3c0c0e" +
+				"6120885f39e8ccaadbb20f3196378c07b1ff22d10049d3039a7a92fe7efdd95d",
+			"3f4a79eacd4445fca628a310d41e12fcd813c4d43aa4ef2b81226953248d6d00adfee6b79cb88bfa1f99270369fd063c" +
+				"023e5ed546719b0b2d143dd1bca46b0e0e615fe5c63d95c5a6b873b8b50bc52487354e69c3dfbf416e7aca18d5842c89" +
+				"b676efdd38087008fa5a810161fcdec26f20ccf2f1e6ab0f9d2bb93e051cb9e86a9b28c5bb62fd5f5391379f887c0f70" +
+				"6a08bcc3b9e7506aaf02485d688198f5e22eefdf837b2dd919320b17482c5cc54271b4ccb41d267629b3f844fd63750b" +
+				"01f5276c79e33718bb561a152acb2eb36d8be75bce05c9d1b94eb609106f38226fb2e0f5cd5c5c39c59dda166862de49" +
+				"8b8d92f6bcb41af433d65a2ac23da87f39764cb64e79e74a8f4ce4dd567480d967cefac46b6e9c06434c371563583435" +
+				"7edd2ce6f105eea854ac126ccfa3de2aac5607565a4e5efaac5eed491c335f6fc97e6eb7e9cea3e12de38dfb315220c0" +
+				"a3f84536abb2fdd722813e083feda010391ac3d8fd1cd9212b5d94e634e69ebcc800c4d5c4c1091c64afc37acf563c7f" +
+				"c0a6e4c082bc55544f50a7971f3fb97d5853d72c3af34ffd5ce123998be5360d1059820c66a81e1ee6d9c1803b5b62af" +
+				"6bc877526df255b6d1d835d8c840bebbcd6cc0ee910f17da37caf8488afbc08397a1941fcc79e76a5888a95b3d5405e1" +
+				"3f737bea5c78d716a48eb9dc0aec8de39c4b45c6914ad4a8185969f70b1adf46",
+			"191e941c57510e22d29afad257de5ca436d2316221fe870c7cb75205a6c071c2735aed0bc24c37f3d5bd960ab97a829a" +
+				"508f966bbaed7a82645e65eadaf24ab5e6d9421392c5b15b7f9b640d34fec512846a3100b80f75ef51064602118c1a77" +
+				"d28d938f6efc22041d60159a518d3de7c4d840c9c68109672d743d299d8d2577ef60c19ab463c716b3fa75fa56f57353" +
+				"49d414a44df12bf0dd44aa3e10822a651ed4cb0eb6f47c9bd0ef14a034a7ac2451e30434d513eb22e68b7587a8de9b4e" +
+				"63a059d05c8b22c7c51e2cfee2d8bef511412e93c859a13726d87c57d1bc4c2e68ab121562f839c3a3d233e87ed63c69" +
+				"b7e57525367753fbebcc2a9805a2802659f5888b2c69115bf865559f10d906c09d048a0d71bfee4b33857393ec2b69e4" +
+				"51433496d02c9a7910abb954317720bbde9e69108eafc3e90bad3d5ca4066d7b1e49013fa04e948104a1dd82b12509ec" +
+				"b146e948c54bd8bfb5e6d18127cd1f7a93c3cf9f2d869d5a78878c03fe808a0d799e910be6f26d18db61c485b303631d" +
+				"3568368fc41986d08a95ea6ac0592240c19d7b22416b9c82ae6241e211dd5610d0baaa9823158f9c32b66318f5529491" +
+				"b7eeadcaa71898a63bac9d95f4aa548d5e97568d744fc429104e32edd9c87519892a198a30d333d427739ffb9607b092" +
+				"e910ae37771abf2adb9f63bc058bf58062ad456cb934679795bbdfcdfad5e0f2",
+		},
+		{
+			RSABSSA_SHA384_PSSZERO_RANDOMIZED,
+			"84ea86c8cf3beedfed73beceabd792027c609d1100bf041fdd60d826a718130d",
+			"",
+			"80682c48982407b489d53d1261b19ec8627d02b8cda5336750b8cee332ae260de57b02d72609c1e0e9f28e2040fc65b6" +
+				"f02d56dbd6aa9af8fde656f70495dfb723ba01173d4707a12fddac628ca29f3e32340bd8f7ddb557cf819f6b01e445ad" +
+				"96f874ba235584ee71f6581f62d4f43bf03f910f6510deb85e8ef06c7f09d9794a008be7ff2529f0ebb69decef646387" +
+				"dc767b74939265fec0223aa6d84d2a8a1cc912d5ca25b4e144ab8f6ba054b54910176d5737a2cff011da431bd5f2a0d2" +
+				"d66b9e70b39f4b050e45c0d9c16f02deda9ddf2d00f3e4b01037d7029cd49c2d46a8e1fc2c0c17520af1f4b5e25ba396" +
+				"afc4cd60c494a4c426448b35b49635b337cfb08e7c22a39b256dd032c00adddafb51a627f99a0e1704170ac1f1912e49" +
+				"d9db10ec04c19c58f420212973e0cb329524223a6aa56c7937c5dffdb5d966b6cd4cbc26f3201dd25c80960a1a111b32" +
+				"947bb78973d269fac7f5186530930ed19f68507540eed9e1bab8b00f00d8ca09b3f099aae46180e04e3584bd7ca054df" +
+				"18a1504b89d1d1675d0966c4ae1407be325cdf623cf13ff13e4a28b594d59e3eadbadf6136eee7a59d6a444c9eb4e219" +
+				"8e8a974f27a39eb63af2c9af3870488b8adaad444674f512133ad80b9220e09158521614f1faadfe8505ef57b7df6813" +
+				"048603f0dd04f4280177a11380fbfc861dbcbd7418d62155248dad5fdec0991f",
+			"4c1b82d9b97b968b2ce0754e326abd49e3d723ed937d84bead34b6a834483b43d510bf62ca47683ed366d94d3d357b27" +
+				"0a85cf2cc2ddd171141b45d7549d5373cf67d14f6f462c14ebded906793144faba37f129c0f3172854ec0f854e555552" +
+				"eec5a30c87788f1039814594f04348709e26a883be82affff207b1886b75c037f43f847f45d89bcbf210c22ffcdf8118" +
+				"ce8a526b3723e6209c26319f8f5d2adcf0b637031c9fdf53470a915c587e30287ba88ed4f1cd5e93cf3d4990acf31fff" +
+				"dbfddec80ae0b728d5b4c612a396fd81acaa65566a4dc1c24624f44fd10cdba05f3d0bed2e69bb0d13d41a9f1b4e67aa" +
+				"566520778733ced5e6260f4d1982f63bb835442acffe3cb87f5f8ec6bb84226e0eab787159d08e57604b13557ceea97f" +
+				"2c4ad0631accf898f302df86f0b64354ec0b3bdf1b4e2a4deb4d38f655ea8d80de4cc19aa06ffcd56e348faf894c8774" +
+				"c53235ddcc152d80cf66b417eee4d182781bab8c979937a3c7502d8f39c57c4f09884de5a7247f2539910a96e4b15f9a" +
+				"3df88edc21a13030af357467a99dca50dba4afe4a6185a240ac8f1d8aab2e83443025f94e1af930f56f78661369cc679" +
+				"0701f31b83aec40f96a72c7f7ba13b4ebdd8e24e7351f4ffba0a7c072cb28f13aff06cd02368491044fcc536213b2e3b" +
+				"1cf6ca81cf2097b7b19d2b36bd246f390f53768f1c2e56113ea91b33c7cfa647",
+			"4894f64d7214c216282d9842cbf7e7cccd9c0dcb1f4294a6bdeccd4c4c2446160d7cac7892f01b70dfa69f533891d2fb" +
+				"b447f7cf7541d1b504a2d46fc1bb6de26b345972aada8ebce280b906f3a10a13208f77ef896fbe6bc4504327fd4c5c8f" +
+				"03211d45ae9672e9f4be0f4900762ba2a7177a58b90d6dd1263faf2b7a5f15d50a7b00e733742c1b6a1ea4eb5fbfb407" +
+				"abf14496ab26b50cf1a5a56dea616b7a6a5595777400571a751c682b9fdd6badb3f72292f314f4ba2ba0f394f91676a4" +
+				"bb12e60ea08c977f7082be6357c1ca82fe3301fe5fb4128609bee2410db0481aea3a5737fb0bce9381272c2202644f66" +
+				"2e99f64bf1190d66e230cc0371ec33fe32fe725dfd872041914d39462a909414a780c9aab394af443199eba56c83986d" +
+				"22d57d4421b41ff8e5bec537d271223adb34d26c64989048a88d8f352a06a7cc153e216a6bed9548bb38d2a1600b2f34" +
+				"03289df6df74aec525ef9e413b7140a7c1a914dedd74a336f1beed39a8e5e2cef76cac094df0dbb3fa55d4b7ee781c74" +
+				"bed3bd8bc7aa6ef3f1dbfa4674945720ec93dafa6d0650229ab75e3fae687327fac081cf4bb376e02a2b73314c54c12f" +
+				"88572c28980f13aba5731bc5a3a60575ea116c8ea2fe5009168deb1255026c9310783ff7f644255d3e1691e194db1bab" +
+				"d7780b9a5dc0cb3de2b700d12f49cbe4db51ca2f3c8a58b09e854cc71e8070ab",
+			"195363ba25e4bf763f6538c86865785f93f4ea6092da3ad200d41b99eb0eb0869fa792df619fd8fa5923d5d03d5882fa" +
+				"ae6d25054118deef5e4a6a252dd5afb0dac262b74c391090b1575fbafd959d26bc294f47fb45a2c1c209932c4f94b243" +
+				"94eded91fbdd015e1a85dde63c9e77a0283f812cad1192d86432c51331e46fd4f3771bbafb929f847a19cb05e5f79b6b" +
+				"519d67e8f005951e53656be97cb612d2f506618b366403b34648451d6fbc7318c2f3f583cc6fa17bf2108398f9284e06" +
+				"02187904406a9322f1e7b8016ca9ad11b835756df862c465c420535e25faa48bf341f7ee8192be47fa875791f32f56d5" +
+				"e631d237060688f052426dee5b0b2b74ca5f830e82a453379eedb541fa4fcdaa19dae6509401e3cdd4c40f5c9243db3f" +
+				"6d7115c4e8cd6db8290723ab01d9d0d7e355a97a01547800e43f11736668c3f8908848d759c33a67a2f506abc3f6871c" +
+				"be625b1bc71eb06d785a59501396712c581a60d6ccc450d2f4eb4cf08ae0dbfa45c2860425be90cc4cd4c989495bbd29" +
+				"63e19c59ae5d90d1ca884e80d654b5f2cd6a80c3588b514ee91c802736f594c340397b316a97e9c70b0609955b6c3ee0" +
+				"6f4760d9377f0797a0411a244db395bb8b711ef79fbcb5589226174029be79a72dcd6f4ca566b7b1b9a27e43b5c02a9a" +
+				"579d60bdda183398d66d76e0e8eceb1af2f27633589d043bcdc041683b31f7f1",
+		},
+		{
+			RSABSSA_SHA384_PSS_DETERMINISTIC,
+			"",
+			"051722b35f458781397c3a671a7d3bd3096503940e4c4f1aaa269d60300ce449555cd7340100df9d46944c5356825abf",
+			"80682c48982407b489d53d1261b19ec8627d02b8cda5336750b8cee332ae260de57b02d72609c1e0e9f28e2040fc65b6" +
+				"f02d56dbd6aa9af8fde656f70495dfb723ba01173d4707a12fddac628ca29f3e32340bd8f7ddb557cf819f6b01e445ad" +
+				"96f874ba235584ee71f6581f62d4f43bf03f910f6510deb85e8ef06c7f09d9794a008be7ff2529f0ebb69decef646387" +
+				"dc767b74939265fec0223aa6d84d2a8a1cc912d5ca25b4e144ab8f6ba054b54910176d5737a2cff011da431bd5f2a0d2" +
+				"d66b9e70b39f4b050e45c0d9c16f02deda9ddf2d00f3e4b01037d7029cd49c2d46a8e1fc2c0c17520af1f4b5e25ba396" +
+				"afc4cd60c494a4c426448b35b49635b337cfb08e7c22a39b256dd032c00adddafb51a627f99a0e1704170ac1f1912e49" +
+				"d9db10ec04c19c58f420212973e0cb329524223a6aa56c7937c5dffdb5d966b6cd4cbc26f3201dd25c80960a1a111b32" +
+				"947bb78973d269fac7f5186530930ed19f68507540eed9e1bab8b00f00d8ca09b3f099aae46180e04e3584bd7ca054df" +
+				"18a1504b89d1d1675d0966c4ae1407be325cdf623cf13ff13e4a28b594d59e3eadbadf6136eee7a59d6a444c9eb4e219" +
+				"8e8a974f27a39eb63af2c9af3870488b8adaad444674f512133ad80b9220e09158521614f1faadfe8505ef57b7df6813" +
+				"048603f0dd04f4280177a11380fbfc861dbcbd7418d62155248dad5fdec0991f",
+			"10c166c6a711e81c46f45b18e5873cc4f494f003180dd7f115585d871a28930259654fe28a54dab319cc5011204c8373" +
+				"b50a57b0fdc7a678bd74c523259dfe4fd5ea9f52f170e19dfa332930ad1609fc8a00902d725cfe50685c95e5b2968c9a" +
+				"2828a21207fcf393d15f849769e2af34ac4259d91dfd98c3a707c509e1af55647efaa31290ddf48e0133b798562af5ea" +
+				"bd327270ac2fb6c594734ce339a14ea4fe1b9a2f81c0bc230ca523bda17ff42a377266bc2778a274c0ae5ec5a8cbbe36" +
+				"4fcf0d2403f7ee178d77ff28b67a20c7ceec009182dbcaa9bc99b51ebbf13b7d542be337172c6474f2cd3561219fe0df" +
+				"a3fb207cff89632091ab841cf38d8aa88af6891539f263adb8eac6402c41b6ebd72984e43666e537f5f5fe27b2b5aa11" +
+				"4957e9a580730308a5f5a9c63a1eb599f093ab401d0c6003a451931b6d124180305705845060ebba6b0036154fcef3e5" +
+				"e9f9e4b87e8f084542fd1dd67e7782a5585150181c01eb6d90cb95883837384a5b91dbb606f266059ecc51b5acbaa280" +
+				"e45cfd2eec8cc1cdb1b7211c8e14805ba683f9b78824b2eb005bc8a7d7179a36c152cb87c8219e5569bba911bb32a1b9" +
+				"23ca83de0e03fb10fba75d85c55907dda5a2606bf918b056c3808ba496a4d95532212040a5f44f37e1097f26dc27b98a" +
+				"51837daa78f23e532156296b64352669c94a8a855acf30533d8e0594ace7c442",
+			"364f6a40dbfbc3bbb257943337eeff791a0f290898a6791283bba581d9eac90a6376a837241f5f73a78a5c6746e1306b" +
+				"a3adab6067c32ff69115734ce014d354e2f259d4cbfb890244fd451a497fe6ecf9aa90d19a2d441162f7eaa7ce3fc4e8" +
+				"9fd4e76b7ae585be2a2c0fd6fb246b8ac8d58bcb585634e30c9168a434786fe5e0b74bfe8187b47ac091aa571ffea0a8" +
+				"64cb906d0e28c77a00e8cd8f6aba4317a8cc7bf32ce566bd1ef80c64de041728abe087bee6cadd0b7062bde5ceef308a" +
+				"23bd1ccc154fd0c3a26110df6193464fc0d24ee189aea8979d722170ba945fdcce9b1b4b63349980f3a92dc2e5418c54" +
+				"d38a862916926b3f9ca270a8cf40dfb9772bfbdd9a3e0e0892369c18249211ba857f35963d0e05d8da98f1aa0c6bba58" +
+				"f47487b8f663e395091275f82941830b050b260e4767ce2fa903e75ff8970c98bfb3a08d6db91ab1746c86420ee2e909" +
+				"bf681cac173697135983c3594b2def673736220452fde4ddec867d40ff42dd3da36c84e3e52508b891a00f50b4f62d11" +
+				"2edb3b6b6cc3dbd546ba10f36b03f06c0d82aeec3b25e127af545fac28e1613a0517a6095ad18a98ab79f68801e05c17" +
+				"5e15bae21f821e80c80ab4fdec6fb34ca315e194502b8f3dcf7892b511aee45060e3994cd15e003861bc7220a2babd7b" +
+				"40eda03382548a34a7110f9b1779bf3ef6011361611e6bc5c0dc851e1509de1a",
+			"6fef8bf9bc182cd8cf7ce45c7dcf0e6f3e518ae48f06f3c670c649ac737a8b8119a34d51641785be151a697ed7825fdf" +
+				"ece82865123445eab03eb4bb91cecf4d6951738495f8481151b62de869658573df4e50a95c17c31b52e154ae26a04067" +
+				"d5ecdc1592c287550bb982a5bb9c30fd53a768cee6baabb3d483e9f1e2da954c7f4cf492fe3944d2fe456c1ecaf08403" +
+				"69e33fb4010e6b44bb1d721840513524d8e9a3519f40d1b81ae34fb7a31ee6b7ed641cb16c2ac999004c2191de020145" +
+				"7523f5a4700dd649267d9286f5c1d193f1454c9f868a57816bf5ff76c838a2eeb616a3fc9976f65d4371deecfbab2936" +
+				"2caebdff69c635fe5a2113da4d4d8c24f0b16a0584fa05e80e607c5d9a2f765f1f069f8d4da21f27c2a3b5c984b4ab24" +
+				"899bef46c6d9323df4862fe51ce300fca40fb539c3bb7fe2dcc9409e425f2d3b95e70e9c49c5feb6ecc9d43442c33d50" +
+				"003ee936845892fb8be475647da9a080f5bc7f8a716590b3745c2209fe05b17992830ce15f32c7b22cde755c8a2fe50b" +
+				"d814a0434130b807dc1b7218d4e85342d70695a5d7f29306f25623ad1e8aa08ef71b54b8ee447b5f64e73d09bdd6c3b7" +
+				"ca224058d7c67cc7551e9241688ada12d859cb7646fbd3ed8b34312f3b49d69802f0eaa11bc4211c2f7a29cd5c01ed01" +
+				"a39001c5856fab36228f5ee2f2e1110811872fe7c865c42ed59029c706195d52",
+		},
+		{
+			RSABSSA_SHA384_PSSZERO_DETERMINISTIC,
+			"",
+			"",
+			"55f2053e9a4309ac61ac4da7f3a314e626f362e95f30337962d12f08b343165c8dea34d7812dc2dcb227cfa8de49bca5" +
+				"7880ac55f6d77b37ed83a32eb33656ddf0cde29761aef9f86bd758280b3403a63b466831cba4c97e17e9a11e4139f9d8" +
+				"4e5912b017eafbafdbb3ae59a1424feae6914eb1bf20922c6db5da8a538752b3b662ae15cae7beac9a0362b8836001c5" +
+				"7b0c5167dceb9a66e6ab6a90e9898646b4274c3662e4316926c4da7caf5aeff611934b70581280ec68fb2ce04c5681ef" +
+				"95b086b7289afae8ecd669325659791853a9f4c0b784f6f60b212c3b39754d5539e3671d7930d1272e82b3853b6583a8" +
+				"3d9ff70c00ce1938c05eccee531cb075564059b2749e84b45dff7d179c69c86c5d1870aeffd6281d099838a3a988ff9e" +
+				"2684f6cc896b5326275309187d9e3558163131e4d247c2ec8317a2c09f8079d32db8241c869bc5f773722ed8e68bfa5c" +
+				"518d20b955abf02103fce1a025149b14670fdfc8a3f0089516db047f86b9be626ff44989d6fcc162c9570da5b862b473" +
+				"04eca2aceba4dedd6a672458aae779004fe116009600a6a52eb6161a3d09fda09963b56f2870a150df7183bfa03ce735" +
+				"513e637631fb4f980657a8cdb953b2156594607f8ebf7de6999626197072afd7ff60a5d2f782dabe026e0f298df141b8" +
+				"a276aaf7202d959088d7721786b04c79e45c807eb46fcf3a94031ef351aff644",
+			"0c86f078fe8fd2ea6b4e120d3fef7555701a7c6b7bd5606a7fb2ef2769d119f2639477a7904984d67f0ecf419059aac5" +
+				"8041977871d8da253a1aee14cde49cfb919f502f4d79d56d473a95f450982ad83398c1f3dd3a3342a18df9e81447998e" +
+				"ae6c7f9de94148a30de0846fc2402b17b2dfe233c450ba41f141ec14b27bf4e7d79a5c0fa23ad64c2d2fa33691a3048d" +
+				"835f7e477ecba458e4d58f8dbbcfec2a484e1442ab4b266cfc610fec95f6258ef137590254931dea30f58e96a64cef7a" +
+				"ca013cb037259d4dec8a2298d3e2ce96c75a10f39dcdfe7e90eba200c73fc3f5fbbdc4d50d33990559504d0ddb4fe504" +
+				"07fc21321128f72866c780d1412f20d4788ad0ebc2077dca4ae87108e416c3510609867196f4fbb69ff6c3a4c0249e3d" +
+				"6bcf157636666a0e17d8dba9034d9875e40bbff075b0a936acd75baf15179042959d6b27f8e233b60db93a2abce81f47" +
+				"e259f76b5a68d58c21fd8ccd7e102fc9292ec5a1bad8618a94f09ca6a58b1c5c7062fb17bd62035d898b76ead5f52a98" +
+				"69d5b6fbbbf5cd07bc3c35adbff4f03949fe32b455cd5b3de07859d65045b72fb1f4a0ab5c80a27a60b57ebd9e0b1737" +
+				"78d3be592e74cdc6a9ffa147cbb021a87b9a525bc9135114d4daacf0b111773551474ea98493ed8562dac1c9e6398ada" +
+				"60573ff550a01aa4468fd493fb69b3a98ab3790fc7f71ef5dfa3f1979ebe35af",
+			"5ca77254ce107e6e6eedcf8ca03e08d4e92eeb0f4f08b2a2e7fb69da2f5db95f2167ce58a861e45a5cac1bf7d3df3edd" +
+				"64a2802bb5c16ceb62b2f5a0355c0d0f6d8270b658fa26e86afc18a88e91b0ec07e813d50ed4fb20376bf8470179a3a9" +
+				"7d5a29f9f9fe931d6bff233c45d62cd91cdb9a692cda309fad962fd9f7f19f89cc48bc75f9b521aeca21921330c7e91f" +
+				"f7ff2af6e62fe3112f7ec675e866c5961556a1796f2fd4707dd9fcde702caf003b5acfde1cd97bc5d2a63d126ac0587b" +
+				"f8ed6a3064d20dbdef9e207423e678f36e516e4c2696cc74f0a74be4c3ddaaf6cdbc95c9d58d930f0f4e00dfa2bf5d0a" +
+				"333964ec03226073030b9b78210d3160ec2722abf3c01efa1636a28c6c5ac9d14913537322ee42d26ab26518ec2af032" +
+				"02ea0e190a4790b7a8951be98313000c62d1fe0ea05647c451348f97ef5ced6c6e83303aececcc508fcc8f18f7751e05" +
+				"0f9f7a562f45b0d03159486d067ab4b3df1b0f270d009436f0305640929a2b61cfeef24a2e39a9a622c9d9d9e2c99245" +
+				"ea415243f472b226e068ebba7624ccf012b86b21d80cb2e3b718224b2f7b638a16b7665a1a493b014dd3d0f7b97ca290" +
+				"665b1f0972bc4a7d4051e843182771b6258d9d63f919fde109f8487f443ea54518c053acfbf7c0cfe60435b6966d42c0" +
+				"34cf6ad3be2281fa2bf1a90f1d2cba55643e9ae37065a7534f53402e6f4c2a3a",
+			"4454b6983ff01cb28545329f394936efa42ed231e15efbc025fdaca00277acf0c8e00e3d8b0ecebd35b057b8ebfc14e1" +
+				"a7097368a4abd20b555894ccef3d1b9528c6bcbda6b95376bef230d0f1feff0c1064c62c60a7ae7431d1fdfa43a81eed" +
+				"9235e363e1ffa0b2797aba6aad6082fcd285e14fc8b71de6b9c87cb4059c7dc1e96ae1e63795a1e9af86b9073d1d848a" +
+				"ef3eca8a03421bcd116572456b53bcfd4dabb0a9691f1fabda3ed0ce357aee2cfee5b1a0eb226f69716d4e011d96eede" +
+				"5e38a9acb531a64336a0d5b0bae3ab085b658692579a376740ff6ce69e89b06f360520b864e33d82d029c808248a19e1" +
+				"8e31f0ecd16fac5cd4870f8d3ebc1c32c718124152dc905672ab0b7af48bf7d1ac1ff7b9c742549c91275ab105458ae3" +
+				"7621757add83482bbcf779e777bbd61126e93686635d4766aedf5103cf7978f3856ccac9e28d21a850dbb03c81112861" +
+				"6d315d717be1c2b6254f8509acae862042c034530329ce15ca2e2f6b1f5fd59272746e3918c748c0eb810bf76884fa10" +
+				"fcf749326bbfaa5ba285a0186a22e4f628dbf178d3bb5dc7e165ca73f6a55ecc14c4f5a26c4693ce5da032264cbec319" +
+				"b12ddb9787d0efa4fcf1e5ccee35ad85ecd453182df9ed735893f830b570faae8be0f6fe2e571a4e0d927cba4debd368" +
+				"d3b4fca33ec6251897a137cf75474a32ac8256df5e5ffa518b88b43fb6f63a24",
+		},
+	}
+)
+
+func blindTestKey(t *testing.T) *PrivateKey {
+	hexInt := func(s string) *big.Int {
+		v, ok := new(big.Int).SetString(s, 16)
+		if !ok {
+			t.Fatalf("invalid hex %s", s)
+		}
+		return v
+	}
+	p, q := hexInt(blindTestP), hexInt(blindTestQ)
+	key := &PrivateKey{
+		PublicKey: PublicKey{N: new(big.Int).Mul(p, q), E: big.NewInt(DefaultE)},
+		P:         p,
+		Q:         q,
+		Phi:       new(big.Int).Mul(new(big.Int).Sub(p, one), new(big.Int).Sub(q, one)),
+		D:         hexInt(blindTestD),
+	}
+	if err := key.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	key.Precompute()
+	return key
+}
+
+func TestBlindSignVectors(t *testing.T) {
+	key := blindTestKey(t)
+	msg, _ := hex.DecodeString(blindTestMsg)
+	for _, v := range blindVectors {
+		prefix, _ := hex.DecodeString(v.msgPrefix)
+		salt, _ := hex.DecodeString(v.salt)
+		inv, _ := new(big.Int).SetString(v.inv, 16)
+		preparedMsg := append(prefix, msg...)
+
+		r := new(big.Int).ModInverse(inv, key.N)
+		blinded, err := v.variant.blind(&key.PublicKey, preparedMsg, salt, r)
+		if err != nil {
+			t.Fatalf("%s: %v", v.variant.Name, err)
+		}
+		if hex.EncodeToString(blinded) != v.blindedMsg {
+			t.Errorf("%s: blinded message mismatch", v.variant.Name)
+		}
+		blindSig, err := BlindSign(key, blinded)
+		if err != nil {
+			t.Fatalf("%s: %v", v.variant.Name, err)
+		}
+		if hex.EncodeToString(blindSig) != v.blindSig {
+			t.Errorf("%s: blind signature mismatch", v.variant.Name)
+		}
+		sig, err := v.variant.Finalize(&key.PublicKey, preparedMsg, blindSig, inv)
+		if err != nil {
+			t.Fatalf("%s: %v", v.variant.Name, err)
+		}
+		if hex.EncodeToString(sig) != v.sig {
+			t.Errorf("%s: signature mismatch", v.variant.Name)
+		}
+	}
+}
+
+func TestBlindSign(t *testing.T) {
+	key, err := GenerateKey(2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := []byte("rate-limit token")
+	variants := []*BlindSignVariant{
+		RSABSSA_SHA384_PSS_RANDOMIZED, RSABSSA_SHA384_PSSZERO_RANDOMIZED,
+		RSABSSA_SHA384_PSS_DETERMINISTIC, RSABSSA_SHA384_PSSZERO_DETERMINISTIC,
+	}
+	for _, v := range variants {
+		// client
+		msg, err := v.Prepare(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		blinded, inv, err := v.Blind(&key.PublicKey, msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// server
+		blindSig, err := BlindSign(key, blinded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// client
+		sig, err := v.Finalize(&key.PublicKey, msg, blindSig, inv)
+		if err != nil {
+			t.Fatalf("%s: %v", v.Name, err)
+		}
+		// anyone, the server cannot link the signature to the blinded message
+		if ok, err := v.Verify(&key.PublicKey, msg, sig); err != nil || !ok {
+			t.Errorf("%s: verification failed: %v", v.Name, err)
+		}
+		if bytes.Equal(sig, blindSig) {
+			t.Errorf("%s: signature equals blind signature", v.Name)
+		}
+		if ok, _ := v.Verify(&key.PublicKey, []byte("other token"), sig); ok {
+			t.Errorf("%s: signature of another message verified", v.Name)
+		}
+		if v.Randomized == bytes.Equal(msg, token) {
+			t.Errorf("%s: unexpected prepared message", v.Name)
+		}
+
+		// blind signature of another blinding does not finalize
+		blinded2, _, _ := v.Blind(&key.PublicKey, msg)
+		blindSig2, _ := BlindSign(key, blinded2)
+		if _, err := v.Finalize(&key.PublicKey, msg, blindSig2, inv); err != ErrInvalidBlindSign {
+			t.Errorf("%s: wrong blind signature finalized", v.Name)
+		}
+	}
+
+	// PSS signatures do not verify as PSSZERO and vice versa
+	msg, _ := RSABSSA_SHA384_PSS_DETERMINISTIC.Prepare(token)
+	blinded, inv, _ := RSABSSA_SHA384_PSS_DETERMINISTIC.Blind(&key.PublicKey, msg)
+	blindSig, _ := BlindSign(key, blinded)
+	sig, _ := RSABSSA_SHA384_PSS_DETERMINISTIC.Finalize(&key.PublicKey, msg, blindSig, inv)
+	if ok, _ := RSABSSA_SHA384_PSSZERO_DETERMINISTIC.Verify(&key.PublicKey, msg, sig); ok {
+		t.Errorf("PSS signature verified as PSSZERO")
+	}
+	if _, err := BlindSign(key, make([]byte, 10)); err != ErrInvalidBlind {
+		t.Errorf("short blinded message accepted")
+	}
+}
+
+func TestThreshold(t *testing.T) {
+	const threshold, parties = 3, 5
+	pub, shares, err := GenerateThresholdKey(thresholdSecbit, threshold, parties)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("test threshold rsa")
+	// deterministic PSS encoding so that every party signs the same representative
+	x, err := EncodePSS(sha256.New, &pub.PublicKey, msg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sigShares := make([]*SignatureShare, parties)
+	for i, share := range shares {
+		sigShares[i], err = share.SignShare(pub, x)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !pub.VerifyShare(x, sigShares[i]) {
+			t.Errorf("share %d: valid signature share rejected", share.Index)
+		}
+	}
+
+	for _, subset := range [][]int{{0, 1, 2}, {4, 2, 0}, {1, 3, 4}} {
+		selected := make([]*SignatureShare, len(subset))
+		for i, j := range subset {
+			selected[i] = sigShares[j]
+		}
+		y, err := pub.CombineShares(x, selected)
+		if err != nil {
+			t.Fatalf("%v: %v", subset, err)
+		}
+		if ok, err := RSAVerify(x, y, &pub.PublicKey); err != nil || !ok {
+			t.Errorf("%v: RSAVerify failed: %v", subset, err)
+		}
+		sig := y.FillBytes(make([]byte, byteLen(pub.N)))
+		if ok, err := VerifyPSS(sha256.New, &pub.PublicKey, msg, sig); err != nil || !ok {
+			t.Errorf("%v: VerifyPSS failed: %v", subset, err)
+		}
+	}
+
+	// forged shares and proofs are detected
+	forged := *sigShares[0]
+	forged.X = new(big.Int).Add(forged.X, one)
+	if pub.VerifyShare(x, &forged) {
+		t.Errorf("forged signature share accepted")
+	}
+	if pub.VerifyShare(new(big.Int).Add(x, one), sigShares[0]) {
+		t.Errorf("signature share of another message accepted")
+	}
+	moved := *sigShares[0]
+	moved.Index = 2
+	if pub.VerifyShare(x, &moved) {
+		t.Errorf("signature share accepted for another party")
+	}
+	if _, err := pub.CombineShares(x, []*SignatureShare{&forged, sigShares[1], sigShares[2]}); err != ErrInvalidShare {
+		t.Errorf("combination with forged share not detected")
+	}
+	if _, err := pub.CombineShares(x, sigShares[:threshold-1]); err != ErrNotEnough {
+		t.Errorf("not enough shares accepted")
+	}
+	if _, err := pub.CombineShares(x, []*SignatureShare{sigShares[0], sigShares[0], sigShares[1]}); err != ErrInvalidShare {
+		t.Errorf("duplicate shares accepted")
+	}
+	for _, index := range []int{0, parties + 1} {
+		if _, err := (&KeyShare{Index: index, S: shares[0].S}).SignShare(pub, x); err != ErrInvalidShare {
+			t.Errorf("index %d: expect ErrInvalidShare, got %v", index, err)
+		}
+	}
+}
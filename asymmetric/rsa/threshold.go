@@ -0,0 +1,251 @@
+package rsa
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/hongyanwang/crypto-lab/common/modular"
+)
+
+var (
+	ErrInvalidShare = errors.New("rsa: invalid signature share")
+	ErrNotEnough    = errors.New("rsa: not enough signature shares")
+)
+
+// ThresholdPublicKey public key of Shoup's threshold RSA, any Threshold of Parties can sign
+// signatures are standard RSA signatures under PublicKey, V and VKs verify signature shares
+type ThresholdPublicKey struct {
+	PublicKey
+	Threshold int
+	Parties   int
+	V         *big.Int   // random square in Z_N*
+	VKs       []*big.Int // verification keys, VKs[i-1] = V^s_i (mod N) of party i
+}
+
+// KeyShare share s_i = f(i) of D held by party Index, f is a random polynomial over Z_m with f(0) = D
+type KeyShare struct {
+	Index int
+	S     *big.Int
+}
+
+// SignatureShare signature share x_i = x^(2*Delta*s_i) with proof of correctness (C, Z)
+type SignatureShare struct {
+	Index int
+	X     *big.Int
+	C, Z  *big.Int
+}
+
+// GenerateThresholdKey trusted dealer generates a secbit-bit key with safe primes P = 2p'+1, Q = 2q'+1 and
+// splits D among partyNum parties, the dealer must erase P, Q and D afterwards
+// Shoup, Practical Threshold Signatures, EUROCRYPT 2000
+func GenerateThresholdKey(secbit, threshold, partyNum int) (*ThresholdPublicKey, []*KeyShare, error) {
+	if threshold < 1 || threshold > partyNum {
+		return nil, nil, fmt.Errorf("invalid threshold %d of %d parties", threshold, partyNum)
+	}
+	// E must be a prime larger than the number of parties
+	if partyNum >= DefaultE {
+		return nil, nil, fmt.Errorf("too many parties: %d", partyNum)
+	}
+
+	// 1. N = PQ with safe primes, m = p'q'
+	e := big.NewInt(DefaultE)
+	var p, q, n *big.Int
+	for {
+		var err error
+		if p, err = modular.GenerateSafePrime(secbit / 2); err != nil {
+			return nil, nil, err
+		}
+		if q, err = modular.GenerateSafePrime(secbit - secbit/2); err != nil {
+			return nil, nil, err
+		}
+		n = new(big.Int).Mul(p, q)
+		if p.Cmp(q) != 0 && n.BitLen() == secbit {
+			break
+		}
+	}
+	pp := new(big.Int).Rsh(p, 1)
+	qq := new(big.Int).Rsh(q, 1)
+	m := new(big.Int).Mul(pp, qq)
+	d := new(big.Int).ModInverse(e, m)
+	if d == nil {
+		return nil, nil, fmt.Errorf("E is not invertible")
+	}
+
+	// 2. random polynomial f of degree threshold-1 over Z_m with f(0) = D
+	coefficients := make([]*big.Int, threshold)
+	coefficients[0] = d
+	for i := 1; i < threshold; i++ {
+		a, err := rand.Int(rand.Reader, m)
+		if err != nil {
+			return nil, nil, err
+		}
+		coefficients[i] = a
+	}
+
+	// 3. verification base V = r^2 generates QR_N with overwhelming probability
+	r, err := modular.RandomUnit(n)
+	if err != nil {
+		return nil, nil, err
+	}
+	pub := &ThresholdPublicKey{
+		PublicKey: PublicKey{N: n, E: e},
+		Threshold: threshold,
+		Parties:   partyNum,
+		V:         r.Exp(r, big.NewInt(2), n),
+		VKs:       make([]*big.Int, partyNum),
+	}
+
+	// 4. s_i = f(i) mod m, v_i = V^s_i
+	shares := make([]*KeyShare, partyNum)
+	for i := 1; i <= partyNum; i++ {
+		x := big.NewInt(int64(i))
+		s := new(big.Int)
+		for j := threshold - 1; j >= 0; j-- {
+			s.Mul(s, x)
+			s.Add(s, coefficients[j])
+			s.Mod(s, m)
+		}
+		shares[i-1] = &KeyShare{Index: i, S: s}
+		pub.VKs[i-1] = new(big.Int).Exp(pub.V, s, n)
+	}
+	return pub, shares, nil
+}
+
+// SignShare party signs message representative x, e.g. the output of EncodePSS
+// x_i = x^(2*Delta*s_i), proof that log_V(v_i) = log_x~(x_i^2) with x~ = x^(4*Delta)
+func (share *KeyShare) SignShare(pub *ThresholdPublicKey, x *big.Int) (*SignatureShare, error) {
+	if share.Index < 1 || share.Index > pub.Parties {
+		return nil, ErrInvalidShare
+	}
+	if x.Sign() <= 0 || x.Cmp(pub.N) >= 0 {
+		return nil, errors.New("message must be in [1, N)")
+	}
+	delta := modular.Factorial(pub.Parties)
+	n := pub.N
+
+	exp := new(big.Int).Lsh(delta, 1)
+	exp.Mul(exp, share.S)
+	xi := new(big.Int).Exp(x, exp, n)
+
+	// r has |N| + 2*L1 bits to hide s_i * c
+	r, err := rand.Int(rand.Reader, new(big.Int).Lsh(one, uint(n.BitLen()+2*proofHashBits)))
+	if err != nil {
+		return nil, err
+	}
+	xTilde := new(big.Int).Exp(x, new(big.Int).Lsh(delta, 2), n)
+	vPrime := new(big.Int).Exp(pub.V, r, n)
+	xPrime := new(big.Int).Exp(xTilde, r, n)
+	xi2 := new(big.Int).Exp(xi, big.NewInt(2), n)
+	c := proofChallenge(pub.V, xTilde, pub.VKs[share.Index-1], xi2, vPrime, xPrime)
+
+	z := new(big.Int).Mul(share.S, c)
+	z.Add(z, r)
+	return &SignatureShare{Index: share.Index, X: xi, C: c, Z: z}, nil
+}
+
+// VerifyShare verify proof of signature share on message representative x
+// c = H(V, x~, v_i, x_i^2, V^z * v_i^-c, x~^z * x_i^-2c)
+func (pub *ThresholdPublicKey) VerifyShare(x *big.Int, ss *SignatureShare) bool {
+	if ss == nil || ss.Index < 1 || ss.Index > pub.Parties || ss.X == nil || ss.C == nil || ss.Z == nil {
+		return false
+	}
+	n := pub.N
+	vi := pub.VKs[ss.Index-1]
+	xTilde := new(big.Int).Exp(x, new(big.Int).Lsh(modular.Factorial(pub.Parties), 2), n)
+	xi2 := new(big.Int).Exp(ss.X, big.NewInt(2), n)
+
+	viInv := new(big.Int).ModInverse(vi, n)
+	xi2Inv := new(big.Int).ModInverse(xi2, n)
+	if viInv == nil || xi2Inv == nil {
+		return false
+	}
+	vPrime := new(big.Int).Exp(pub.V, ss.Z, n)
+	vPrime.Mul(vPrime, new(big.Int).Exp(viInv, ss.C, n))
+	vPrime.Mod(vPrime, n)
+	xPrime := new(big.Int).Exp(xTilde, ss.Z, n)
+	xPrime.Mul(xPrime, new(big.Int).Exp(xi2Inv, ss.C, n))
+	xPrime.Mod(xPrime, n)
+
+	c := proofChallenge(pub.V, xTilde, vi, xi2, vPrime, xPrime)
+	return c.Cmp(ss.C) == 0
+}
+
+// CombineShares combine Threshold verified signature shares into signature y with y^E = x (mod N)
+// w = mul( x_i^(2*lambda_i) ) = x^(4*Delta^2*D), y = w^a * x^b with a*4*Delta^2 + b*E = 1
+func (pub *ThresholdPublicKey) CombineShares(x *big.Int, shares []*SignatureShare) (*big.Int, error) {
+	if len(shares) < pub.Threshold {
+		return nil, ErrNotEnough
+	}
+	shares = shares[:pub.Threshold]
+	indices := make([]int, len(shares))
+	seen := make(map[int]bool, len(shares))
+	for i, ss := range shares {
+		if ss == nil || ss.Index < 1 || ss.Index > pub.Parties || seen[ss.Index] || !modular.IsUnit(ss.X, pub.N) {
+			return nil, ErrInvalidShare
+		}
+		seen[ss.Index] = true
+		indices[i] = ss.Index
+	}
+
+	if !modular.IsUnit(x, pub.N) {
+		return nil, errors.New("message must be invertible mod N")
+	}
+	n := pub.N
+	delta := modular.Factorial(pub.Parties)
+	w := big.NewInt(1)
+	for i, ss := range shares {
+		lambda := lagrangeDelta(delta, indices, i)
+		lambda.Lsh(lambda, 1)
+		w.Mul(w, modular.ExpInt(ss.X, lambda, n))
+		w.Mod(w, n)
+	}
+
+	ePrime := new(big.Int).Mul(delta, delta)
+	ePrime.Lsh(ePrime, 2)
+	a, b := new(big.Int), new(big.Int)
+	if new(big.Int).GCD(a, b, ePrime, pub.E).Cmp(one) != 0 {
+		return nil, fmt.Errorf("E is not coprime to 4*Delta^2")
+	}
+	y := modular.ExpInt(w, a, n)
+	y.Mul(y, modular.ExpInt(x, b, n))
+	y.Mod(y, n)
+	if new(big.Int).Exp(y, pub.E, n).Cmp(new(big.Int).Mod(x, n)) != 0 {
+		return nil, ErrInvalidShare
+	}
+	return y, nil
+}
+
+// proof hash output size L1 in bits
+const proofHashBits = sha256.Size * 8
+
+// proofChallenge Fiat-Shamir challenge, SHA-256 of length-prefixed values
+func proofChallenge(values ...*big.Int) *big.Int {
+	h := sha256.New()
+	for _, v := range values {
+		b := v.Bytes()
+		var l [4]byte
+		binary.BigEndian.PutUint32(l[:], uint32(len(b)))
+		h.Write(l[:])
+		h.Write(b)
+	}
+	return new(big.Int).SetBytes(h.Sum(nil))
+}
+
+// lagrangeDelta integer coefficient Delta * lambda_(0,j) = Delta * mul( j' / (j' - j) ) for j = indices[i]
+func lagrangeDelta(delta *big.Int, indices []int, i int) *big.Int {
+	num := new(big.Int).Set(delta)
+	den := big.NewInt(1)
+	for k, jj := range indices {
+		if k == i {
+			continue
+		}
+		num.Mul(num, big.NewInt(int64(jj)))
+		den.Mul(den, big.NewInt(int64(jj-indices[i])))
+	}
+	// the division is exact
+	return num.Quo(num, den)
+}
@@ -0,0 +1,300 @@
+package sm2
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/hongyanwang/crypto-lab/hash/sm3"
+)
+
+// SM2 key exchange protocol, GB/T 32918.3
+//
+//	initiator A                                responder B
+//	RA = rA*G              ---- RA ---->
+//	                                           RB = rB*G, V = tB*(PA + x1'*RA)
+//	                                           KB = KDF(xV||yV||ZA||ZB), SB (optional)
+//	                       <--- RB, SB ---
+//	U = tA*(PB + x2'*RB)
+//	KA = KDF(xU||yU||ZA||ZB), check SB, SA (optional)
+//	                       ---- SA ---->
+//	                                           check SA
+
+// key exchange states
+const (
+	stateNew = iota
+	stateSent
+	stateKeyDerived
+	stateConfirmed
+	stateFailed
+)
+
+var (
+	// w = ceil(ceil(log2(n))/2) - 1 = 127
+	twoPowW      = new(big.Int).Lsh(one, 127)
+	twoPowWMinus = new(big.Int).Sub(twoPowW, one)
+
+	ErrConfirmFailed = errors.New("sm2: key confirmation failed")
+)
+
+// KeyExchange state of one party in SM2 key exchange
+type KeyExchange struct {
+	initiator bool
+	state     int
+	klen      int // bit length of the agreed key
+
+	privkey *PrivateKey
+	peerPub *PublicKey
+	za, zb  []byte // ZA of initiator and ZB of responder
+
+	ephemeral *PrivateKey // rA or rB, with RA or RB
+
+	key     []byte // agreed key
+	inner   []byte // sm3(x||ZA||ZB||x1||y1||x2||y2)
+	sharedY []byte // y coordinate of the shared point U or V
+}
+
+// NewInitiator create key exchange state of initiator A
+// id and peerID are the identities of A and B, klen is the bit length of the agreed key
+func NewInitiator(privkey *PrivateKey, peerPubkey *PublicKey, id, peerID []byte, klen int) (*KeyExchange, error) {
+	return newKeyExchange(true, privkey, peerPubkey, id, peerID, klen)
+}
+
+// NewResponder create key exchange state of responder B
+// id and peerID are the identities of B and A, klen is the bit length of the agreed key
+func NewResponder(privkey *PrivateKey, peerPubkey *PublicKey, id, peerID []byte, klen int) (*KeyExchange, error) {
+	return newKeyExchange(false, privkey, peerPubkey, id, peerID, klen)
+}
+
+func newKeyExchange(initiator bool, privkey *PrivateKey, peerPubkey *PublicKey, id, peerID []byte, klen int) (*KeyExchange, error) {
+	if klen <= 0 {
+		return nil, fmt.Errorf("invalid key length: %d", klen)
+	}
+	if err := checkID(id); err != nil {
+		return nil, err
+	}
+	if err := checkID(peerID); err != nil {
+		return nil, err
+	}
+	if !isValidPoint(peerPubkey) {
+		return nil, fmt.Errorf("invalid peer public key")
+	}
+
+	ke := &KeyExchange{
+		initiator: initiator,
+		klen:      klen,
+		privkey:   privkey,
+		peerPub:   peerPubkey,
+	}
+	z := ZA(privkey.PublicKey, id)
+	peerZ := ZA(*peerPubkey, peerID)
+	if initiator {
+		ke.za, ke.zb = z, peerZ
+	} else {
+		ke.za, ke.zb = peerZ, z
+	}
+	return ke, nil
+}
+
+// Init initiator generates ephemeral key pair and outputs RA to responder
+func (ke *KeyExchange) Init() (*PublicKey, error) {
+	if !ke.initiator || ke.state != stateNew {
+		return nil, fmt.Errorf("Init can only be called once by initiator")
+	}
+	r, err := randomScalar()
+	if err != nil {
+		return nil, err
+	}
+	return ke.init(r), nil
+}
+
+// Respond responder receives RA, derives the key, outputs RB and confirmation SB to initiator
+func (ke *KeyExchange) Respond(ra *PublicKey) (*PublicKey, []byte, error) {
+	if ke.initiator || ke.state != stateNew {
+		return nil, nil, fmt.Errorf("Respond can only be called once by responder")
+	}
+	r, err := randomScalar()
+	if err != nil {
+		return nil, nil, err
+	}
+	return ke.respond(r, ra)
+}
+
+// Confirm initiator receives RB and optional SB, derives the key and outputs confirmation SA
+// SB is checked if it is not nil
+func (ke *KeyExchange) Confirm(rb *PublicKey, sb []byte) ([]byte, error) {
+	if !ke.initiator || ke.state != stateSent {
+		return nil, fmt.Errorf("Confirm can only be called by initiator after Init")
+	}
+	if err := ke.derive(rb); err != nil {
+		return nil, err
+	}
+	if sb != nil {
+		s1 := ke.confirmHash(0x02)
+		if subtle.ConstantTimeCompare(s1, sb) != 1 {
+			ke.abort()
+			return nil, ErrConfirmFailed
+		}
+	}
+	ke.state = stateConfirmed
+	return ke.confirmHash(0x03), nil
+}
+
+// Finish responder checks confirmation SA of initiator
+func (ke *KeyExchange) Finish(sa []byte) error {
+	if ke.initiator || ke.state != stateKeyDerived {
+		return fmt.Errorf("Finish can only be called by responder after Respond")
+	}
+	s2 := ke.confirmHash(0x03)
+	if subtle.ConstantTimeCompare(s2, sa) != 1 {
+		ke.abort()
+		return ErrConfirmFailed
+	}
+	ke.state = stateConfirmed
+	return nil
+}
+
+// Key returns the agreed key, nil if key is not derived yet or confirmation failed
+func (ke *KeyExchange) Key() []byte {
+	if ke.state != stateKeyDerived && ke.state != stateConfirmed {
+		return nil
+	}
+	return append([]byte{}, ke.key...)
+}
+
+// abort discard the derived key after confirmation failure
+func (ke *KeyExchange) abort() {
+	for i := range ke.key {
+		ke.key[i] = 0
+	}
+	ke.key = nil
+	ke.state = stateFailed
+}
+
+// init set ephemeral key of initiator
+func (ke *KeyExchange) init(r *big.Int) *PublicKey {
+	ke.ephemeral = ephemeralKey(r)
+	ke.state = stateSent
+	return &PublicKey{
+		X: ke.ephemeral.X,
+		Y: ke.ephemeral.Y,
+	}
+}
+
+// respond set ephemeral key of responder and derive the key
+func (ke *KeyExchange) respond(r *big.Int, ra *PublicKey) (*PublicKey, []byte, error) {
+	ke.ephemeral = ephemeralKey(r)
+	if err := ke.derive(ra); err != nil {
+		return nil, nil, err
+	}
+	rb := &PublicKey{
+		X: ke.ephemeral.X,
+		Y: ke.ephemeral.Y,
+	}
+	return rb, ke.confirmHash(0x02), nil
+}
+
+// derive compute the shared point and the agreed key
+// t = (d + x'*r) mod n, shared point = t*(P_peer + x_peer'*R_peer)
+func (ke *KeyExchange) derive(peerEph *PublicKey) error {
+	if !isValidPoint(peerEph) {
+		return fmt.Errorf("invalid peer ephemeral public key")
+	}
+	curve := P256Sm2()
+	n := sm2P256.N
+
+	t := new(big.Int).Mul(reduceX(ke.ephemeral.X), ke.ephemeral.D)
+	t = t.Add(t, ke.privkey.D)
+	t = t.Mod(t, n)
+
+	px, py := curve.ScalarMult(peerEph.X, peerEph.Y, reduceX(peerEph.X).Bytes())
+	px, py = curve.Add(ke.peerPub.X, ke.peerPub.Y, px, py)
+	// cofactor h is 1
+	x, y := curve.ScalarMult(px, py, t.Bytes())
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return fmt.Errorf("shared point is infinity")
+	}
+
+	xBytes, yBytes := intToBytes(x, coordLen), intToBytes(y, coordLen)
+	z := make([]byte, 0, 2*coordLen+2*sm3.SIZE)
+	z = append(z, xBytes...)
+	z = append(z, yBytes...)
+	z = append(z, ke.za...)
+	z = append(z, ke.zb...)
+	key, err := KDF(ke.klen, z)
+	if err != nil {
+		return err
+	}
+
+	// RA is always (x1,y1), RB is always (x2,y2)
+	ra, rb := &ke.ephemeral.PublicKey, peerEph
+	if !ke.initiator {
+		ra, rb = peerEph, &ke.ephemeral.PublicKey
+	}
+	inner := make([]byte, 0, coordLen+2*sm3.SIZE+4*coordLen)
+	inner = append(inner, xBytes...)
+	inner = append(inner, ke.za...)
+	inner = append(inner, ke.zb...)
+	inner = append(inner, intToBytes(ra.X, coordLen)...)
+	inner = append(inner, intToBytes(ra.Y, coordLen)...)
+	inner = append(inner, intToBytes(rb.X, coordLen)...)
+	inner = append(inner, intToBytes(rb.Y, coordLen)...)
+
+	ke.key = key
+	ke.inner = sm3.SM3(inner)
+	ke.sharedY = yBytes
+	ke.state = stateKeyDerived
+	return nil
+}
+
+// confirmHash sm3(prefix||y||sm3(x||ZA||ZB||x1||y1||x2||y2))
+// prefix 0x02 for SB/S1, 0x03 for SA/S2
+func (ke *KeyExchange) confirmHash(prefix byte) []byte {
+	msg := make([]byte, 0, 1+coordLen+sm3.SIZE)
+	msg = append(msg, prefix)
+	msg = append(msg, ke.sharedY...)
+	msg = append(msg, ke.inner...)
+	return sm3.SM3(msg)
+}
+
+// reduceX x' = 2^w + (x & (2^w-1))
+func reduceX(x *big.Int) *big.Int {
+	ret := new(big.Int).And(x, twoPowWMinus)
+	return ret.Add(ret, twoPowW)
+}
+
+// ephemeralKey compute R = r*G
+func ephemeralKey(r *big.Int) *PrivateKey {
+	x, y := P256Sm2().ScalarBaseMult(r.Bytes())
+	return &PrivateKey{
+		PublicKey: PublicKey{
+			X: x,
+			Y: y,
+		},
+		D: r,
+	}
+}
+
+// randomScalar generate random number in [1, n-1]
+func randomScalar() (*big.Int, error) {
+	n1 := new(big.Int).Sub(P256Sm2().Params().N, one)
+	k, err := rand.Int(rand.Reader, n1)
+	if err != nil {
+		return nil, err
+	}
+	return k.Add(k, one), nil
+}
+
+// isValidPoint check the point is a valid point on curve
+func isValidPoint(p *PublicKey) bool {
+	if p == nil || p.X == nil || p.Y == nil {
+		return false
+	}
+	curve := P256Sm2()
+	if p.X.Sign() < 0 || p.Y.Sign() < 0 || p.X.Cmp(curve.Params().P) >= 0 || p.Y.Cmp(curve.Params().P) >= 0 {
+		return false
+	}
+	return curve.IsOnCurve(p.X, p.Y)
+}
@@ -1,12 +1,27 @@
 package sm2
 
 import (
+	"errors"
 	"math/big"
 )
 
+// ciphertext layouts of SM2 encryption
+const (
+	C1C3C2 = iota // GB/T 32918.4-2016
+	C1C2C3        // legacy layout of the 2010 draft
+)
+
+const (
+	coordLen = 32             // byte length of a coordinate
+	pointLen = 1 + 2*coordLen // byte length of an uncompressed point
+	maxIDLen = 8192           // 8*len(id) must fit in 2 bytes
+)
+
 var (
 	zero = big.NewInt(0)
 	one  = big.NewInt(1)
+
+	errZeroKey = errors.New("derived key is zero")
 )
 
 // PrivateKey SM2 private key
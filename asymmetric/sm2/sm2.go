@@ -3,6 +3,7 @@ package sm2
 import (
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/subtle"
 	"encoding/binary"
 	"fmt"
 	"math/big"
@@ -28,6 +29,9 @@ func GenerateKey() (*PrivateKey, error) {
 
 // SM2Sign SM2 sign
 func SM2Sign(msg []byte, privkey *PrivateKey, id []byte) (Signature, error) {
+	if err := checkID(id); err != nil {
+		return Signature{}, err
+	}
 	curve := P256Sm2()
 	m := ZA(privkey.PublicKey, id)
 	m = append(m, msg...)
@@ -78,6 +82,9 @@ func SM2Verify(msg []byte, sig Signature, pubkey PublicKey, id []byte) (bool, er
 	if sig.S.Cmp(one) == -1 || sig.S.Cmp(sm2P256.N) >= 0 {
 		return false, fmt.Errorf("wrong signature, s should between 1 and N-1")
 	}
+	if err := checkID(id); err != nil {
+		return false, err
+	}
 
 	m := ZA(pubkey, id)
 	m = append(m, msg...)
@@ -101,56 +108,225 @@ func SM2Verify(msg []byte, sig Signature, pubkey PublicKey, id []byte) (bool, er
 	return false, nil
 }
 
-// SM2Encrypt
+// SM2Encrypt SM2 public key encryption, output ciphertext in C1||C3||C2 layout
 func SM2Encrypt(msg []byte, pubkey *PublicKey) ([]byte, error) {
-	return nil, nil
+	return SM2EncryptWithMode(msg, pubkey, C1C3C2)
 }
 
-// SM2Decrypt
+// SM2Decrypt SM2 private key decryption, input ciphertext in C1||C3||C2 layout
 func SM2Decrypt(cipher []byte, privkey *PrivateKey) ([]byte, error) {
-	return nil, nil
+	return SM2DecryptWithMode(cipher, privkey, C1C3C2)
+}
+
+// SM2EncryptWithMode SM2 public key encryption with specified ciphertext layout
+// C1 = k*G, C2 = msg xor KDF(x2||y2, klen), C3 = sm3(x2||msg||y2), where (x2,y2) = k*P
+func SM2EncryptWithMode(msg []byte, pubkey *PublicKey, mode int) ([]byte, error) {
+	if mode != C1C3C2 && mode != C1C2C3 {
+		return nil, fmt.Errorf("unsupported ciphertext mode: %d", mode)
+	}
+	if len(msg) == 0 {
+		return nil, fmt.Errorf("message cannot be empty")
+	}
+	if !isValidPoint(pubkey) {
+		return nil, fmt.Errorf("invalid public key")
+	}
+
+	for {
+		k, err := randomScalar()
+		if err != nil {
+			return nil, err
+		}
+		cipher, err := encrypt(msg, pubkey, k, mode)
+		if err == errZeroKey {
+			continue
+		}
+		return cipher, err
+	}
+}
+
+// SM2DecryptWithMode SM2 private key decryption with specified ciphertext layout
+func SM2DecryptWithMode(cipher []byte, privkey *PrivateKey, mode int) ([]byte, error) {
+	if mode != C1C3C2 && mode != C1C2C3 {
+		return nil, fmt.Errorf("unsupported ciphertext mode: %d", mode)
+	}
+	if len(cipher) <= pointLen+sm3.SIZE {
+		return nil, fmt.Errorf("ciphertext is too short")
+	}
+
+	// 1. parse C1 and check it is on curve, cofactor h is 1 so S=h*C1 is never infinity
+	curve := P256Sm2()
+	x1, y1, err := unmarshalPoint(cipher[:pointLen])
+	if err != nil {
+		return nil, err
+	}
+
+	var c2, c3 []byte
+	if mode == C1C3C2 {
+		c3 = cipher[pointLen : pointLen+sm3.SIZE]
+		c2 = cipher[pointLen+sm3.SIZE:]
+	} else {
+		c2 = cipher[pointLen : len(cipher)-sm3.SIZE]
+		c3 = cipher[len(cipher)-sm3.SIZE:]
+	}
+
+	// 2. (x2,y2) = d*C1, t = KDF(x2||y2, klen)
+	x2, y2 := curve.ScalarMult(x1, y1, privkey.D.Bytes())
+	x2Bytes, y2Bytes := intToBytes(x2, coordLen), intToBytes(y2, coordLen)
+	t, err := KDF(8*len(c2), append(append([]byte{}, x2Bytes...), y2Bytes...))
+	if err != nil {
+		return nil, err
+	}
+	if isAllZero(t) {
+		return nil, fmt.Errorf("decrypt failed: derived key is zero")
+	}
+
+	// 3. msg = C2 xor t, check C3 = sm3(x2||msg||y2)
+	msg := make([]byte, len(c2))
+	for i := range c2 {
+		msg[i] = c2[i] ^ t[i]
+	}
+	u := hashC3(x2Bytes, msg, y2Bytes)
+	if subtle.ConstantTimeCompare(u, c3) != 1 {
+		return nil, fmt.Errorf("decrypt failed: C3 mismatch")
+	}
+	return msg, nil
 }
 
-// ZA prepare for signature
-// sm3(ENTLA, id, a, b, x, y)
+// encrypt SM2 encryption using given random k
+func encrypt(msg []byte, pubkey *PublicKey, k *big.Int, mode int) ([]byte, error) {
+	curve := P256Sm2()
+	// C1 = k*G
+	x1, y1 := curve.ScalarBaseMult(k.Bytes())
+	// (x2,y2) = k*P
+	x2, y2 := curve.ScalarMult(pubkey.X, pubkey.Y, k.Bytes())
+	x2Bytes, y2Bytes := intToBytes(x2, coordLen), intToBytes(y2, coordLen)
+
+	t, err := KDF(8*len(msg), append(append([]byte{}, x2Bytes...), y2Bytes...))
+	if err != nil {
+		return nil, err
+	}
+	if isAllZero(t) {
+		return nil, errZeroKey
+	}
+
+	c1 := marshalPoint(x1, y1)
+	c2 := make([]byte, len(msg))
+	for i := range msg {
+		c2[i] = msg[i] ^ t[i]
+	}
+	c3 := hashC3(x2Bytes, msg, y2Bytes)
+
+	cipher := make([]byte, 0, len(c1)+len(c2)+len(c3))
+	cipher = append(cipher, c1...)
+	if mode == C1C3C2 {
+		cipher = append(cipher, c3...)
+		cipher = append(cipher, c2...)
+	} else {
+		cipher = append(cipher, c2...)
+		cipher = append(cipher, c3...)
+	}
+	return cipher, nil
+}
+
+// hashC3 sm3(x2||msg||y2)
+func hashC3(x2, msg, y2 []byte) []byte {
+	buf := make([]byte, 0, len(x2)+len(msg)+len(y2))
+	buf = append(buf, x2...)
+	buf = append(buf, msg...)
+	buf = append(buf, y2...)
+	return sm3.SM3(buf)
+}
+
+// ZA prepare for signature and key exchange
+// sm3(ENTL, id, a, b, xG, yG, x, y), ENTL is the 2-byte bit length of id
 func ZA(pubkey PublicKey, id []byte) []byte {
-	msg := make([]byte, 4)
-	binary.BigEndian.PutUint32(msg, uint32(len(id)))
+	msg := make([]byte, 2, 2+len(id)+6*coordLen)
+	binary.BigEndian.PutUint16(msg, uint16(8*len(id)))
 	msg = append(msg, id...)
-	msg = append(msg, sm2P256ToBig(&sm2P256.a).Bytes()...)
-	msg = append(msg, sm2P256ToBig(&sm2P256.b).Bytes()...)
-	msg = append(msg, pubkey.X.Bytes()...)
-	msg = append(msg, pubkey.Y.Bytes()...)
+	msg = append(msg, intToBytes(sm2P256ToBig(&sm2P256.a), coordLen)...)
+	msg = append(msg, intToBytes(sm2P256ToBig(&sm2P256.b), coordLen)...)
+	msg = append(msg, intToBytes(sm2P256.Gx, coordLen)...)
+	msg = append(msg, intToBytes(sm2P256.Gy, coordLen)...)
+	msg = append(msg, intToBytes(pubkey.X, coordLen)...)
+	msg = append(msg, intToBytes(pubkey.Y, coordLen)...)
 	return sm3.SM3(msg)
 }
 
-// KDF key derivation function
+// checkID id length in bits must fit in 2 bytes
+func checkID(id []byte) error {
+	if len(id) >= maxIDLen {
+		return fmt.Errorf("id is too long, must be shorter than %d bytes", maxIDLen)
+	}
+	return nil
+}
+
+// KDF key derivation function based on sm3
+// klen is the bit length of derived key, output is leftmost klen bits of
+// sm3(z||ct_1)||sm3(z||ct_2)||..., where ct_i is a 32-bit big-endian counter starting from 1
 func KDF(klen int, z []byte) ([]byte, error) {
+	if klen <= 0 {
+		return nil, fmt.Errorf("invalid key length: %d", klen)
+	}
+	hashBits := 8 * sm3.SIZE
+	totalRound := (klen + hashBits - 1) / hashBits
+	if uint64(totalRound) > 0xffffffff {
+		return nil, fmt.Errorf("key length is too large: %d", klen)
+	}
+
+	ret := make([]byte, 0, totalRound*sm3.SIZE)
+	msg := make([]byte, len(z)+4)
+	copy(msg, z)
 	var ct uint32 = 0x00000001
+	for i := 0; i < totalRound; i++ {
+		binary.BigEndian.PutUint32(msg[len(z):], ct)
+		ret = append(ret, sm3.SM3(msg)...)
+		ct++
+	}
 
-	d := klen / 256
-	var totalRound int
-	if d*256 == klen {
-		totalRound = d
-	} else {
-		totalRound = d + 1
+	// keep leftmost klen bits
+	ret = ret[:(klen+7)/8]
+	if r := klen % 8; r != 0 {
+		ret[len(ret)-1] &= byte(0xff << uint(8-r))
 	}
-	ha := make([][]byte, totalRound)
-	for i := 1; i < totalRound; i++ {
-		var msg []byte
-		binary.BigEndian.PutUint32(msg, ct)
-		msg = append(z, msg...)
-		ha[i-1] = sm3.SM3(msg)
-		ct = ct + 1
+	return ret, nil
+}
+
+// isAllZero check if every byte is zero
+func isAllZero(b []byte) bool {
+	var acc byte
+	for _, v := range b {
+		acc |= v
 	}
+	return acc == 0
+}
+
+// intToBytes convert big int to big-endian bytes with fixed length
+func intToBytes(x *big.Int, length int) []byte {
+	ret := make([]byte, length)
+	xBytes := x.Bytes()
+	copy(ret[length-len(xBytes):], xBytes)
+	return ret
+}
+
+// marshalPoint encode point in uncompressed form 04||x||y
+func marshalPoint(x, y *big.Int) []byte {
+	ret := make([]byte, 0, pointLen)
+	ret = append(ret, 0x04)
+	ret = append(ret, intToBytes(x, coordLen)...)
+	ret = append(ret, intToBytes(y, coordLen)...)
+	return ret
+}
 
-	var ret []byte
-	for _, b := range ha {
-		ret = append(ret, b...)
+// unmarshalPoint decode uncompressed point 04||x||y and check it is on curve
+func unmarshalPoint(data []byte) (*big.Int, *big.Int, error) {
+	if len(data) != pointLen || data[0] != 0x04 {
+		return nil, nil, fmt.Errorf("invalid point encoding")
 	}
-	if totalRound != d {
-		copy(ret[:], ret[:klen])
+	curve := P256Sm2()
+	x := new(big.Int).SetBytes(data[1 : 1+coordLen])
+	y := new(big.Int).SetBytes(data[1+coordLen:])
+	if x.Cmp(sm2P256.P) >= 0 || y.Cmp(sm2P256.P) >= 0 || !curve.IsOnCurve(x, y) {
+		return nil, nil, fmt.Errorf("point is not on curve")
 	}
-
-	return ret, nil
+	return x, y, nil
 }
@@ -1,6 +1,9 @@
 package sm2
 
 import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
 	"testing"
 )
 
@@ -25,3 +28,260 @@ func TestSM2(t *testing.T) {
 		t.Errorf("verification failed")
 	}
 }
+
+// example of GB/T 32918.5 using the recommended curve
+func TestSM2EncryptKAT(t *testing.T) {
+	d, _ := new(big.Int).SetString("3945208F7B2144B13F36E38AC6D39F95889393692860B51A42FB81EF4DF7C5B8", 16)
+	k, _ := new(big.Int).SetString("59276E27D506861A16680F3AD9C02DCCEF3CC1FA3CDBE4CE6D54B80DEAC1BC21", 16)
+	msg := []byte("encryption standard")
+	c1 := "04" +
+		"04EBFC718E8D1798620432268E77FEB6415E2EDE0E073C0F4F640ECD2E149A73" +
+		"E858F9D81E5430A57B36DAAB8F950A3C64E6EE6A63094D99283AFF767E124DF0"
+	c2 := "21886CA989CA9C7D58087307CA93092D651EFA"
+	c3 := "59983C18F809E262923C53AEC295D30383B54E39D609D160AFCB1908D0BD8766"
+
+	privkey := testKey(d)
+	tests := []struct {
+		mode   int
+		expect string
+	}{
+		{C1C3C2, c1 + c3 + c2},
+		{C1C2C3, c1 + c2 + c3},
+	}
+	for _, test := range tests {
+		cipher, err := encrypt(msg, &privkey.PublicKey, k, test.mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect, _ := hex.DecodeString(test.expect)
+		if !bytes.Equal(cipher, expect) {
+			t.Errorf("mode %d: wrong ciphertext %X", test.mode, cipher)
+		}
+		plain, err := SM2DecryptWithMode(expect, privkey, test.mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(plain, msg) {
+			t.Errorf("mode %d: wrong plaintext %s", test.mode, plain)
+		}
+	}
+}
+
+func TestSM2Encrypt(t *testing.T) {
+	privkey, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, size := range []int{1, 31, 32, 33, 100} {
+		msg := bytes.Repeat([]byte{0x5a}, size)
+		for _, mode := range []int{C1C3C2, C1C2C3} {
+			cipher, err := SM2EncryptWithMode(msg, &privkey.PublicKey, mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(cipher) != pointLen+32+size {
+				t.Errorf("wrong ciphertext length %d", len(cipher))
+			}
+			plain, err := SM2DecryptWithMode(cipher, privkey, mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(plain, msg) {
+				t.Errorf("size %d mode %d: decrypt failed", size, mode)
+			}
+
+			// any modification of C2 or C3 must be detected
+			cipher[len(cipher)-1] ^= 1
+			if _, err := SM2DecryptWithMode(cipher, privkey, mode); err == nil {
+				t.Errorf("size %d mode %d: tampered ciphertext accepted", size, mode)
+			}
+		}
+	}
+
+	cipher, err := SM2Encrypt([]byte("test"), &privkey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SM2DecryptWithMode(cipher, privkey, C1C2C3); err == nil {
+		t.Errorf("decrypt with wrong mode should fail")
+	}
+	cipher[1] ^= 1
+	if _, err := SM2Decrypt(cipher, privkey); err == nil {
+		t.Errorf("invalid C1 accepted")
+	}
+}
+
+func TestKDF(t *testing.T) {
+	z := []byte("kdf test")
+	full, err := KDF(8*100, z)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, klen := range []int{1, 7, 8, 255, 256, 257, 512, 800} {
+		k, err := KDF(klen, z)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(k) != (klen+7)/8 {
+			t.Errorf("klen %d: wrong output length %d", klen, len(k))
+		}
+		// output must be a prefix of longer output, with unused bits cleared
+		n := klen / 8
+		if !bytes.Equal(k[:n], full[:n]) {
+			t.Errorf("klen %d: output is not a prefix", klen)
+		}
+		if r := klen % 8; r != 0 && k[n] != full[n]&byte(0xff<<uint(8-r)) {
+			t.Errorf("klen %d: wrong last byte", klen)
+		}
+	}
+	if _, err := KDF(0, z); err == nil {
+		t.Errorf("zero klen should fail")
+	}
+}
+
+func testKey(d *big.Int) *PrivateKey {
+	x, y := P256Sm2().ScalarBaseMult(d.Bytes())
+	return &PrivateKey{
+		PublicKey: PublicKey{
+			X: x,
+			Y: y,
+		},
+		D: d,
+	}
+}
+
+func BenchmarkEncrypt(b *testing.B) {
+	privkey, _ := GenerateKey()
+	msg := []byte("encryption standard")
+	for i := 0; i < b.N; i++ {
+		SM2Encrypt(msg, &privkey.PublicKey)
+	}
+}
+
+func BenchmarkDecrypt(b *testing.B) {
+	privkey, _ := GenerateKey()
+	cipher, _ := SM2Encrypt([]byte("encryption standard"), &privkey.PublicKey)
+	for i := 0; i < b.N; i++ {
+		SM2Decrypt(cipher, privkey)
+	}
+}
+
+// example of GB/T 32918.5 using the recommended curve
+func TestSM2VerifyKAT(t *testing.T) {
+	d, _ := new(big.Int).SetString("3945208F7B2144B13F36E38AC6D39F95889393692860B51A42FB81EF4DF7C5B8", 16)
+	r, _ := new(big.Int).SetString("F5A03B0648D2C4630EEAC513E1BB81A15944DA3827D5B74143AC7EACEEE720B3", 16)
+	s, _ := new(big.Int).SetString("B1B6AA29DF212FD8763182BC0D421CA1BB9038FD1F7F42D4840B69C485BBC1AA", 16)
+	za, _ := hex.DecodeString("B2E14C5C79C6DF5B85F4FE7ED8DB7A262B9DA7E07CCB0EA9F4747B8CCDA8A4F3")
+	privkey := testKey(d)
+	id := []byte("1234567812345678")
+
+	if !bytes.Equal(ZA(privkey.PublicKey, id), za) {
+		t.Errorf("wrong ZA")
+	}
+	v, err := SM2Verify([]byte("message digest"), Signature{R: r, S: s}, privkey.PublicKey, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !v {
+		t.Errorf("verification of standard signature failed")
+	}
+}
+
+func TestKeyExchangeKAT(t *testing.T) {
+	hexToInt := func(s string) *big.Int {
+		i, _ := new(big.Int).SetString(s, 16)
+		return i
+	}
+	ida := []byte("1234567812345678")
+	idb := []byte("1234567812345678")
+	privA := testKey(hexToInt("81EB26E941BB5AF16DF116495F90695272AE2CD63D6C4AE1678418BE48230029"))
+	privB := testKey(hexToInt("785129917D45A9EA5437A59356B82338EAADDA6CEB199088F14AE10DEFA229B5"))
+	rA := hexToInt("D4DE15474DB74D06491C440D305E012400990F3E390C7E87153C12DB2EA60BB3")
+	rB := hexToInt("7E07124814B309489125EAED101113164EBF0F3458C5BD88335C1F9D596243D6")
+	expect, _ := hex.DecodeString("6C89347354DE2484C60B4AB1FDE4C6E5")
+
+	alice, err := NewInitiator(privA, &privB.PublicKey, ida, idb, 128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := NewResponder(privB, &privA.PublicKey, idb, ida, 128)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ra := alice.init(rA)
+	rb, sb, err := bob.respond(rB, ra)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sa, err := alice.Confirm(rb, sb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bob.Finish(sa); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(alice.Key(), expect) || !bytes.Equal(bob.Key(), expect) {
+		t.Errorf("wrong agreed key %X, %X", alice.Key(), bob.Key())
+	}
+}
+
+func TestKeyExchange(t *testing.T) {
+	privA, _ := GenerateKey()
+	privB, _ := GenerateKey()
+	ida, idb := []byte("alice@example.com"), []byte("bob@example.com")
+
+	alice, err := NewInitiator(privA, &privB.PublicKey, ida, idb, 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := NewResponder(privB, &privA.PublicKey, idb, ida, 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ra, err := alice.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rb, sb, err := bob.Respond(ra)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// tampered SB must be rejected
+	eve, _ := NewInitiator(privA, &privB.PublicKey, ida, idb, 256)
+	eve.init(alice.ephemeral.D)
+	badSB := append([]byte{}, sb...)
+	badSB[0] ^= 1
+	if _, err := eve.Confirm(rb, badSB); err != ErrConfirmFailed || eve.Key() != nil {
+		t.Errorf("tampered SB accepted")
+	}
+
+	sa, err := alice.Confirm(rb, sb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(alice.Key(), bob.Key()) || len(alice.Key()) != 32 {
+		t.Errorf("agreed keys differ")
+	}
+	badSA := append([]byte{}, sa...)
+	badSA[0] ^= 1
+	if err := bob.Finish(badSA); err != ErrConfirmFailed || bob.Key() != nil {
+		t.Errorf("tampered SA accepted")
+	}
+
+	// confirmation is optional
+	alice, _ = NewInitiator(privA, &privB.PublicKey, ida, idb, 128)
+	bob, _ = NewResponder(privB, &privA.PublicKey, idb, ida, 128)
+	ra, _ = alice.Init()
+	rb, _, _ = bob.Respond(ra)
+	if _, err := alice.Confirm(rb, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(alice.Key(), bob.Key()) {
+		t.Errorf("agreed keys differ without confirmation")
+	}
+	if _, _, err := bob.Respond(ra); err == nil {
+		t.Errorf("Respond should only be called once")
+	}
+}
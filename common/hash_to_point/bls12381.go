@@ -0,0 +1,396 @@
+package hash_to_point
+
+import (
+	"crypto/sha256"
+	"math/big"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fp"
+)
+
+// BLS12-381 suites of RFC 9380 section 8.8
+//
+//	BLS12381G1_XMD:SHA-256_SSWU_RO_, BLS12381G1_XMD:SHA-256_SSWU_NU_
+//	BLS12381G2_XMD:SHA-256_SSWU_RO_, BLS12381G2_XMD:SHA-256_SSWU_NU_
+//
+// u is mapped to isogenous curve E' by simplified SWU, then mapped to E by the 11-isogeny (G1) or 3-isogeny (G2),
+// and multiplied by h_eff to clear the cofactor.
+// gnark ScalarMultiplication uses GLV endomorphism which is only valid in the prime order subgroup,
+// so the cofactor is cleared by double-and-add here.
+var (
+	blsFp = newPrimeField(fp.Modulus())
+
+	// G1: E': y^2 = x^3 + A'x + B', Z = 11
+	g1SSWU = newSSWUMap(fp.Modulus(),
+		hexInt("144698a3b8e9433d693a02c96d4982b0ea985383ee66a8d8e8981aefd881ac98936f8da0e0f97f5cf428082d584c1d"),
+		hexInt("12e2908d11688030018b12e8753eee3b2016c1f0f24f4070a0b9c14fcef35ef55a23215a316ceaa5d1cc48e98e172be0"),
+		big.NewInt(11))
+	g1HEff = hexInt("d201000000010001")
+
+	// coefficients of 11-isogeny map, RFC 9380 appendix E.2, index i is the coefficient of x^i
+	g1XNum = hexInts(
+		"11a05f2b1e833340b809101dd99815856b303e88a2d7005ff2627b56cdb4e2c85610c2d5f2e62d6eaeac1662734649b7",
+		"17294ed3e943ab2f0588bab22147a81c7c17e75b2f6a8417f565e33c70d1e86b4838f2a6f318c356e834eef1b3cb83bb",
+		"0d54005db97678ec1d1048c5d10a9a1bce032473295983e56878e501ec68e25c958c3e3d2a09729fe0179f9dac9edcb0",
+		"1778e7166fcc6db74e0609d307e55412d7f5e4656a8dbf25f1b33289f1b330835336e25ce3107193c5b388641d9b6861",
+		"0e99726a3199f4436642b4b3e4118e5499db995a1257fb3f086eeb65982fac18985a286f301e77c451154ce9ac8895d9",
+		"1630c3250d7313ff01d1201bf7a74ab5db3cb17dd952799b9ed3ab9097e68f90a0870d2dcae73d19cd13c1c66f652983",
+		"0d6ed6553fe44d296a3726c38ae652bfb11586264f0f8ce19008e218f9c86b2a8da25128c1052ecaddd7f225a139ed84",
+		"17b81e7701abdbe2e8743884d1117e53356de5ab275b4db1a682c62ef0f2753339b7c8f8c8f475af9ccb5618e3f0c88e",
+		"080d3cf1f9a78fc47b90b33563be990dc43b756ce79f5574a2c596c928c5d1de4fa295f296b74e956d71986a8497e317",
+		"169b1f8e1bcfa7c42e0c37515d138f22dd2ecb803a0c5c99676314baf4bb1b7fa3190b2edc0327797f241067be390c9e",
+		"10321da079ce07e272d8ec09d2565b0dfa7dccdde6787f96d50af36003b14866f69b771f8c285decca67df3f1605fb7b",
+		"06e08c248e260e70bd1e962381edee3d31d79d7e22c837bc23c0bf1bc24c6b68c24b1b80b64d391fa9c8ba2e8ba2d229",
+	)
+	g1XDen = hexInts(
+		"08ca8d548cff19ae18b2e62f4bd3fa6f01d5ef4ba35b48ba9c9588617fc8ac62b558d681be343df8993cf9fa40d21b1c",
+		"12561a5deb559c4348b4711298e536367041e8ca0cf0800c0126c2588c48bf5713daa8846cb026e9e5c8276ec82b3bff",
+		"0b2962fe57a3225e8137e629bff2991f6f89416f5a718cd1fca64e00b11aceacd6a3d0967c94fedcfcc239ba5cb83e19",
+		"03425581a58ae2fec83aafef7c40eb545b08243f16b1655154cca8abc28d6fd04976d5243eecf5c4130de8938dc62cd8",
+		"13a8e162022914a80a6f1d5f43e7a07dffdfc759a12062bb8d6b44e833b306da9bd29ba81f35781d539d395b3532a21e",
+		"0e7355f8e4e667b955390f7f0506c6e9395735e9ce9cad4d0a43bcef24b8982f7400d24bc4228f11c02df9a29f6304a5",
+		"0772caacf16936190f3e0c63e0596721570f5799af53a1894e2e073062aede9cea73b3538f0de06cec2574496ee84a3a",
+		"14a7ac2a9d64a8b230b3f5b074cf01996e7f63c21bca68a81996e1cdf9822c580fa5b9489d11e2d311f7d99bbdcc5a5e",
+		"0a10ecf6ada54f825e920b3dafc7a3cce07f8d1d7161366b74100da67f39883503826692abba43704776ec3a79a1d641",
+		"095fc13ab9e92ad4476d6e3eb3a56680f682b4ee96f7d03776df533978f31c1593174e4b4b7865002d6384d168ecdd0a",
+		"01",
+	)
+	g1YNum = hexInts(
+		"090d97c81ba24ee0259d1f094980dcfa11ad138e48a869522b52af6c956543d3cd0c7aee9b3ba3c2be9845719707bb33",
+		"134996a104ee5811d51036d776fb46831223e96c254f383d0f906343eb67ad34d6c56711962fa8bfe097e75a2e41c696",
+		"00cc786baa966e66f4a384c86a3b49942552e2d658a31ce2c344be4b91400da7d26d521628b00523b8dfe240c72de1f6",
+		"01f86376e8981c217898751ad8746757d42aa7b90eeb791c09e4a3ec03251cf9de405aba9ec61deca6355c77b0e5f4cb",
+		"08cc03fdefe0ff135caf4fe2a21529c4195536fbe3ce50b879833fd221351adc2ee7f8dc099040a841b6daecf2e8fedb",
+		"16603fca40634b6a2211e11db8f0a6a074a7d0d4afadb7bd76505c3d3ad5544e203f6326c95a807299b23ab13633a5f0",
+		"04ab0b9bcfac1bbcb2c977d027796b3ce75bb8ca2be184cb5231413c4d634f3747a87ac2460f415ec961f8855fe9d6f2",
+		"0987c8d5333ab86fde9926bd2ca6c674170a05bfe3bdd81ffd038da6c26c842642f64550fedfe935a15e4ca31870fb29",
+		"09fc4018bd96684be88c9e221e4da1bb8f3abd16679dc26c1e8b6e6a1f20cabe69d65201c78607a360370e577bdba587",
+		"0e1bba7a1186bdb5223abde7ada14a23c42a0ca7915af6fe06985e7ed1e4d43b9b3f7055dd4eba6f2bafaaebca731c30",
+		"19713e47937cd1be0dfd0b8f1d43fb93cd2fcbcb6caf493fd1183e416389e61031bf3a5cce3fbafce813711ad011c132",
+		"18b46a908f36f6deb918c143fed2edcc523559b8aaf0c2462e6bfe7f911f643249d9cdf41b44d606ce07c8a4d0074d8e",
+		"0b182cac101b9399d155096004f53f447aa7b12a3426b08ec02710e807b4633f06c851c1919211f20d4c04f00b971ef8",
+		"0245a394ad1eca9b72fc00ae7be315dc757b3b080d4c158013e6632d3c40659cc6cf90ad1c232a6442d9d3f5db980133",
+		"05c129645e44cf1102a159f748c4a3fc5e673d81d7e86568d9ab0f5d396a7ce46ba1049b6579afb7866b1e715475224b",
+		"15e6be4e990f03ce4ea50b3b42df2eb5cb181d8f84965a3957add4fa95af01b2b665027efec01c7704b456be69c8b604",
+	)
+	g1YDen = hexInts(
+		"16112c4c3a9c98b252181140fad0eae9601a6de578980be6eec3232b5be72e7a07f3688ef60c206d01479253b03663c1",
+		"1962d75c2381201e1a0cbd6c43c348b885c84ff731c4d59ca4a10356f453e01f78a4260763529e3532f6102c2e49a03d",
+		"058df3306640da276faaae7d6e8eb15778c4855551ae7f310c35a5dd279cd2eca6757cd636f96f891e2538b53dbf67f2",
+		"16b7d288798e5395f20d23bf89edb4d1d115c5dbddbcd30e123da489e726af41727364f2c28297ada8d26d98445f5416",
+		"0be0e079545f43e4b00cc912f8228ddcc6d19c9f0f69bbb0542eda0fc9dec916a20b15dc0fd2ededda39142311a5001d",
+		"08d9e5297186db2d9fb266eaac783182b70152c65550d881c5ecd87b6f0f5a6449f38db9dfa9cce202c6477faaf9b7ac",
+		"166007c08a99db2fc3ba8734ace9824b5eecfdfa8d0cf8ef5dd365bc400a0051d5fa9c01a58b1fb93d1a1399126a775c",
+		"16a3ef08be3ea7ea03bcddfabba6ff6ee5a4375efa1f4fd7feb34fd206357132b920f5b00801dee460ee415a15812ed9",
+		"1866c8ed336c61231a1be54fd1d74cc4f9fb0ce4c6af5920abc5750c4bf39b4852cfe2f7bb9248836b233d9d55535d4a",
+		"167a55cda70a6e1cea820597d94a84903216f763e13d87bb5308592e7ea7d4fbc7385ea3d529b35e346ef48bb8913f55",
+		"04d2f259eea405bd48f010a01ad2911d9c6dd039bb61a6290e591b36e636a5c871a5c29f4f83060400f8b49cba8f6aa8",
+		"0accbb67481d033ff5852c1e48c50c477f94ff8aefce42d28c0f9a88cea7913516f968986f7ebbea9684b529e2561092",
+		"0ad6b9514c767fe3c3613144b45f1496543346d98adf02267d5ceef9a00d9b8693000763e3b90ac11e99b138573345cc",
+		"02660400eb2e4f3b628bdd0d53cd76f2bf565b94e72927c1cb748df27942480e420517bd8714cc80d1fadc1326ed06f7",
+		"0e0fa1d816ddc03e6b24255e0d7819c171c40f65e273b853324efcd6356caa205ca2f570f13497804415473a1d634b8f",
+		"01",
+	)
+
+	// G2: E': y^2 = x^3 + 240i*x + 1012(1+i), Z = -(2+i)
+	g2SSWU = &sswuMap2{
+		a: fp2{big.NewInt(0), big.NewInt(240)},
+		b: fp2{big.NewInt(1012), big.NewInt(1012)},
+		z: fp2{blsFp.neg(big.NewInt(2)), blsFp.neg(big.NewInt(1))},
+	}
+	g2HEff = hexInt("0bc69f08f2ee75b3584c6a0ea91b352888e2a8e9145ad7689986ff031508ffe1329c2f178731db956d82bf015d1212b02ec0ec69d7477c1ae954cbc06689f6a359894c0adebbf6b4e8020005aaa95551")
+
+	// coefficients of 3-isogeny map, RFC 9380 appendix E.3
+	g2XNum = []fp2{
+		hexFp2("5c759507e8e333ebb5b7a9a47d7ed8532c52d39fd3a042a88b58423c50ae15d5c2638e343d9c71c6238aaaaaaaa97d6",
+			"5c759507e8e333ebb5b7a9a47d7ed8532c52d39fd3a042a88b58423c50ae15d5c2638e343d9c71c6238aaaaaaaa97d6"),
+		hexFp2("00",
+			"11560bf17baa99bc32126fced787c88f984f87adf7ae0c7f9a208c6b4f20a4181472aaa9cb8d555526a9ffffffffc71a"),
+		hexFp2("11560bf17baa99bc32126fced787c88f984f87adf7ae0c7f9a208c6b4f20a4181472aaa9cb8d555526a9ffffffffc71e",
+			"8ab05f8bdd54cde190937e76bc3e447cc27c3d6fbd7063fcd104635a790520c0a395554e5c6aaaa9354ffffffffe38d"),
+		hexFp2("171d6541fa38ccfaed6dea691f5fb614cb14b4e7f4e810aa22d6108f142b85757098e38d0f671c7188e2aaaaaaaa5ed1",
+			"00"),
+	}
+	g2XDen = []fp2{
+		hexFp2("00",
+			"1a0111ea397fe69a4b1ba7b6434bacd764774b84f38512bf6730d2a0f6b0f6241eabfffeb153ffffb9feffffffffaa63"),
+		hexFp2("0c",
+			"1a0111ea397fe69a4b1ba7b6434bacd764774b84f38512bf6730d2a0f6b0f6241eabfffeb153ffffb9feffffffffaa9f"),
+		hexFp2("01", "00"),
+	}
+	g2YNum = []fp2{
+		hexFp2("1530477c7ab4113b59a4c18b076d11930f7da5d4a07f649bf54439d87d27e500fc8c25ebf8c92f6812cfc71c71c6d706",
+			"1530477c7ab4113b59a4c18b076d11930f7da5d4a07f649bf54439d87d27e500fc8c25ebf8c92f6812cfc71c71c6d706"),
+		hexFp2("00",
+			"5c759507e8e333ebb5b7a9a47d7ed8532c52d39fd3a042a88b58423c50ae15d5c2638e343d9c71c6238aaaaaaaa97be"),
+		hexFp2("11560bf17baa99bc32126fced787c88f984f87adf7ae0c7f9a208c6b4f20a4181472aaa9cb8d555526a9ffffffffc71c",
+			"8ab05f8bdd54cde190937e76bc3e447cc27c3d6fbd7063fcd104635a790520c0a395554e5c6aaaa9354ffffffffe38f"),
+		hexFp2("124c9ad43b6cf79bfbf7043de3811ad0761b0f37a1e26286b0e977c69aa274524e79097a56dc4bd9e1b371c71c718b10",
+			"00"),
+	}
+	g2YDen = []fp2{
+		hexFp2("1a0111ea397fe69a4b1ba7b6434bacd764774b84f38512bf6730d2a0f6b0f6241eabfffeb153ffffb9feffffffffa8fb",
+			"1a0111ea397fe69a4b1ba7b6434bacd764774b84f38512bf6730d2a0f6b0f6241eabfffeb153ffffb9feffffffffa8fb"),
+		hexFp2("00",
+			"1a0111ea397fe69a4b1ba7b6434bacd764774b84f38512bf6730d2a0f6b0f6241eabfffeb153ffffb9feffffffffa9d3"),
+		hexFp2("12",
+			"1a0111ea397fe69a4b1ba7b6434bacd764774b84f38512bf6730d2a0f6b0f6241eabfffeb153ffffb9feffffffffaa99"),
+		hexFp2("01", "00"),
+	}
+)
+
+// HashToG1 hash_to_curve of suite BLS12381G1_XMD:SHA-256_SSWU_RO_, output is in G1
+func HashToG1(msg, dst []byte) (bls12381.G1Affine, error) {
+	u, err := hashToField(sha256.New, msg, dst, blsFp.p, 1, 2)
+	if err != nil {
+		return bls12381.G1Affine{}, err
+	}
+	q0, q1 := mapToG1(u[0][0]), mapToG1(u[1][0])
+	var q bls12381.G1Jac
+	q.FromAffine(&q0)
+	q.AddMixed(&q1)
+	return clearCofactorG1(&q), nil
+}
+
+// EncodeToG1 encode_to_curve of suite BLS12381G1_XMD:SHA-256_SSWU_NU_, output is in G1
+func EncodeToG1(msg, dst []byte) (bls12381.G1Affine, error) {
+	u, err := hashToField(sha256.New, msg, dst, blsFp.p, 1, 1)
+	if err != nil {
+		return bls12381.G1Affine{}, err
+	}
+	q0 := mapToG1(u[0][0])
+	var q bls12381.G1Jac
+	q.FromAffine(&q0)
+	return clearCofactorG1(&q), nil
+}
+
+// HashToG2 hash_to_curve of suite BLS12381G2_XMD:SHA-256_SSWU_RO_, output is in G2
+func HashToG2(msg, dst []byte) (bls12381.G2Affine, error) {
+	u, err := hashToField(sha256.New, msg, dst, blsFp.p, 2, 2)
+	if err != nil {
+		return bls12381.G2Affine{}, err
+	}
+	q0, q1 := mapToG2(fp2{u[0][0], u[0][1]}), mapToG2(fp2{u[1][0], u[1][1]})
+	var q bls12381.G2Jac
+	q.FromAffine(&q0)
+	q.AddMixed(&q1)
+	return clearCofactorG2(&q), nil
+}
+
+// EncodeToG2 encode_to_curve of suite BLS12381G2_XMD:SHA-256_SSWU_NU_, output is in G2
+func EncodeToG2(msg, dst []byte) (bls12381.G2Affine, error) {
+	u, err := hashToField(sha256.New, msg, dst, blsFp.p, 2, 1)
+	if err != nil {
+		return bls12381.G2Affine{}, err
+	}
+	q0 := mapToG2(fp2{u[0][0], u[0][1]})
+	var q bls12381.G2Jac
+	q.FromAffine(&q0)
+	return clearCofactorG2(&q), nil
+}
+
+// mapToG1 map to E' and apply 11-isogeny, output is on E but not in G1
+func mapToG1(u *big.Int) bls12381.G1Affine {
+	x, y := g1SSWU.mapToCurve(u)
+
+	var p bls12381.G1Affine
+	xDen, yDen := blsFp.poly(g1XDen, x), blsFp.poly(g1YDen, x)
+	// exceptional case of the isogeny, output the identity
+	if xDen.Sign() == 0 || yDen.Sign() == 0 {
+		return p
+	}
+	xNum, yNum := blsFp.poly(g1XNum, x), blsFp.poly(g1YNum, x)
+	p.X.SetBigInt(blsFp.mul(xNum, blsFp.inv(xDen)))
+	p.Y.SetBigInt(blsFp.mul(y, blsFp.mul(yNum, blsFp.inv(yDen))))
+	return p
+}
+
+// mapToG2 map to E' and apply 3-isogeny, output is on E but not in G2
+func mapToG2(u fp2) bls12381.G2Affine {
+	x, y := g2SSWU.mapToCurve(u)
+
+	var p bls12381.G2Affine
+	xDen, yDen := fp2Poly(g2XDen, x), fp2Poly(g2YDen, x)
+	if xDen.isZero() || yDen.isZero() {
+		return p
+	}
+	px := fp2Mul(fp2Poly(g2XNum, x), fp2Inv(xDen))
+	py := fp2Mul(y, fp2Mul(fp2Poly(g2YNum, x), fp2Inv(yDen)))
+	p.X.A0.SetBigInt(px.a0)
+	p.X.A1.SetBigInt(px.a1)
+	p.Y.A0.SetBigInt(py.a0)
+	p.Y.A1.SetBigInt(py.a1)
+	return p
+}
+
+// clearCofactorG1 h_eff * P
+func clearCofactorG1(p *bls12381.G1Jac) bls12381.G1Affine {
+	var res bls12381.G1Jac
+	res.Set(p)
+	for i := g1HEff.BitLen() - 2; i >= 0; i-- {
+		res.DoubleAssign()
+		if g1HEff.Bit(i) == 1 {
+			res.AddAssign(p)
+		}
+	}
+	var ret bls12381.G1Affine
+	ret.FromJacobian(&res)
+	return ret
+}
+
+// clearCofactorG2 h_eff * P
+func clearCofactorG2(p *bls12381.G2Jac) bls12381.G2Affine {
+	var res bls12381.G2Jac
+	res.Set(p)
+	for i := g2HEff.BitLen() - 2; i >= 0; i-- {
+		res.DoubleAssign()
+		if g2HEff.Bit(i) == 1 {
+			res.AddAssign(p)
+		}
+	}
+	var ret bls12381.G2Affine
+	ret.FromJacobian(&res)
+	return ret
+}
+
+// poly evaluate polynomial with coefficients in ascending order by Horner's method
+func (f *primeField) poly(coeffs []*big.Int, x *big.Int) *big.Int {
+	ret := new(big.Int).Set(coeffs[len(coeffs)-1])
+	for i := len(coeffs) - 2; i >= 0; i-- {
+		ret = f.add(f.mul(ret, x), coeffs[i])
+	}
+	return ret
+}
+
+// fp2 element a0 + a1*i of GF(p^2) = GF(p)[i]/(i^2+1)
+type fp2 struct {
+	a0, a1 *big.Int
+}
+
+func (x fp2) isZero() bool {
+	return x.a0.Sign() == 0 && x.a1.Sign() == 0
+}
+
+func fp2Add(x, y fp2) fp2 {
+	return fp2{blsFp.add(x.a0, y.a0), blsFp.add(x.a1, y.a1)}
+}
+
+func fp2Neg(x fp2) fp2 {
+	return fp2{blsFp.neg(x.a0), blsFp.neg(x.a1)}
+}
+
+func fp2Mul(x, y fp2) fp2 {
+	return fp2{
+		blsFp.sub(blsFp.mul(x.a0, y.a0), blsFp.mul(x.a1, y.a1)),
+		blsFp.add(blsFp.mul(x.a0, y.a1), blsFp.mul(x.a1, y.a0)),
+	}
+}
+
+// fp2Norm a0^2 + a1^2
+func fp2Norm(x fp2) *big.Int {
+	return blsFp.add(blsFp.mul(x.a0, x.a0), blsFp.mul(x.a1, x.a1))
+}
+
+// fp2Inv (a0 - a1*i) / (a0^2 + a1^2), inverse of 0 is 0
+func fp2Inv(x fp2) fp2 {
+	t := blsFp.inv(fp2Norm(x))
+	return fp2{blsFp.mul(x.a0, t), blsFp.neg(blsFp.mul(x.a1, t))}
+}
+
+// fp2IsSquare x is square iff its norm is square in GF(p)
+func fp2IsSquare(x fp2) bool {
+	return blsFp.isSquare(fp2Norm(x))
+}
+
+// fp2Sqrt square root of a square x, for p = 3 mod 4
+func fp2Sqrt(x fp2) fp2 {
+	if x.a1.Sign() == 0 {
+		if blsFp.isSquare(x.a0) {
+			return fp2{blsFp.sqrt(x.a0), new(big.Int)}
+		}
+		// -1 is not square, so -a0 is square
+		return fp2{new(big.Int), blsFp.sqrt(blsFp.neg(x.a0))}
+	}
+	alpha := blsFp.sqrt(fp2Norm(x))
+	half := blsFp.inv(big.NewInt(2))
+	delta := blsFp.mul(blsFp.add(x.a0, alpha), half)
+	if !blsFp.isSquare(delta) {
+		delta = blsFp.mul(blsFp.sub(x.a0, alpha), half)
+	}
+	r0 := blsFp.sqrt(delta)
+	r1 := blsFp.mul(x.a1, blsFp.inv(blsFp.add(r0, r0)))
+	return fp2{r0, r1}
+}
+
+// fp2Sgn0 sgn0 of RFC 9380 section 4.1 for m = 2
+func fp2Sgn0(x fp2) uint {
+	sign0, sign1 := x.a0.Bit(0), x.a1.Bit(0)
+	if x.a0.Sign() == 0 {
+		return sign1
+	}
+	return sign0
+}
+
+// fp2Poly evaluate polynomial with coefficients in ascending order by Horner's method
+func fp2Poly(coeffs []fp2, x fp2) fp2 {
+	ret := coeffs[len(coeffs)-1]
+	for i := len(coeffs) - 2; i >= 0; i-- {
+		ret = fp2Add(fp2Mul(ret, x), coeffs[i])
+	}
+	return ret
+}
+
+// sswuMap2 simplified SWU map of RFC 9380 section 6.6.2 for GF(p^2)
+type sswuMap2 struct {
+	a, b, z fp2
+}
+
+// mapToCurve straight-line map of field element u to curve point (x, y)
+func (m *sswuMap2) mapToCurve(u fp2) (fp2, fp2) {
+	one2 := fp2{big.NewInt(1), new(big.Int)}
+	tv1 := fp2Mul(m.z, fp2Mul(u, u))
+	tv2 := fp2Inv(fp2Add(fp2Mul(tv1, tv1), tv1))
+	var x1 fp2
+	if tv2.isZero() {
+		x1 = fp2Mul(m.b, fp2Inv(fp2Mul(m.z, m.a)))
+	} else {
+		x1 = fp2Mul(fp2Mul(fp2Neg(m.b), fp2Inv(m.a)), fp2Add(one2, tv2))
+	}
+	gx1 := m.g(x1)
+	x2 := fp2Mul(tv1, x1)
+
+	x, y := x2, fp2Sqrt(m.g(x2))
+	if fp2IsSquare(gx1) {
+		x, y = x1, fp2Sqrt(gx1)
+	}
+	if fp2Sgn0(u) != fp2Sgn0(y) {
+		y = fp2Neg(y)
+	}
+	return x, y
+}
+
+// g x^3 + ax + b
+func (m *sswuMap2) g(x fp2) fp2 {
+	x3 := fp2Mul(fp2Mul(x, x), x)
+	return fp2Add(fp2Add(x3, fp2Mul(m.a, x)), m.b)
+}
+
+func hexInt(s string) *big.Int {
+	ret, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("invalid hex constant " + s)
+	}
+	return ret
+}
+
+func hexInts(s ...string) []*big.Int {
+	ret := make([]*big.Int, len(s))
+	for i := range s {
+		ret[i] = hexInt(s[i])
+	}
+	return ret
+}
+
+func hexFp2(a0, a1 string) fp2 {
+	return fp2{hexInt(a0), hexInt(a1)}
+}
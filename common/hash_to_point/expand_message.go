@@ -0,0 +1,99 @@
+package hash_to_point
+
+import (
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+)
+
+// security parameter k of hash_to_field, in bits
+const securityBits = 128
+
+var (
+	ErrEmptyDST = errors.New("hash_to_point: DST must not be empty")
+
+	oversizeDSTPrefix = []byte("H2C-OVERSIZE-DST-")
+)
+
+// ExpandMessageXMD expand_message_xmd of RFC 9380 section 5.3.1
+// output lenInBytes uniformly random bytes from msg and domain separation tag dst
+func ExpandMessageXMD(h func() hash.Hash, msg, dst []byte, lenInBytes int) ([]byte, error) {
+	if len(dst) == 0 {
+		return nil, ErrEmptyDST
+	}
+	H := h()
+	bInBytes, sInBytes := H.Size(), H.BlockSize()
+
+	// DST longer than 255 bytes is hashed, section 5.3.3
+	if len(dst) > 255 {
+		H.Reset()
+		H.Write(oversizeDSTPrefix)
+		H.Write(dst)
+		dst = H.Sum(nil)
+	}
+
+	ell := (lenInBytes + bInBytes - 1) / bInBytes
+	if ell > 255 || lenInBytes > 65535 || lenInBytes <= 0 {
+		return nil, fmt.Errorf("invalid output length %d", lenInBytes)
+	}
+	dstPrime := append(append(make([]byte, 0, len(dst)+1), dst...), byte(len(dst)))
+	libStr := []byte{byte(lenInBytes >> 8), byte(lenInBytes)}
+
+	// b_0 = H(Z_pad || msg || l_i_b_str || 0 || DST_prime)
+	H.Reset()
+	H.Write(make([]byte, sInBytes))
+	H.Write(msg)
+	H.Write(libStr)
+	H.Write([]byte{0})
+	H.Write(dstPrime)
+	b0 := H.Sum(nil)
+
+	// b_1 = H(b_0 || 1 || DST_prime)
+	H.Reset()
+	H.Write(b0)
+	H.Write([]byte{1})
+	H.Write(dstPrime)
+	bi := H.Sum(nil)
+
+	uniform := make([]byte, 0, ell*bInBytes)
+	uniform = append(uniform, bi...)
+	// b_i = H(strxor(b_0, b_(i-1)) || i || DST_prime)
+	for i := 2; i <= ell; i++ {
+		for j := range bi {
+			bi[j] ^= b0[j]
+		}
+		H.Reset()
+		H.Write(bi)
+		H.Write([]byte{byte(i)})
+		H.Write(dstPrime)
+		bi = H.Sum(bi[:0])
+		uniform = append(uniform, bi...)
+	}
+	return uniform[:lenInBytes], nil
+}
+
+// fieldElementLen L = ceil((ceil(log2(p)) + k) / 8)
+func fieldElementLen(p *big.Int) int {
+	return (p.BitLen() + securityBits + 7) / 8
+}
+
+// hashToField hash_to_field of RFC 9380 section 5.2
+// output count elements of GF(p^m), each element is m integers in [0, p)
+func hashToField(h func() hash.Hash, msg, dst []byte, p *big.Int, m, count int) ([][]*big.Int, error) {
+	L := fieldElementLen(p)
+	uniform, err := ExpandMessageXMD(h, msg, dst, count*m*L)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([][]*big.Int, count)
+	for i := 0; i < count; i++ {
+		ret[i] = make([]*big.Int, m)
+		for j := 0; j < m; j++ {
+			offset := L * (j + i*m)
+			e := new(big.Int).SetBytes(uniform[offset : offset+L])
+			ret[i][j] = e.Mod(e, p)
+		}
+	}
+	return ret, nil
+}
@@ -1,4 +1,6 @@
-// hash to ecc point using Try-and-Increment algorithm
+// hash to ecc point
+// RFC 9380 hash_to_curve and encode_to_curve are implemented for P-256, SM2 curve and BLS12-381,
+// HashToPoint is the legacy Try-and-Increment algorithm
 package hash_to_point
 
 import (
@@ -13,6 +15,9 @@ var (
 )
 
 // HashToPoint hash a number to an ecc point, return (x,y)
+//
+// Deprecated: it is variable-time, may fail and has no domain separation,
+// use P256_XMD_SHA256_SSWU.HashToCurve instead.
 func HashToPoint(t *big.Int, tryTimes int) (*big.Int, *big.Int, error) {
 	if t.Cmp(DefaultCurveType.Params().P) >= 0 {
 		return nil, nil, fmt.Errorf("invalid number, must smaller than P")
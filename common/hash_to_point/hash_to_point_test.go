@@ -1,8 +1,13 @@
 package hash_to_point
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"math/big"
+	"strings"
 	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fp"
 )
 
 func TestHashToPoint(t *testing.T) {
@@ -19,3 +24,240 @@ func TestHashToPoint(t *testing.T) {
 	t.Logf("x: %v", x)
 	t.Logf("y: %v", y)
 }
+
+var (
+	dstShort = "QUUX-V01-CS02-with-expander-SHA256-128"
+	dstLong  = "QUUX-V01-CS02-with-expander-SHA256-128-long-DST-" + strings.Repeat("1", 208)
+)
+
+// RFC 9380 appendix K.1, expand_message_xmd with SHA-256
+var expandTests = []struct {
+	dst     string
+	msg     string
+	length  int
+	uniform string
+}{
+	{dstShort, "", 32, "68a985b87eb6b46952128911f2a4412bbc302a9d759667f87f7a21d803f07235"},
+	{dstShort, "abc", 32, "d8ccab23b5985ccea865c6c97b6e5b8350e794e603b4b97902f53a8a0d605615"},
+	{dstShort, "", 128, "af84c27ccfd45d41914fdff5df25293e221afc53d8ad2ac06d5e3e29485dadbee0d121587713a3e0dd4d5e69e93eb7cd4f5df4cd103e188cf60cb02edc3edf18eda8576c412b18ffb658e3dd6ec849469b979d444cf7b26911a08e63cf31f9dcc541708d3491184472c2c29bb749d4286b004ceb5ee6b9a7fa5b646c993f0ced"},
+	{dstShort, "abc", 128, "abba86a6129e366fc877aab32fc4ffc70120d8996c88aee2fe4b32d6c7b6437a647e6c3163d40b76a73cf6a5674ef1d890f95b664ee0afa5359a5c4e07985635bbecbac65d747d3d2da7ec2b8221b17b0ca9dc8a1ac1c07ea6a1e60583e2cb00058e77b7b72a298425cd1b941ad4ec65e8afc50303a22c0f99b0509b4c895f40"},
+	{dstLong, "", 32, "e8dc0c8b686b7ef2074086fbdd2f30e3f8bfbd3bdf177f73f04b97ce618a3ed3"},
+	{dstLong, "abc", 32, "52dbf4f36cf560fca57dedec2ad924ee9c266341d8f3d6afe5171733b16bbb12"},
+	{dstLong, "", 128, "14604d85432c68b757e485c8894db3117992fc57e0e136f71ad987f789a0abc287c47876978e2388a02af86b1e8d1342e5ce4f7aaa07a87321e691f6fba7e0072eecc1218aebb89fb14a0662322d5edbd873f0eb35260145cd4e64f748c5dfe60567e126604bcab1a3ee2dc0778102ae8a5cfd1429ebc0fa6bf1a53c36f55dfc"},
+	{dstLong, "abc", 128, "1a30a5e36fbdb87077552b9d18b9f0aee16e80181d5b951d0471d55b66684914aef87dbb3626eaabf5ded8cd0686567e503853e5c84c259ba0efc37f71c839da2129fe81afdaec7fbdc0ccd4c794727a17c0d20ff0ea55e1389d6982d1241cb8d165762dbc39fb0cee4474d2cbbd468a835ae5b2f20e4f959f56ab24cd6fe267"},
+}
+
+// P256_XMD:SHA-256_SSWU_RO_, DST QUUX-V01-CS02-with-P256_XMD:SHA-256_SSWU_RO_
+var p256ROTests = []struct {
+	msg string
+	x   string
+	y   string
+}{
+	{"", "2c15230b26dbc6fc9a37051158c95b79656e17a1a920b11394ca91c44247d3e4", "8a7a74985cc5c776cdfe4b1f19884970453912e9d31528c060be9ab5c43e8415"},
+	{"abc", "0bb8b87485551aa43ed54f009230450b492fead5f1cc91658775dac4a3388a0f", "5c41b3d0731a27a7b14bc0bf0ccded2d8751f83493404c84a88e71ffd424212e"},
+}
+
+// P256_XMD:SHA-256_SSWU_NU_, DST QUUX-V01-CS02-with-P256_XMD:SHA-256_SSWU_NU_
+var p256NUTests = []struct {
+	msg string
+	x   string
+	y   string
+}{
+	{"", "f871caad25ea3b59c16cf87c1894902f7e7b2c822c3d3f73596c5ace8ddd14d1", "87b9ae23335bee057b99bac1e68588b18b5691af476234b8971bc4f011ddc99b"},
+	{"abc", "fc3f5d734e8dce41ddac49f47dd2b8a57257522a865c124ed02b92b5237befa4", "fe4d197ecf5a62645b9690599e1d80e82c500b22ac705a0b421fac7b47157866"},
+}
+
+// BLS12381G1_XMD:SHA-256_SSWU_RO_, DST QUUX-V01-CS02-with-BLS12381G1_XMD:SHA-256_SSWU_RO_
+var blsg1ROTests = []struct {
+	msg string
+	x   string
+	y   string
+}{
+	{"", "052926add2207b76ca4fa57a8734416c8dc95e24501772c814278700eed6d1e4e8cf62d9c09db0fac349612b759e79a1", "08ba738453bfed09cb546dbb0783dbb3a5f1f566ed67bb6be0e8c67e2e81a4cc68ee29813bb7994998f3eae0c9c6a265"},
+	{"abc", "03567bc5ef9c690c2ab2ecdf6a96ef1c139cc0b2f284dca0a9a7943388a49a3aee664ba5379a7655d3c68900be2f6903", "0b9c15f3fe6e5cf4211f346271d7b01c8f3b28be689c8429c85b67af215533311f0b8dfaaa154fa6b88176c229f2885d"},
+}
+
+// BLS12381G1_XMD:SHA-256_SSWU_NU_, DST QUUX-V01-CS02-with-BLS12381G1_XMD:SHA-256_SSWU_NU_
+var blsg1NUTests = []struct {
+	msg string
+	x   string
+	y   string
+}{
+	{"", "184bb665c37ff561a89ec2122dd343f20e0f4cbcaec84e3c3052ea81d1834e192c426074b02ed3dca4e7676ce4ce48ba", "04407b8d35af4dacc809927071fc0405218f1401a6d15af775810e4e460064bcc9468beeba82fdc751be70476c888bf3"},
+	{"abc", "009769f3ab59bfd551d53a5f846b9984c59b97d6842b20a2c565baa167945e3d026a3755b6345df8ec7e6acb6868ae6d", "1532c00cf61aa3d0ce3e5aa20c3b531a2abd2c770a790a2613818303c6b830ffc0ecf6c357af3317b9575c567f11cd2c"},
+}
+
+// BLS12381G2_XMD:SHA-256_SSWU_RO_, DST QUUX-V01-CS02-with-BLS12381G2_XMD:SHA-256_SSWU_RO_
+var blsg2ROTests = []struct {
+	msg string
+	x   string
+	y   string
+}{
+	{"", "0141ebfbdca40eb85b87142e130ab689c673cf60f1a3e98d69335266f30d9b8d4ac44c1038e9dcdd5393faf5c41fb78a,05cb8437535e20ecffaef7752baddf98034139c38452458baeefab379ba13dff5bf5dd71b72418717047f5b0f37da03d", "0503921d7f6a12805e72940b963c0cf3471c7b2a524950ca195d11062ee75ec076daf2d4bc358c4b190c0c98064fdd92,12424ac32561493f3fe3c260708a12b7c620e7be00099a974e259ddc7d1f6395c3c811cdd19f1e8dbf3e9ecfdcbab8d6"},
+	{"abc", "02c2d18e033b960562aae3cab37a27ce00d80ccd5ba4b7fe0e7a210245129dbec7780ccc7954725f4168aff2787776e6,139cddbccdc5e91b9623efd38c49f81a6f83f175e80b06fc374de9eb4b41dfe4ca3a230ed250fbe3a2acf73a41177fd8", "1787327b68159716a37440985269cf584bcb1e621d3a7202be6ea05c4cfe244aeb197642555a0645fb87bf7466b2ba48,00aa65dae3c8d732d10ecd2c50f8a1baf3001578f71c694e03866e9f3d49ac1e1ce70dd94a733534f106d4cec0eddd16"},
+}
+
+// BLS12381G2_XMD:SHA-256_SSWU_NU_, DST QUUX-V01-CS02-with-BLS12381G2_XMD:SHA-256_SSWU_NU_
+var blsg2NUTests = []struct {
+	msg string
+	x   string
+	y   string
+}{
+	{"", "00e7f4568a82b4b7dc1f14c6aaa055edf51502319c723c4dc2688c7fe5944c213f510328082396515734b6612c4e7bb7,126b855e9e69b1f691f816e48ac6977664d24d99f8724868a184186469ddfd4617367e94527d4b74fc86413483afb35b", "0caead0fd7b6176c01436833c79d305c78be307da5f6af6c133c47311def6ff1e0babf57a0fb5539fce7ee12407b0a42,1498aadcf7ae2b345243e281ae076df6de84455d766ab6fcdaad71fab60abb2e8b980a440043cd305db09d283c895e3d"},
+	{"abc", "108ed59fd9fae381abfd1d6bce2fd2fa220990f0f837fa30e0f27914ed6e1454db0d1ee957b219f61da6ff8be0d6441f,0296238ea82c6d4adb3c838ee3cb2346049c90b96d602d7bb1b469b905c9228be25c627bffee872def773d5b2a2eb57d", "033f90f6057aadacae7963b0a0b379dd46750c1c94a6357c99b65f63b79e321ff50fe3053330911c56b6ceea08fee656,153606c417e59fb331b7ae6bce4fbf7c5190c33ce9402b5ebe2b70e44fca614f3f1382a3625ed5493843d0b0a652fc3f"},
+}
+
+func TestExpandMessageXMD(t *testing.T) {
+	for _, test := range expandTests {
+		uniform, err := ExpandMessageXMD(sha256.New, []byte(test.msg), []byte(test.dst), test.length)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(uniform) != test.uniform {
+			t.Errorf("wrong output for msg %q, length %d", test.msg, test.length)
+		}
+	}
+
+	if _, err := ExpandMessageXMD(sha256.New, []byte("msg"), nil, 32); err != ErrEmptyDST {
+		t.Errorf("empty DST accepted")
+	}
+	if _, err := ExpandMessageXMD(sha256.New, []byte("msg"), []byte("dst"), 255*32+1); err == nil {
+		t.Errorf("too long output accepted")
+	}
+}
+
+func TestP256(t *testing.T) {
+	suite := P256_XMD_SHA256_SSWU
+	for _, test := range p256ROTests {
+		x, y, err := suite.HashToCurve([]byte(test.msg), []byte("QUUX-V01-CS02-with-P256_XMD:SHA-256_SSWU_RO_"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkPoint(t, test.msg, x, y, test.x, test.y)
+	}
+	for _, test := range p256NUTests {
+		x, y, err := suite.EncodeToCurve([]byte(test.msg), []byte("QUUX-V01-CS02-with-P256_XMD:SHA-256_SSWU_NU_"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkPoint(t, test.msg, x, y, test.x, test.y)
+	}
+}
+
+func TestSM2(t *testing.T) {
+	suite := SM2_XMD_SM3_SSWU
+	dst := []byte("CRYPTO-LAB-V01-CS01-with-SM2P256_XMD:SM3_SSWU_RO_")
+	seen := make(map[string]bool)
+	for i := 0; i < 20; i++ {
+		msg := []byte{byte(i)}
+		x, y, err := suite.HashToCurve(msg, dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !suite.Curve.IsOnCurve(x, y) {
+			t.Fatalf("hash of %x is not on curve", msg)
+		}
+		x2, y2, _ := suite.HashToCurve(msg, dst)
+		if x.Cmp(x2) != 0 || y.Cmp(y2) != 0 {
+			t.Errorf("hash is not deterministic")
+		}
+		seen[x.String()] = true
+
+		x, y, err = suite.EncodeToCurve(msg, dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !suite.Curve.IsOnCurve(x, y) {
+			t.Fatalf("encoding of %x is not on curve", msg)
+		}
+	}
+	if len(seen) != 20 {
+		t.Errorf("collision of hash outputs")
+	}
+
+	// domain separation
+	x1, _, _ := suite.HashToCurve([]byte("msg"), []byte("DST-1"))
+	x2, _, _ := suite.HashToCurve([]byte("msg"), []byte("DST-2"))
+	if x1.Cmp(x2) == 0 {
+		t.Errorf("same output for different DST")
+	}
+}
+
+func TestBLS12381G1(t *testing.T) {
+	for _, test := range blsg1ROTests {
+		p, err := HashToG1([]byte(test.msg), []byte("QUUX-V01-CS02-with-BLS12381G1_XMD:SHA-256_SSWU_RO_"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !p.IsInSubGroup() {
+			t.Errorf("hash of %q is not in G1", test.msg)
+		}
+		checkPoint(t, test.msg, fpToInt(&p.X), fpToInt(&p.Y), test.x, test.y)
+	}
+	for _, test := range blsg1NUTests {
+		p, err := EncodeToG1([]byte(test.msg), []byte("QUUX-V01-CS02-with-BLS12381G1_XMD:SHA-256_SSWU_NU_"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !p.IsInSubGroup() {
+			t.Errorf("encoding of %q is not in G1", test.msg)
+		}
+		checkPoint(t, test.msg, fpToInt(&p.X), fpToInt(&p.Y), test.x, test.y)
+	}
+}
+
+func TestBLS12381G2(t *testing.T) {
+	for _, test := range blsg2ROTests {
+		p, err := HashToG2([]byte(test.msg), []byte("QUUX-V01-CS02-with-BLS12381G2_XMD:SHA-256_SSWU_RO_"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !p.IsInSubGroup() {
+			t.Errorf("hash of %q is not in G2", test.msg)
+		}
+		x, y := strings.Split(test.x, ","), strings.Split(test.y, ",")
+		checkPoint(t, test.msg, fpToInt(&p.X.A0), fpToInt(&p.Y.A0), x[0], y[0])
+		checkPoint(t, test.msg, fpToInt(&p.X.A1), fpToInt(&p.Y.A1), x[1], y[1])
+	}
+	for _, test := range blsg2NUTests {
+		p, err := EncodeToG2([]byte(test.msg), []byte("QUUX-V01-CS02-with-BLS12381G2_XMD:SHA-256_SSWU_NU_"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !p.IsInSubGroup() {
+			t.Errorf("encoding of %q is not in G2", test.msg)
+		}
+		x, y := strings.Split(test.x, ","), strings.Split(test.y, ",")
+		checkPoint(t, test.msg, fpToInt(&p.X.A0), fpToInt(&p.Y.A0), x[0], y[0])
+		checkPoint(t, test.msg, fpToInt(&p.X.A1), fpToInt(&p.Y.A1), x[1], y[1])
+	}
+}
+
+func checkPoint(t *testing.T, msg string, x, y *big.Int, expectX, expectY string) {
+	ex, ey := hexInt(strings.TrimPrefix(expectX, "0x")), hexInt(strings.TrimPrefix(expectY, "0x"))
+	if x.Cmp(ex) != 0 || y.Cmp(ey) != 0 {
+		t.Errorf("wrong point for msg %q: (%x, %x)", msg, x, y)
+	}
+}
+
+func fpToInt(e *fp.Element) *big.Int {
+	return e.ToBigIntRegular(new(big.Int))
+}
+
+func BenchmarkP256HashToCurve(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		P256_XMD_SHA256_SSWU.HashToCurve([]byte("msg"), []byte("DST"))
+	}
+}
+
+func BenchmarkHashToG1(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		HashToG1([]byte("msg"), []byte("DST"))
+	}
+}
+
+func BenchmarkHashToG2(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		HashToG2([]byte("msg"), []byte("DST"))
+	}
+}
@@ -0,0 +1,182 @@
+package hash_to_point
+
+import (
+	"crypto/elliptic"
+	"crypto/sha256"
+	"hash"
+	"math/big"
+
+	"github.com/hongyanwang/crypto-lab/asymmetric/sm2"
+	"github.com/hongyanwang/crypto-lab/hash/sm3"
+)
+
+// Suite hash-to-curve suite of RFC 9380 using expand_message_xmd and simplified SWU map
+// for short Weierstrass curves y^2 = x^3 + ax + b over prime field with a, b != 0 and p = 3 mod 4
+//
+//	hash_to_curve:   u0, u1 = hash_to_field(msg, 2), P = map_to_curve(u0) + map_to_curve(u1)
+//	encode_to_curve: u = hash_to_field(msg, 1), P = map_to_curve(u)
+//
+// the cofactor of supported curves is 1
+type Suite struct {
+	// ID suite name without the _RO_/_NU_ suffix, e.g. P256_XMD:SHA-256_SSWU
+	ID    string
+	Curve elliptic.Curve
+	Hash  func() hash.Hash
+	sswu  *sswuMap
+}
+
+var (
+	one = big.NewInt(1)
+
+	// P256_XMD_SHA256_SSWU P256_XMD:SHA-256_SSWU_RO_ and P256_XMD:SHA-256_SSWU_NU_ of RFC 9380 section 8.2
+	P256_XMD_SHA256_SSWU = newSuite("P256_XMD:SHA-256_SSWU", elliptic.P256(), sha256.New, -10)
+
+	// SM2_XMD_SM3_SSWU suite for SM2 recommended curve, Z = -9 is found by find_z_sswu of RFC 9380 appendix H.2
+	SM2_XMD_SM3_SSWU = newSuite("SM2P256_XMD:SM3_SSWU", sm2.P256Sm2(), sm3.New, -9)
+)
+
+// newSuite create suite for curve with a = -3
+func newSuite(id string, curve elliptic.Curve, h func() hash.Hash, z int64) *Suite {
+	params := curve.Params()
+	a := new(big.Int).Sub(params.P, big.NewInt(3))
+	return &Suite{
+		ID:    id,
+		Curve: curve,
+		Hash:  h,
+		sswu:  newSSWUMap(params.P, a, params.B, big.NewInt(z)),
+	}
+}
+
+// HashToCurve hash_to_curve, output is uniformly distributed on the curve, use it as a random oracle
+// DST must be unique for the application and suite, e.g. "MYAPP-V01-CS01-with-P256_XMD:SHA-256_SSWU_RO_"
+func (s *Suite) HashToCurve(msg, dst []byte) (*big.Int, *big.Int, error) {
+	u, err := hashToField(s.Hash, msg, dst, s.sswu.p, 1, 2)
+	if err != nil {
+		return nil, nil, err
+	}
+	x0, y0 := s.sswu.mapToCurve(u[0][0])
+	x1, y1 := s.sswu.mapToCurve(u[1][0])
+	x, y := s.Curve.Add(x0, y0, x1, y1)
+	return x, y, nil
+}
+
+// EncodeToCurve encode_to_curve, cheaper than HashToCurve but output is not uniformly distributed
+func (s *Suite) EncodeToCurve(msg, dst []byte) (*big.Int, *big.Int, error) {
+	u, err := hashToField(s.Hash, msg, dst, s.sswu.p, 1, 1)
+	if err != nil {
+		return nil, nil, err
+	}
+	x, y := s.sswu.mapToCurve(u[0][0])
+	return x, y, nil
+}
+
+// sswuMap simplified SWU map of RFC 9380 section 6.6.2 for prime field with p = 3 mod 4
+type sswuMap struct {
+	*primeField
+	a, b, z *big.Int
+	c1      *big.Int // -b/a
+}
+
+func newSSWUMap(p, a, b, z *big.Int) *sswuMap {
+	f := newPrimeField(p)
+	m := &sswuMap{
+		primeField: f,
+		a:          f.reduce(a),
+		b:          f.reduce(b),
+		z:          f.reduce(z),
+	}
+	m.c1 = f.mul(f.neg(m.b), f.inv(m.a))
+	return m
+}
+
+// mapToCurve straight-line map of field element u to curve point (x, y)
+func (m *sswuMap) mapToCurve(u *big.Int) (*big.Int, *big.Int) {
+	tv1 := m.mul(m.z, m.mul(u, u))            // Z * u^2
+	tv2 := m.inv(m.add(m.mul(tv1, tv1), tv1)) // 1 / (Z^2 * u^4 + Z * u^2), inv0(0) = 0
+	x1 := m.mul(m.c1, m.add(one, tv2))        // (-B / A) * (1 + tv2)
+	if tv2.Sign() == 0 {
+		x1 = m.mul(m.b, m.inv(m.mul(m.z, m.a))) // B / (Z * A)
+	}
+	gx1 := m.g(x1)
+	x2 := m.mul(tv1, x1)
+	gx2 := m.g(x2)
+
+	x, y := x2, m.sqrt(gx2)
+	if m.isSquare(gx1) {
+		x, y = x1, m.sqrt(gx1)
+	}
+	if m.sgn0(u) != m.sgn0(y) {
+		y = m.neg(y)
+	}
+	return x, y
+}
+
+// g x^3 + ax + b
+func (m *sswuMap) g(x *big.Int) *big.Int {
+	x3 := m.mul(m.mul(x, x), x)
+	return m.add(m.add(x3, m.mul(m.a, x)), m.b)
+}
+
+// primeField arithmetic of GF(p) with p = 3 mod 4
+type primeField struct {
+	p        *big.Int
+	sqrtExp  *big.Int // (p+1)/4
+	legendre *big.Int // (p-1)/2
+}
+
+func newPrimeField(p *big.Int) *primeField {
+	f := &primeField{p: p}
+	f.sqrtExp = new(big.Int).Add(p, one)
+	f.sqrtExp.Rsh(f.sqrtExp, 2)
+	f.legendre = new(big.Int).Sub(p, one)
+	f.legendre.Rsh(f.legendre, 1)
+	return f
+}
+
+func (f *primeField) reduce(x *big.Int) *big.Int {
+	return new(big.Int).Mod(x, f.p)
+}
+
+func (f *primeField) add(x, y *big.Int) *big.Int {
+	ret := new(big.Int).Add(x, y)
+	return ret.Mod(ret, f.p)
+}
+
+func (f *primeField) sub(x, y *big.Int) *big.Int {
+	ret := new(big.Int).Sub(x, y)
+	return ret.Mod(ret, f.p)
+}
+
+func (f *primeField) mul(x, y *big.Int) *big.Int {
+	ret := new(big.Int).Mul(x, y)
+	return ret.Mod(ret, f.p)
+}
+
+func (f *primeField) neg(x *big.Int) *big.Int {
+	ret := new(big.Int).Neg(x)
+	return ret.Mod(ret, f.p)
+}
+
+// inv inv0 of RFC 9380, inverse of 0 is 0
+func (f *primeField) inv(x *big.Int) *big.Int {
+	if x.Sign() == 0 {
+		return new(big.Int)
+	}
+	return new(big.Int).ModInverse(x, f.p)
+}
+
+// isSquare Euler's criterion, 0 is square
+func (f *primeField) isSquare(x *big.Int) bool {
+	e := new(big.Int).Exp(x, f.legendre, f.p)
+	return e.Sign() == 0 || e.Cmp(one) == 0
+}
+
+// sqrt x^((p+1)/4), the result is a square root only if x is square
+func (f *primeField) sqrt(x *big.Int) *big.Int {
+	return new(big.Int).Exp(x, f.sqrtExp, f.p)
+}
+
+// sgn0 sign of field element, RFC 9380 section 4.1
+func (f *primeField) sgn0(x *big.Int) uint {
+	return x.Bit(0)
+}
@@ -0,0 +1,90 @@
+// Package modular implements helpers for arithmetic in Z_n* shared by the RSA and Paillier based schemes
+package modular
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+)
+
+var one = big.NewInt(1)
+
+// ExpInt x^e (mod n) for possibly negative e, x must be invertible if e < 0
+func ExpInt(x, e, n *big.Int) *big.Int {
+	if e.Sign() >= 0 {
+		return new(big.Int).Exp(x, e, n)
+	}
+	inv := new(big.Int).ModInverse(x, n)
+	return inv.Exp(inv, new(big.Int).Neg(e), n)
+}
+
+// IsUnit x is in Z_n*, i.e. 0 < x < n and gcd(x, n) = 1
+func IsUnit(x, n *big.Int) bool {
+	return x != nil && x.Sign() > 0 && x.Cmp(n) < 0 && new(big.Int).GCD(nil, nil, x, n).Cmp(one) == 0
+}
+
+// RandomUnit uniformly random element of Z_n*
+func RandomUnit(n *big.Int) (*big.Int, error) {
+	for {
+		r, err := rand.Int(rand.Reader, n)
+		if err != nil {
+			return nil, err
+		}
+		if IsUnit(r, n) {
+			return r, nil
+		}
+	}
+}
+
+// GenerateSafePrime random safe prime p = 2p'+1 of bits length with p' prime, top two bits of p are set
+// candidates are sieved by small primes before Miller-Rabin tests since both p' and p must be prime
+func GenerateSafePrime(bits int) (*big.Int, error) {
+	if bits < 16 {
+		return nil, fmt.Errorf("safe prime too small: %d bits", bits)
+	}
+	bound := new(big.Int).Lsh(one, uint(bits-1))
+	r := new(big.Int)
+	for {
+		pp, err := rand.Int(rand.Reader, bound)
+		if err != nil {
+			return nil, err
+		}
+		pp.SetBit(pp, bits-2, 1)
+		pp.SetBit(pp, bits-3, 1)
+		pp.SetBit(pp, 0, 1)
+
+		// s divides p = 2p'+1 iff p' = (s-1)/2 (mod s)
+		sieved := true
+		for _, sp := range SmallPrimes {
+			rem := r.Mod(pp, big.NewInt(sp)).Int64()
+			if rem == 0 || rem == (sp-1)/2 {
+				sieved = false
+				break
+			}
+		}
+		if !sieved {
+			continue
+		}
+		p := new(big.Int).Lsh(pp, 1)
+		p.Add(p, one)
+		if pp.ProbablyPrime(20) && p.ProbablyPrime(20) {
+			return p, nil
+		}
+	}
+}
+
+// SmallPrimes odd primes below 2000 for sieving and trial division
+var SmallPrimes = func() []int64 {
+	var primes []int64
+	for n := int64(3); n < 2000; n += 2 {
+		if big.NewInt(n).ProbablyPrime(0) {
+			primes = append(primes, n)
+		}
+	}
+	return primes
+}()
+
+// Factorial n!
+func Factorial(n int) *big.Int {
+	return new(big.Int).MulRange(1, int64(n))
+}
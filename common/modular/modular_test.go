@@ -0,0 +1,67 @@
+package modular
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestExpInt(t *testing.T) {
+	n := big.NewInt(101 * 103)
+	x := big.NewInt(1234)
+	for _, e := range []int64{0, 1, 7, -1, -7} {
+		y := ExpInt(x, big.NewInt(e), n)
+		// x^e * x^-e = 1
+		z := new(big.Int).Mul(y, ExpInt(x, big.NewInt(-e), n))
+		if z.Mod(z, n).Cmp(one) != 0 {
+			t.Fatalf("x^%d * x^%d != 1", e, -e)
+		}
+	}
+}
+
+func TestUnit(t *testing.T) {
+	n := big.NewInt(3 * 5 * 7)
+	for _, c := range []struct {
+		x    *big.Int
+		unit bool
+	}{{nil, false}, {big.NewInt(0), false}, {big.NewInt(1), true}, {big.NewInt(4), true}, {big.NewInt(21), false}, {n, false}} {
+		if IsUnit(c.x, n) != c.unit {
+			t.Fatalf("IsUnit(%v) != %v", c.x, c.unit)
+		}
+	}
+	for i := 0; i < 100; i++ {
+		r, err := RandomUnit(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !IsUnit(r, n) {
+			t.Fatalf("%s is not a unit", r)
+		}
+	}
+}
+
+func TestSmallPrimes(t *testing.T) {
+	// 303 primes below 2000, without 2
+	if len(SmallPrimes) != 302 || SmallPrimes[0] != 3 || SmallPrimes[len(SmallPrimes)-1] != 1999 {
+		t.Fatalf("unexpected small primes: %d primes", len(SmallPrimes))
+	}
+}
+
+func TestGenerateSafePrime(t *testing.T) {
+	p, err := GenerateSafePrime(256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pp := new(big.Int).Rsh(p, 1)
+	if p.BitLen() != 256 || !p.ProbablyPrime(20) || !pp.ProbablyPrime(20) {
+		t.Fatalf("%s is not a safe prime of 256 bits", p)
+	}
+	if _, err := GenerateSafePrime(8); err == nil {
+		t.Fatal("tiny safe prime accepted")
+	}
+}
+
+func BenchmarkGenerateSafePrime512(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		GenerateSafePrime(512)
+	}
+}
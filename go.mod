@@ -1,8 +1,9 @@
 module github.com/hongyanwang/crypto-lab
 
-go 1.13
+go 1.15
 
 require (
 	github.com/consensys/gnark-crypto v0.5.3
 	github.com/ldsec/lattigo/v2 v2.1.2-0.20210118094248-ac34a39dbfd0
+	golang.org/x/crypto v0.0.0-20210322153248-0c34fe9e7dc2
 )
@@ -1,98 +1,155 @@
 package sm3
 
-import "encoding/binary"
+import (
+	"crypto/hmac"
+	"encoding/binary"
+	"hash"
+)
 
 // message block size, 64 bytes
 const BLOCKSIZE = 64
 
+// hash output size, 32 bytes
+const SIZE = 32
+
 // initial vector
 var IV = [8]uint32{0x7380166f, 0x4914b2b9, 0x172442d7, 0xda8a0600, 0xa96f30bc, 0x163138aa, 0xe38dee4d, 0xb0fb0e4e}
 
+// digest sm3 hash state, implements hash.Hash
+type digest struct {
+	v   [8]uint32
+	x   [BLOCKSIZE]byte // pending bytes of an incomplete block
+	nx  int
+	len uint64
+}
+
+// New returns a new hash.Hash computing sm3
+func New() hash.Hash {
+	d := new(digest)
+	d.Reset()
+	return d
+}
+
 // SM3 sm3 hash function
 func SM3(msg []byte) []byte {
-	msgPadding := padding(msg)
-	n := len(msgPadding)
-	v := IV
-	for i := 0; i < n; i++ {
-		v = cf(v, msgPadding[i])
-	}
-
-	ret := make([]byte, 32)
-	for i := 0; i < 8; i++ {
-		binary.BigEndian.PutUint32(ret[i*4:], v[i])
-	}
-	return ret
+	var d digest
+	d.Reset()
+	d.Write(msg)
+	sum := d.checkSum()
+	return sum[:]
 }
 
-// cf compression function
-func cf(v [8]uint32, b [16]uint32) [8]uint32 {
-	msgExt := msgExtend(b)
-	A, B, C, D, E, F, G, H := v[0], v[1], v[2], v[3], v[4], v[5], v[6], v[7]
-	for i := 0; i < 64; i++ {
-		ss1 := leftRotate(leftRotate(A, 12)+E+leftRotate(ti(i), uint32(i)), 7)
-		ss2 := ss1 ^ leftRotate(A, 12)
-		tt1 := ffj(A, B, C, i) + D + ss2 + msgExt[i+68]
-		tt2 := ggj(E, F, G, i) + H + ss1 + msgExt[i]
-		D = C
-		C = leftRotate(B, 9)
-		B = A
-		A = tt1
-		H = G
-		G = leftRotate(F, 19)
-		F = E
-		E = permutation0(tt2)
-	}
-	return [8]uint32{A ^ v[0], B ^ v[1], C ^ v[2], D ^ v[3], E ^ v[4], F ^ v[5], G ^ v[6], H ^ v[7]}
+// HMAC hmac-sm3 of msg with key
+func HMAC(key, msg []byte) []byte {
+	mac := hmac.New(New, key)
+	mac.Write(msg)
+	return mac.Sum(nil)
 }
 
-// msgExtend extend message block from [16]uint32 to [132]uint32
-func msgExtend(block [16]uint32) [132]uint32 {
-	var ret = [132]uint32{}
+// Size returns hash output size
+func (d *digest) Size() int { return SIZE }
+
+// BlockSize returns message block size
+func (d *digest) BlockSize() int { return BLOCKSIZE }
+
+// Reset resets the hash to initial state
+func (d *digest) Reset() {
+	d.v = IV
+	d.nx = 0
+	d.len = 0
+}
 
-	for i := 0; i < 16; i++ {
-		ret[i] = block[i]
+// Write absorbs more data, it never returns an error
+func (d *digest) Write(p []byte) (int, error) {
+	nn := len(p)
+	d.len += uint64(nn)
+	if d.nx > 0 {
+		n := copy(d.x[d.nx:], p)
+		d.nx += n
+		if d.nx == BLOCKSIZE {
+			block(&d.v, d.x[:])
+			d.nx = 0
+		}
+		p = p[n:]
 	}
-	for i := 16; i < 68; i++ {
-		p1 := permutation1(ret[i-16] ^ ret[i-9] ^ (leftRotate(ret[i-3], 15)))
-		ret[i] = p1 ^ (leftRotate(ret[i-13], 7)) ^ ret[i-6]
+	if len(p) >= BLOCKSIZE {
+		n := len(p) &^ (BLOCKSIZE - 1)
+		block(&d.v, p[:n])
+		p = p[n:]
 	}
-	for i := 68; i < 132; i++ {
-		ret[i] = ret[i-68] ^ ret[i-64]
+	if len(p) > 0 {
+		d.nx = copy(d.x[:], p)
 	}
-
-	return ret
+	return nn, nil
 }
 
-// padding pad string to binary string, assume len(msg) < 2^64
-func padding(msg []byte) [][16]uint32 {
-	length := 8 * len(msg)
-
-	// append 1
-	msg = append(msg, 1<<7)
+// Sum appends the hash of data written so far to b, the state is not changed
+func (d *digest) Sum(b []byte) []byte {
+	d0 := *d
+	sum := d0.checkSum()
+	return append(b, sum[:]...)
+}
 
-	// append k {00...0}, len(bin) + 1 + k = 448 mod 512
-	for len(msg)%BLOCKSIZE != 56 {
-		msg = append(msg, 0)
+// checkSum pad message and output hash
+// append 1, k zeros s.t. len+1+k = 448 mod 512, and 64-bit length
+func (d *digest) checkSum() [SIZE]byte {
+	length := d.len << 3
+	var tmp [BLOCKSIZE + 8]byte
+	tmp[0] = 0x80
+	if d.len%BLOCKSIZE < 56 {
+		d.Write(tmp[:56-d.len%BLOCKSIZE])
+	} else {
+		d.Write(tmp[:BLOCKSIZE+56-d.len%BLOCKSIZE])
 	}
+	binary.BigEndian.PutUint64(tmp[:8], length)
+	d.Write(tmp[:8])
 
-	// append 64 len(m) s.t. len(m)+k+1+64 = 0 mod 512
-	for i := 8; i > 0; i-- {
-		msg = append(msg, byte(length>>(8*(i-1))&255))
+	var ret [SIZE]byte
+	for i := 0; i < 8; i++ {
+		binary.BigEndian.PutUint32(ret[i*4:], d.v[i])
 	}
+	return ret
+}
 
-	// convert byte slice to uint32 slice
-	var ret [][16]uint32
-	n := len(msg) / BLOCKSIZE
-	for i := 0; i < n; i++ {
-		block := [16]uint32{}
-		for j := 0; j < 16; j++ {
-			begin := BLOCKSIZE*i + 4*j
-			block[j] = binary.BigEndian.Uint32(msg[begin : begin+4])
+// block run compression function on each 64-byte block of p
+func block(v *[8]uint32, p []byte) {
+	var w [68]uint32
+	for len(p) >= BLOCKSIZE {
+		// message extension, W'[j] = W[j]^W[j+4] is computed in the rounds
+		for i := 0; i < 16; i++ {
+			w[i] = binary.BigEndian.Uint32(p[4*i:])
+		}
+		for i := 16; i < 68; i++ {
+			w[i] = permutation1(w[i-16]^w[i-9]^leftRotate(w[i-3], 15)) ^ leftRotate(w[i-13], 7) ^ w[i-6]
 		}
-		ret = append(ret, block)
-	}
 
-	return ret
+		A, B, C, D, E, F, G, H := v[0], v[1], v[2], v[3], v[4], v[5], v[6], v[7]
+		for i := 0; i < 64; i++ {
+			a12 := leftRotate(A, 12)
+			ss1 := leftRotate(a12+E+leftRotate(ti(i), uint32(i)), 7)
+			ss2 := ss1 ^ a12
+			tt1 := ffj(A, B, C, i) + D + ss2 + (w[i] ^ w[i+4])
+			tt2 := ggj(E, F, G, i) + H + ss1 + w[i]
+			D = C
+			C = leftRotate(B, 9)
+			B = A
+			A = tt1
+			H = G
+			G = leftRotate(F, 19)
+			F = E
+			E = permutation0(tt2)
+		}
+		v[0] ^= A
+		v[1] ^= B
+		v[2] ^= C
+		v[3] ^= D
+		v[4] ^= E
+		v[5] ^= F
+		v[6] ^= G
+		v[7] ^= H
+
+		p = p[BLOCKSIZE:]
+	}
 }
 
 // ti constant in compression function
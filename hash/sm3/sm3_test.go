@@ -1,13 +1,95 @@
 package sm3
 
 import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
 	"testing"
 )
 
+// test vectors of GB/T 32905 appendix A
 func TestSM3(t *testing.T) {
-	msg := []byte("test")
-	hash := SM3(msg)
-	t.Log(hash)
+	tests := []struct {
+		msg  string
+		hash string
+	}{
+		{"abc", "66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0"},
+		{strings.Repeat("abcd", 16), "debe9ff92275b8a138604889c18e5a4d6fdb70e5387e5765293dcba39c0c5732"},
+	}
+	for _, test := range tests {
+		if hash := hex.EncodeToString(SM3([]byte(test.msg))); hash != test.hash {
+			t.Errorf("SM3(%q) = %s, want %s", test.msg, hash, test.hash)
+		}
+		h := New()
+		for i := 0; i < len(test.msg); i++ {
+			h.Write([]byte{test.msg[i]})
+		}
+		if hash := hex.EncodeToString(h.Sum(nil)); hash != test.hash {
+			t.Errorf("streaming hash of %q = %s, want %s", test.msg, hash, test.hash)
+		}
+	}
+}
+
+func TestStreaming(t *testing.T) {
+	msg := make([]byte, 1000)
+	rand.Read(msg)
+	h := New()
+	for n := 0; n <= len(msg); n++ {
+		expect := SM3(msg[:n])
+		// write in chunks of different sizes
+		for _, chunk := range []int{1, 13, 64, 100} {
+			h.Reset()
+			for p := msg[:n]; len(p) > 0; {
+				m := chunk
+				if m > len(p) {
+					m = len(p)
+				}
+				h.Write(p[:m])
+				p = p[m:]
+			}
+			if !bytes.Equal(h.Sum(nil), expect) {
+				t.Fatalf("wrong hash of %d bytes with chunk %d", n, chunk)
+			}
+		}
+	}
+
+	// Sum does not change the state
+	h.Reset()
+	h.Write(msg[:10])
+	prefix := []byte("prefix")
+	if sum := h.Sum(prefix); !bytes.Equal(sum[:len(prefix)], prefix) || !bytes.Equal(sum[len(prefix):], SM3(msg[:10])) {
+		t.Errorf("wrong Sum with prefix")
+	}
+	h.Write(msg[10:])
+	if !bytes.Equal(h.Sum(nil), SM3(msg)) {
+		t.Errorf("wrong hash after Sum")
+	}
+
+	// SM3 does not modify the message
+	buf := make([]byte, 3, 100)
+	copy(buf, "abc")
+	SM3(buf)
+	if buf[:4][3] != 0 {
+		t.Errorf("message is modified")
+	}
+}
+
+func TestHMAC(t *testing.T) {
+	tests := []struct {
+		key string
+		msg string
+		mac string
+	}{
+		{"key", "The quick brown fox jumps over the lazy dog", "bd4a34077888162b210645b8ebf74b9af357303789357a27c7fc457244ebd398"},
+		// key longer than block size
+		{string(make([]byte, 100)), "abc", "37a18b4b791739902d729cee8a07eecabe483a6a1416875406e6a836d5264ec2"},
+	}
+	for _, test := range tests {
+		if mac := hex.EncodeToString(HMAC([]byte(test.key), []byte(test.msg))); mac != test.mac {
+			t.Errorf("wrong hmac %s, want %s", mac, test.mac)
+		}
+	}
 }
 
 func BenchmarkSM3(b *testing.B) {
@@ -17,3 +99,38 @@ func BenchmarkSM3(b *testing.B) {
 		SM3(msg)
 	}
 }
+
+func benchmarkSize(b *testing.B, size int) {
+	b.ReportAllocs()
+	b.SetBytes(int64(size))
+	msg := make([]byte, size)
+	h := New()
+	sum := make([]byte, 0, SIZE)
+	for i := 0; i < b.N; i++ {
+		h.Reset()
+		h.Write(msg)
+		h.Sum(sum[:0])
+	}
+}
+
+func BenchmarkHash64(b *testing.B) {
+	benchmarkSize(b, 64)
+}
+
+func BenchmarkHash1K(b *testing.B) {
+	benchmarkSize(b, 1024)
+}
+
+func BenchmarkHash8K(b *testing.B) {
+	benchmarkSize(b, 8192)
+}
+
+func BenchmarkHMAC(b *testing.B) {
+	b.ReportAllocs()
+	key := make([]byte, 32)
+	msg := make([]byte, 1024)
+	b.SetBytes(int64(len(msg)))
+	for i := 0; i < b.N; i++ {
+		HMAC(key, msg)
+	}
+}
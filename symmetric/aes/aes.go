@@ -4,9 +4,80 @@ import (
 	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
 	"fmt"
+	"io"
 )
 
+// NonceSize standard nonce size of AES-GCM
+const NonceSize = 12
+
+// NewGCM returns AES-GCM AEAD with standard 12-byte nonce and 16-byte tag
+// key length must be 16, 24 or 32 bytes
+func NewGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Seal encrypt and authenticate plaintext and additional data, output ciphertext||tag
+// a nonce must never be reused with the same key
+func Seal(key, nonce, plaintext, additionalData []byte) ([]byte, error) {
+	aead, err := NewGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce length %d, should be %d", len(nonce), aead.NonceSize())
+	}
+	return aead.Seal(nil, nonce, plaintext, additionalData), nil
+}
+
+// Open verify and decrypt ciphertext||tag with the nonce and additional data used in Seal
+func Open(key, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	aead, err := NewGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce length %d, should be %d", len(nonce), aead.NonceSize())
+	}
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
+
+// EncryptGCM encrypt and authenticate message and additional data
+// a random nonce is generated and output as nonce||ciphertext||tag
+func EncryptGCM(msg, key, additionalData []byte) ([]byte, error) {
+	aead, err := NewGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(msg)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, msg, additionalData), nil
+}
+
+// DecryptGCM verify and decrypt nonce||ciphertext||tag output by EncryptGCM
+func DecryptGCM(ciphertext, key, additionalData []byte) ([]byte, error) {
+	aead, err := NewGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aead.NonceSize()+aead.Overhead() {
+		return nil, fmt.Errorf("invalid ciphertext length")
+	}
+	nonce := ciphertext[:aead.NonceSize()]
+	return aead.Open(nil, nonce, ciphertext[aead.NonceSize():], additionalData)
+}
+
+// Encrypt AES-CBC encryption using the key as IV
+//
+// Deprecated: the ciphertext is deterministic and not authenticated, use EncryptGCM instead.
 func Encrypt(msg, key []byte) ([]byte, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
@@ -23,32 +94,46 @@ func Encrypt(msg, key []byte) ([]byte, error) {
 	return ciphertext, nil
 }
 
+// Decrypt AES-CBC decryption using the key as IV
+//
+// Deprecated: the ciphertext is not authenticated, use DecryptGCM instead.
 func Decrypt(ciphertext, key []byte) ([]byte, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
 	}
 
-	plaintext := make([]byte, len(ciphertext))
 	blockSize := block.BlockSize()
+	if len(ciphertext) == 0 || len(ciphertext)%blockSize != 0 {
+		return nil, fmt.Errorf("invalid content")
+	}
+	plaintext := make([]byte, len(ciphertext))
 
 	blockMode := cipher.NewCBCDecrypter(block, key[:blockSize])
 	blockMode.CryptBlocks(plaintext, ciphertext)
 
-	return unpadding(plaintext)
+	return unpadding(plaintext, blockSize)
 }
 
+// padding PKCS#7 padding, msg is copied
 func padding(src []byte, blocksize int) []byte {
 	padnum := blocksize - len(src)%blocksize
 	pad := bytes.Repeat([]byte{byte(padnum)}, padnum)
-	return append(src, pad...)
+	return append(append(make([]byte, 0, len(src)+padnum), src...), pad...)
 }
 
-func unpadding(src []byte) ([]byte, error) {
+// unpadding remove PKCS#7 padding, every padding byte is checked in constant time
+func unpadding(src []byte, blocksize int) ([]byte, error) {
 	n := len(src)
 	unpadnum := int(src[n-1])
 
-	if n < unpadnum {
+	good := subtle.ConstantTimeLessOrEq(1, unpadnum) & subtle.ConstantTimeLessOrEq(unpadnum, blocksize)
+	for i := 1; i <= blocksize; i++ {
+		inPad := subtle.ConstantTimeLessOrEq(i, unpadnum)
+		match := subtle.ConstantTimeByteEq(src[n-i], byte(unpadnum))
+		good &= ^inPad | match
+	}
+	if good&1 != 1 {
 		return nil, fmt.Errorf("invalid content")
 	}
 	return src[:n-unpadnum], nil
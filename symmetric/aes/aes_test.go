@@ -1,7 +1,13 @@
 package aes
 
 import (
+	"bytes"
+	"crypto/rand"
 	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
 	"reflect"
 	"testing"
 )
@@ -29,6 +35,80 @@ func TestAES(t *testing.T) {
 	}
 }
 
+// test case 4 of the GCM specification
+func TestGCMKAT(t *testing.T) {
+	gcmKey, _ := hex.DecodeString("feffe9928665731c6d6a8f9467308308")
+	nonce, _ := hex.DecodeString("cafebabefacedbaddecaf888")
+	ad, _ := hex.DecodeString("feedfacedeadbeeffeedfacedeadbeefabaddad2")
+	plaintext, _ := hex.DecodeString("d9313225f88406e5a55909c5aff5269a86a7a9531534f7da2e4c303d8a318a721c3c0c95956809532fcf0e2449a6b525b16aedf5aa0de657ba637b39")
+	expect, _ := hex.DecodeString("42831ec2217774244b7221b784d0d49ce3aa212f2c02a4e035c17e2329aca12e21d514b25466931c7d8f6a5aac84aa051ba30b396a0aac973d58e091" +
+		"5bc94fbc3221a5db94fae95ae7121a47")
+
+	sealed, err := Seal(gcmKey, nonce, plaintext, ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(sealed, expect) {
+		t.Errorf("wrong ciphertext %x", sealed)
+	}
+	opened, err := Open(gcmKey, nonce, sealed, ad)
+	if err != nil || !bytes.Equal(opened, plaintext) {
+		t.Errorf("open failed: %v", err)
+	}
+	if _, err := Open(gcmKey, nonce, sealed, nil); err == nil {
+		t.Errorf("wrong additional data accepted")
+	}
+	if _, err := Seal(gcmKey, nonce[:8], plaintext, ad); err == nil {
+		t.Errorf("invalid nonce length accepted")
+	}
+}
+
+func TestGCM(t *testing.T) {
+	ad := []byte("aes test header")
+	c1, err := EncryptGCM(msg, key[:], ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, err := EncryptGCM(msg, key[:], ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(c1, c2) {
+		t.Errorf("ciphertexts with random nonce should differ")
+	}
+	plaintext, err := DecryptGCM(c1, key[:], ad)
+	if err != nil || !bytes.Equal(plaintext, msg) {
+		t.Errorf("aes gcm encrypt/decrypt failed: %v", err)
+	}
+	for i := range c1 {
+		c := append([]byte{}, c1...)
+		c[i] ^= 0x80
+		if _, err := DecryptGCM(c, key[:], ad); err == nil {
+			t.Errorf("tampered byte %d accepted", i)
+		}
+	}
+	if _, err := DecryptGCM(c1[:NonceSize+15], key[:], ad); err == nil {
+		t.Errorf("truncated ciphertext accepted")
+	}
+}
+
+func TestUnpadding(t *testing.T) {
+	bad := [][]byte{
+		append(bytes.Repeat([]byte{1}, 15), 0),
+		append(bytes.Repeat([]byte{1}, 15), 17),
+		append(bytes.Repeat([]byte{1}, 14), 3, 3),
+	}
+	for _, b := range bad {
+		if _, err := unpadding(b, 16); err == nil {
+			t.Errorf("invalid padding %x accepted", b)
+		}
+	}
+	good := append(bytes.Repeat([]byte{1}, 13), 3, 3, 3)
+	if p, err := unpadding(good, 16); err != nil || len(p) != 13 {
+		t.Errorf("valid padding rejected")
+	}
+}
+
 func BenchmarkEnc(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		testEnc()
@@ -47,3 +127,165 @@ func testEnc() {
 func testDec() {
 	Decrypt(ciphertext, key[:])
 }
+
+const testSegmentSize = 64
+
+var streamAD = []byte("stream test")
+
+func encryptStream(t testing.TB, plaintext []byte, segmentSize int) []byte {
+	var buf bytes.Buffer
+	w, err := NewEncryptWriterWithSegmentSize(&buf, key[:], streamAD, segmentSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// write in uneven pieces
+	for p := plaintext; len(p) > 0; {
+		n := 7
+		if n > len(p) {
+			n = len(p)
+		}
+		if _, err := w.Write(p[:n]); err != nil {
+			t.Fatal(err)
+		}
+		p = p[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func decryptStream(ciphertext, ad []byte) ([]byte, error) {
+	r, err := NewDecryptReader(bytes.NewReader(ciphertext), key[:], ad)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(r)
+}
+
+func TestStream(t *testing.T) {
+	ctSegment := testSegmentSize + streamTagSize
+	for _, size := range []int{0, 1, testSegmentSize - 1, testSegmentSize, testSegmentSize + 1, 3 * testSegmentSize, 1000} {
+		plaintext := make([]byte, size)
+		rand.Read(plaintext)
+		ciphertext := encryptStream(t, plaintext, testSegmentSize)
+
+		segments := size/testSegmentSize + 1
+		if size > 0 && size%testSegmentSize == 0 {
+			segments--
+		}
+		if len(ciphertext) != streamHeaderSize+size+segments*streamTagSize {
+			t.Errorf("size %d: wrong ciphertext length %d", size, len(ciphertext))
+		}
+		decrypted, err := decryptStream(ciphertext, streamAD)
+		if err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		}
+		if !bytes.Equal(decrypted, plaintext) {
+			t.Errorf("size %d: decrypted data differs", size)
+		}
+
+		if _, err := decryptStream(ciphertext, []byte("other")); err == nil {
+			t.Errorf("size %d: wrong additional data accepted", size)
+		}
+		if segments < 2 {
+			continue
+		}
+
+		// truncation at a segment boundary
+		truncated := ciphertext[:streamHeaderSize+(segments-1)*ctSegment]
+		if _, err := decryptStream(truncated, streamAD); err != ErrStreamTruncated {
+			t.Errorf("size %d: truncated stream accepted: %v", size, err)
+		}
+		// truncation inside a segment
+		if _, err := decryptStream(ciphertext[:len(ciphertext)-1], streamAD); err == nil {
+			t.Errorf("size %d: truncated segment accepted", size)
+		}
+		// reordering segments
+		if len(ciphertext) >= streamHeaderSize+2*ctSegment {
+			reordered := append([]byte{}, ciphertext...)
+			first := append([]byte{}, ciphertext[streamHeaderSize:streamHeaderSize+ctSegment]...)
+			copy(reordered[streamHeaderSize:], ciphertext[streamHeaderSize+ctSegment:streamHeaderSize+2*ctSegment])
+			copy(reordered[streamHeaderSize+ctSegment:], first)
+			if _, err := decryptStream(reordered, streamAD); err == nil {
+				t.Errorf("size %d: reordered stream accepted", size)
+			}
+		}
+		// appending data
+		if _, err := decryptStream(append(append([]byte{}, ciphertext...), 0), streamAD); err == nil {
+			t.Errorf("size %d: extended stream accepted", size)
+		}
+	}
+}
+
+func TestStreamBitFlip(t *testing.T) {
+	plaintext := make([]byte, 2*testSegmentSize+10)
+	ciphertext := encryptStream(t, plaintext, testSegmentSize)
+	for i := 0; i < len(ciphertext); i++ {
+		c := append([]byte{}, ciphertext...)
+		c[i] ^= 0x01
+		if _, err := decryptStream(c, streamAD); err == nil {
+			t.Fatalf("bit flip at byte %d accepted", i)
+		}
+	}
+}
+
+func TestStreamPartialRead(t *testing.T) {
+	plaintext := make([]byte, 3*testSegmentSize)
+	rand.Read(plaintext)
+	ciphertext := encryptStream(t, plaintext, testSegmentSize)
+	// corrupt the last segment, earlier authenticated segments are still readable
+	ciphertext[len(ciphertext)-1] ^= 1
+	r, err := NewDecryptReader(bytes.NewReader(ciphertext), key[:], streamAD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]byte, 2*testSegmentSize)
+	if _, err := io.ReadFull(r, got); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, plaintext[:2*testSegmentSize]) {
+		t.Errorf("wrong plaintext of authenticated segments")
+	}
+	if _, err := r.Read(got); err != ErrStreamCorrupted {
+		t.Errorf("corrupted last segment not detected: %v", err)
+	}
+}
+
+func TestStreamSegmentSize(t *testing.T) {
+	var buf bytes.Buffer
+	for _, size := range []int{MinSegmentSize - 1, MaxSegmentSize + 1} {
+		if _, err := NewEncryptWriterWithSegmentSize(&buf, key[:], streamAD, size); err == nil {
+			t.Errorf("segment size %d accepted", size)
+		}
+	}
+	// a forged header must be rejected before the segment buffers are allocated
+	header := encryptStream(t, nil, testSegmentSize)[:streamHeaderSize]
+	binary.BigEndian.PutUint32(header[1:5], 0xFFFFFFFF)
+	if _, err := NewDecryptReader(bytes.NewReader(header), key[:], streamAD); err == nil {
+		t.Fatal("segment size 0xFFFFFFFF accepted")
+	}
+}
+
+func BenchmarkStreamEnc(b *testing.B) {
+	data := make([]byte, 1<<20)
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		w, _ := NewEncryptWriter(ioutil.Discard, key[:], nil)
+		w.Write(data)
+		w.Close()
+	}
+}
+
+func BenchmarkStreamDec(b *testing.B) {
+	data := make([]byte, 1<<20)
+	var buf bytes.Buffer
+	w, _ := NewEncryptWriter(&buf, key[:], nil)
+	w.Write(data)
+	w.Close()
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		r, _ := NewDecryptReader(bytes.NewReader(buf.Bytes()), key[:], nil)
+		io.Copy(ioutil.Discard, r)
+	}
+}
@@ -0,0 +1,276 @@
+package aes
+
+import (
+	"bufio"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// Segmented streaming AEAD, following the STREAM construction of Hoang, Reyhanitabar, Rogaway and Vizár
+//
+//	stream  = header || segment_0 || segment_1 || ... || segment_last
+//	header  = version(1) || segmentSize(4) || salt(16) || noncePrefix(7)
+//	segKey  = HKDF-SHA256(key, salt, header[:5] || noncePrefix || additionalData)
+//	nonce_i = noncePrefix(7) || i(4) || lastFlag(1)
+//	segment_i = AES-GCM(segKey, nonce_i, plaintext_i), plaintext_i has segmentSize bytes except the last one
+//
+// the last segment is encrypted with lastFlag=1, so truncation at a segment boundary is detected
+const (
+	DefaultSegmentSize = 64 * 1024
+	MinSegmentSize     = 16
+	MaxSegmentSize     = 1 << 24 // bounds the buffers allocated for an unauthenticated header
+
+	streamVersion     = 1
+	streamSaltSize    = 16
+	streamPrefixSize  = 7
+	streamHeaderSize  = 1 + 4 + streamSaltSize + streamPrefixSize
+	streamTagSize     = 16
+	maxStreamSegments = 1 << 32
+)
+
+var (
+	ErrStreamTruncated = errors.New("aes: stream is truncated")
+	ErrStreamCorrupted = errors.New("aes: stream segment authentication failed")
+)
+
+// streamHeader parameters shared by writer and reader
+type streamHeader struct {
+	segmentSize int
+	salt        []byte
+	noncePrefix []byte
+}
+
+// marshal encode header
+func (h *streamHeader) marshal() []byte {
+	buf := make([]byte, streamHeaderSize)
+	buf[0] = streamVersion
+	binary.BigEndian.PutUint32(buf[1:5], uint32(h.segmentSize))
+	copy(buf[5:], h.salt)
+	copy(buf[5+streamSaltSize:], h.noncePrefix)
+	return buf
+}
+
+// newAEAD derive segment key and create AES-GCM
+func (h *streamHeader) newAEAD(key, additionalData []byte) (cipher.AEAD, error) {
+	if len(key) != 16 && len(key) != 24 && len(key) != 32 {
+		return nil, fmt.Errorf("invalid key length %d", len(key))
+	}
+	info := append(h.marshal()[:5], h.noncePrefix...)
+	info = append(info, additionalData...)
+	segKey := make([]byte, len(key))
+	if _, err := io.ReadFull(hkdf.New(sha256.New, key, h.salt, info), segKey); err != nil {
+		return nil, err
+	}
+	return NewGCM(segKey)
+}
+
+// nonce noncePrefix || i || lastFlag
+func (h *streamHeader) nonce(i uint64, last bool) []byte {
+	nonce := make([]byte, NonceSize)
+	copy(nonce, h.noncePrefix)
+	binary.BigEndian.PutUint32(nonce[streamPrefixSize:], uint32(i))
+	if last {
+		nonce[NonceSize-1] = 1
+	}
+	return nonce
+}
+
+// encryptWriter encrypts data written to it and writes segments to the underlying writer
+type encryptWriter struct {
+	w      io.Writer
+	header *streamHeader
+	aead   cipher.AEAD
+	buf    []byte // plaintext of the pending segment
+	ct     []byte
+	index  uint64
+	closed bool
+}
+
+// NewEncryptWriter returns a writer encrypting data to w with DefaultSegmentSize
+// Close must be called to write the last segment
+func NewEncryptWriter(w io.Writer, key, additionalData []byte) (io.WriteCloser, error) {
+	return NewEncryptWriterWithSegmentSize(w, key, additionalData, DefaultSegmentSize)
+}
+
+// NewEncryptWriterWithSegmentSize returns a writer encrypting data to w with given plaintext segment size
+func NewEncryptWriterWithSegmentSize(w io.Writer, key, additionalData []byte, segmentSize int) (io.WriteCloser, error) {
+	if segmentSize < MinSegmentSize || segmentSize > MaxSegmentSize {
+		return nil, fmt.Errorf("invalid segment size %d", segmentSize)
+	}
+	header := &streamHeader{
+		segmentSize: segmentSize,
+		salt:        make([]byte, streamSaltSize),
+		noncePrefix: make([]byte, streamPrefixSize),
+	}
+	if _, err := io.ReadFull(rand.Reader, header.salt); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(rand.Reader, header.noncePrefix); err != nil {
+		return nil, err
+	}
+	aead, err := header.newAEAD(key, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(header.marshal()); err != nil {
+		return nil, err
+	}
+	return &encryptWriter{
+		w:      w,
+		header: header,
+		aead:   aead,
+		buf:    make([]byte, 0, segmentSize),
+		ct:     make([]byte, 0, segmentSize+streamTagSize),
+	}, nil
+}
+
+// Write buffers data, a full segment is written only when more data follows it
+func (ew *encryptWriter) Write(p []byte) (int, error) {
+	if ew.closed {
+		return 0, errors.New("aes: write to closed stream")
+	}
+	n := 0
+	for len(p) > 0 {
+		if len(ew.buf) == ew.header.segmentSize {
+			if err := ew.flush(false); err != nil {
+				return n, err
+			}
+		}
+		m := copy(ew.buf[len(ew.buf):cap(ew.buf)], p)
+		ew.buf = ew.buf[:len(ew.buf)+m]
+		p = p[m:]
+		n += m
+	}
+	return n, nil
+}
+
+// Close writes the last segment, it does not close the underlying writer
+func (ew *encryptWriter) Close() error {
+	if ew.closed {
+		return nil
+	}
+	ew.closed = true
+	return ew.flush(true)
+}
+
+// flush encrypt and write pending segment
+func (ew *encryptWriter) flush(last bool) error {
+	if ew.index >= maxStreamSegments {
+		return errors.New("aes: too many segments")
+	}
+	ew.ct = ew.aead.Seal(ew.ct[:0], ew.header.nonce(ew.index, last), ew.buf, nil)
+	if _, err := ew.w.Write(ew.ct); err != nil {
+		return err
+	}
+	ew.index++
+	ew.buf = ew.buf[:0]
+	return nil
+}
+
+// decryptReader reads segments from the underlying reader and decrypts them
+type decryptReader struct {
+	r      *bufio.Reader
+	header *streamHeader
+	aead   cipher.AEAD
+	ct     []byte
+	pt     []byte
+	plain  []byte // decrypted data not read yet
+	index  uint64
+	done   bool
+	err    error
+}
+
+// NewDecryptReader returns a reader decrypting the stream from r
+// data is only returned after its segment is authenticated, io.EOF is returned after the last segment
+func NewDecryptReader(r io.Reader, key, additionalData []byte) (io.Reader, error) {
+	headerBytes := make([]byte, streamHeaderSize)
+	if _, err := io.ReadFull(r, headerBytes); err != nil {
+		return nil, ErrStreamTruncated
+	}
+	if headerBytes[0] != streamVersion {
+		return nil, fmt.Errorf("unsupported stream version %d", headerBytes[0])
+	}
+	segmentSize := binary.BigEndian.Uint32(headerBytes[1:5])
+	if segmentSize < MinSegmentSize || segmentSize > MaxSegmentSize {
+		return nil, fmt.Errorf("invalid segment size %d", segmentSize)
+	}
+	header := &streamHeader{
+		segmentSize: int(segmentSize),
+		salt:        headerBytes[5 : 5+streamSaltSize],
+		noncePrefix: headerBytes[5+streamSaltSize:],
+	}
+	aead, err := header.newAEAD(key, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	return &decryptReader{
+		r:      bufio.NewReader(r),
+		header: header,
+		aead:   aead,
+		ct:     make([]byte, header.segmentSize+streamTagSize),
+		pt:     make([]byte, 0, header.segmentSize),
+	}, nil
+}
+
+// Read returns authenticated plaintext
+func (dr *decryptReader) Read(p []byte) (int, error) {
+	for len(dr.plain) == 0 {
+		if dr.err != nil {
+			return 0, dr.err
+		}
+		if dr.done {
+			return 0, io.EOF
+		}
+		dr.err = dr.next()
+	}
+	n := copy(p, dr.plain)
+	dr.plain = dr.plain[n:]
+	return n, nil
+}
+
+// next read and decrypt one segment
+// a segment is the last one if it is shorter than a full segment or nothing follows it
+func (dr *decryptReader) next() error {
+	if dr.index >= maxStreamSegments {
+		return errors.New("aes: too many segments")
+	}
+	n, err := io.ReadFull(dr.r, dr.ct)
+	last := false
+	switch err {
+	case nil:
+		if _, err := dr.r.Peek(1); err == io.EOF {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	case io.EOF, io.ErrUnexpectedEOF:
+		last = true
+	default:
+		return err
+	}
+	if n < streamTagSize {
+		return ErrStreamTruncated
+	}
+
+	plain, err := dr.aead.Open(dr.pt[:0], dr.header.nonce(dr.index, last), dr.ct[:n], nil)
+	if err != nil {
+		if last {
+			// a non-last segment at the end means the stream is truncated
+			if _, err := dr.aead.Open(dr.pt[:0], dr.header.nonce(dr.index, false), dr.ct[:n], nil); err == nil {
+				return ErrStreamTruncated
+			}
+		}
+		return ErrStreamCorrupted
+	}
+	dr.plain = plain
+	dr.index++
+	dr.done = last
+	return nil
+}
@@ -0,0 +1,175 @@
+package sm4
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"io"
+)
+
+var (
+	ErrInvalidPadding    = errors.New("sm4: invalid padding")
+	ErrInvalidCiphertext = errors.New("sm4: invalid ciphertext length")
+)
+
+// EncryptECB encrypt message in ECB mode with PKCS#7 padding
+// ECB leaks equal blocks, only use it for compatibility or single-block data
+func EncryptECB(msg, key []byte) ([]byte, error) {
+	block, err := NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	msg = padding(msg, BlockSize)
+	ciphertext := make([]byte, len(msg))
+	for i := 0; i < len(msg); i += BlockSize {
+		block.Encrypt(ciphertext[i:i+BlockSize], msg[i:i+BlockSize])
+	}
+	return ciphertext, nil
+}
+
+// DecryptECB decrypt ciphertext in ECB mode and remove PKCS#7 padding
+func DecryptECB(ciphertext, key []byte) ([]byte, error) {
+	block, err := NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) == 0 || len(ciphertext)%BlockSize != 0 {
+		return nil, ErrInvalidCiphertext
+	}
+	plaintext := make([]byte, len(ciphertext))
+	for i := 0; i < len(ciphertext); i += BlockSize {
+		block.Decrypt(plaintext[i:i+BlockSize], ciphertext[i:i+BlockSize])
+	}
+	return unpadding(plaintext, BlockSize)
+}
+
+// EncryptCBC encrypt message in CBC mode with PKCS#7 padding
+// a random IV is generated and output as IV||ciphertext
+func EncryptCBC(msg, key []byte) ([]byte, error) {
+	block, err := NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	msg = padding(msg, BlockSize)
+	ciphertext := make([]byte, BlockSize+len(msg))
+	iv := ciphertext[:BlockSize]
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return nil, err
+	}
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext[BlockSize:], msg)
+	return ciphertext, nil
+}
+
+// DecryptCBC decrypt IV||ciphertext in CBC mode and remove PKCS#7 padding
+// CBC is not authenticated, use GCM when ciphertext may be modified
+func DecryptCBC(ciphertext, key []byte) ([]byte, error) {
+	block, err := NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < 2*BlockSize || len(ciphertext)%BlockSize != 0 {
+		return nil, ErrInvalidCiphertext
+	}
+	iv := ciphertext[:BlockSize]
+	plaintext := make([]byte, len(ciphertext)-BlockSize)
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext[BlockSize:])
+	return unpadding(plaintext, BlockSize)
+}
+
+// EncryptCTR encrypt message in CTR mode
+// a random IV is generated and output as IV||ciphertext
+func EncryptCTR(msg, key []byte) ([]byte, error) {
+	block, err := NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext := make([]byte, BlockSize+len(msg))
+	iv := ciphertext[:BlockSize]
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return nil, err
+	}
+	cipher.NewCTR(block, iv).XORKeyStream(ciphertext[BlockSize:], msg)
+	return ciphertext, nil
+}
+
+// DecryptCTR decrypt IV||ciphertext in CTR mode
+func DecryptCTR(ciphertext, key []byte) ([]byte, error) {
+	block, err := NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < BlockSize {
+		return nil, ErrInvalidCiphertext
+	}
+	plaintext := make([]byte, len(ciphertext)-BlockSize)
+	cipher.NewCTR(block, ciphertext[:BlockSize]).XORKeyStream(plaintext, ciphertext[BlockSize:])
+	return plaintext, nil
+}
+
+// NewGCM returns SM4-GCM AEAD with standard 12-byte nonce and 16-byte tag
+func NewGCM(key []byte) (cipher.AEAD, error) {
+	block, err := NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptGCM encrypt and authenticate message and additional data in GCM mode
+// a random nonce is generated and output as nonce||ciphertext||tag
+func EncryptGCM(msg, key, additionalData []byte) ([]byte, error) {
+	aead, err := NewGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(msg)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, msg, additionalData), nil
+}
+
+// DecryptGCM verify and decrypt nonce||ciphertext||tag in GCM mode
+func DecryptGCM(ciphertext, key, additionalData []byte) ([]byte, error) {
+	aead, err := NewGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrInvalidCiphertext
+	}
+	nonce := ciphertext[:aead.NonceSize()]
+	return aead.Open(nil, nonce, ciphertext[aead.NonceSize():], additionalData)
+}
+
+// padding PKCS#7 padding, always copies msg
+func padding(src []byte, blocksize int) []byte {
+	padnum := blocksize - len(src)%blocksize
+	ret := make([]byte, len(src)+padnum)
+	copy(ret, src)
+	for i := len(src); i < len(ret); i++ {
+		ret[i] = byte(padnum)
+	}
+	return ret
+}
+
+// unpadding remove PKCS#7 padding, every padding byte is checked in constant time
+func unpadding(src []byte, blocksize int) ([]byte, error) {
+	n := len(src)
+	if n == 0 || n%blocksize != 0 {
+		return nil, ErrInvalidPadding
+	}
+	padnum := int(src[n-1])
+	good := subtle.ConstantTimeLessOrEq(1, padnum) & subtle.ConstantTimeLessOrEq(padnum, blocksize)
+	for i := 1; i <= blocksize; i++ {
+		// only bytes within padding length are checked
+		inPad := subtle.ConstantTimeLessOrEq(i, padnum)
+		match := subtle.ConstantTimeByteEq(src[n-i], byte(padnum))
+		good &= ^inPad | match
+	}
+	if good&1 != 1 {
+		return nil, ErrInvalidPadding
+	}
+	return src[:n-padnum], nil
+}
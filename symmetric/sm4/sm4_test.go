@@ -0,0 +1,148 @@
+package sm4
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/hex"
+	"testing"
+)
+
+var (
+	key = []byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef, 0xfe, 0xdc, 0xba, 0x98, 0x76, 0x54, 0x32, 0x10}
+	msg = []byte("sm4 test msg")
+)
+
+// examples of GB/T 32907-2016 appendix A
+func TestSM4KAT(t *testing.T) {
+	block, err := NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect1, _ := hex.DecodeString("681edf34d206965e86b3e94f536e4246")
+	dst := make([]byte, BlockSize)
+	block.Encrypt(dst, key)
+	if !bytes.Equal(dst, expect1) {
+		t.Errorf("wrong ciphertext %x", dst)
+	}
+	block.Decrypt(dst, dst)
+	if !bytes.Equal(dst, key) {
+		t.Errorf("wrong plaintext %x", dst)
+	}
+
+	// encrypt 1000000 times
+	expect2, _ := hex.DecodeString("595298c7c6fd271f0402f804c33d3f66")
+	copy(dst, key)
+	for i := 0; i < 1000000; i++ {
+		block.Encrypt(dst, dst)
+	}
+	if !bytes.Equal(dst, expect2) {
+		t.Errorf("wrong ciphertext after 1000000 rounds %x", dst)
+	}
+
+	if _, err := NewCipher(key[:15]); err == nil {
+		t.Errorf("invalid key size accepted")
+	}
+}
+
+// example of RFC 8998 appendix A.1
+func TestGCMKAT(t *testing.T) {
+	nonce, _ := hex.DecodeString("00001234567800000000ABCD")
+	ad, _ := hex.DecodeString("FEEDFACEDEADBEEFFEEDFACEDEADBEEFABADDAD2")
+	plaintext, _ := hex.DecodeString("AAAAAAAAAAAAAAAABBBBBBBBBBBBBBBBCCCCCCCCCCCCCCCCDDDDDDDDDDDDDDDDEEEEEEEEEEEEEEEEFFFFFFFFFFFFFFFFEEEEEEEEEEEEEEEEAAAAAAAAAAAAAAAA")
+	expect, _ := hex.DecodeString("17F399F08C67D5EE19D0DC9969C4BB7D5FD46FD3756489069157B282BB200735D82710CA5C22F0CCFA7CBF93D496AC15A56834CBCF98C397B4024A2691233B8D" +
+		"83DE3541E4C2B58177E065A9BF7B62EC")
+
+	aead, err := NewGCM(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext := aead.Seal(nil, nonce, plaintext, ad)
+	if !bytes.Equal(ciphertext, expect) {
+		t.Errorf("wrong ciphertext %X", ciphertext)
+	}
+	plain, err := aead.Open(nil, nonce, ciphertext, ad)
+	if err != nil || !bytes.Equal(plain, plaintext) {
+		t.Errorf("gcm open failed: %v", err)
+	}
+}
+
+func TestModes(t *testing.T) {
+	type modeFunc func(msg, key []byte) ([]byte, error)
+	modes := map[string][2]modeFunc{
+		"ECB": {EncryptECB, DecryptECB},
+		"CBC": {EncryptCBC, DecryptCBC},
+		"CTR": {EncryptCTR, DecryptCTR},
+	}
+	for name, mode := range modes {
+		for _, size := range []int{0, 1, 15, 16, 17, 100} {
+			m := bytes.Repeat([]byte{0x3c}, size)
+			ciphertext, err := mode[0](m, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			plaintext, err := mode[1](ciphertext, key)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if !bytes.Equal(plaintext, m) {
+				t.Errorf("%s: decrypt failed for size %d", name, size)
+			}
+		}
+	}
+
+	ciphertext, err := EncryptGCM(msg, key, []byte("header"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := DecryptGCM(ciphertext, key, []byte("header"))
+	if err != nil || !bytes.Equal(plaintext, msg) {
+		t.Errorf("GCM: decrypt failed: %v", err)
+	}
+	if _, err := DecryptGCM(ciphertext, key, []byte("other")); err == nil {
+		t.Errorf("GCM: wrong additional data accepted")
+	}
+	ciphertext[len(ciphertext)-1] ^= 1
+	if _, err := DecryptGCM(ciphertext, key, []byte("header")); err == nil {
+		t.Errorf("GCM: tampered ciphertext accepted")
+	}
+}
+
+func TestPadding(t *testing.T) {
+	block, _ := NewCipher(key)
+	encBlock := func(b []byte) []byte {
+		dst := make([]byte, BlockSize)
+		block.Encrypt(dst, b)
+		return dst
+	}
+	bad := [][]byte{
+		append(bytes.Repeat([]byte{1}, 15), 0),
+		append(bytes.Repeat([]byte{1}, 15), 17),
+		append(bytes.Repeat([]byte{1}, 14), 3, 3),
+	}
+	for _, b := range bad {
+		if _, err := DecryptECB(encBlock(b), key); err != ErrInvalidPadding {
+			t.Errorf("invalid padding %x accepted", b)
+		}
+	}
+	if _, err := DecryptECB(make([]byte, 15), key); err != ErrInvalidCiphertext {
+		t.Errorf("invalid ciphertext length accepted")
+	}
+}
+
+func BenchmarkEncrypt(b *testing.B) {
+	var block cipher.Block
+	block, _ = NewCipher(key)
+	dst := make([]byte, BlockSize)
+	b.SetBytes(BlockSize)
+	for i := 0; i < b.N; i++ {
+		block.Encrypt(dst, dst)
+	}
+}
+
+func BenchmarkGCM(b *testing.B) {
+	data := make([]byte, 1024)
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		EncryptGCM(data, key, nil)
+	}
+}